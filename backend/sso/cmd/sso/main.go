@@ -4,6 +4,9 @@ package main
 // @version 1.0
 // @description SSO service API.
 // @BasePath /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 import (
 	"flag"
 	"log/slog"
@@ -0,0 +1,220 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	adminModels "github.com/EtoNeAnanasbI95/sso/internal/dto/admin"
+	eventModels "github.com/EtoNeAnanasbI95/sso/internal/dto/event"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/response"
+	sessionModels "github.com/EtoNeAnanasbI95/sso/internal/dto/session"
+	authErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/auth"
+	mfaErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/mfa"
+	sessionErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/session"
+	"github.com/EtoNeAnanasbI95/sso/pkg/contextkeys"
+	"github.com/labstack/echo/v4"
+)
+
+type RevocationService interface {
+	Revoke(ctx context.Context, request adminModels.RevokeTokensRequest, adminId int64) error
+}
+
+type SessionService interface {
+	List(ctx context.Context, userId int64, currentId string) ([]sessionModels.SessionResponse, error)
+	Revoke(ctx context.Context, userId int64, sessionId string) error
+	ForceLogout(ctx context.Context, userId int64, adminId int64) error
+}
+
+type EventService interface {
+	List(ctx context.Context, userId int64, limit int) ([]eventModels.AuthEventResponse, error)
+}
+
+// MFAService снимает второй фактор пользователя, потерявшего телефон и коды восстановления
+type MFAService interface {
+	ResetMFA(ctx context.Context, userId int64, adminId int64, meta domain.SessionMeta) error
+}
+
+type Handler struct {
+	revocations RevocationService
+	sessions    SessionService
+	events      EventService
+	mfa         MFAService
+}
+
+func NewHandler(revocations RevocationService, sessions SessionService, events EventService, mfa MFAService) *Handler {
+	return &Handler{
+		revocations: revocations,
+		sessions:    sessions,
+		events:      events,
+		mfa:         mfa,
+	}
+}
+
+// RevokeTokens godoc
+// @Summary Revoke tokens by jti, by user or by issue time
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body adminModels.RevokeTokensRequest true "What to revoke"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/tokens/revoke [post]
+func (h *Handler) RevokeTokens(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req adminModels.RevokeTokensRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+
+	if err := h.revocations.Revoke(ctx, req, currentUserId(ctx)); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось отозвать токены", err.Error()))
+	}
+
+	payload := struct{}{}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&payload))
+}
+
+// ListUserSessions godoc
+// @Summary List active sessions of a user
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User id"
+// @Success 200 {array} sessionModels.SessionResponse
+// @Router /admin/users/{id}/sessions [get]
+func (h *Handler) ListUserSessions(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Некорректный идентификатор пользователя", err.Error()))
+	}
+
+	sessions, err := h.sessions.List(ctx, userId, "")
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось получить сессии", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&sessions))
+}
+
+// RevokeUserSession godoc
+// @Summary Revoke one session of a user
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User id"
+// @Param sessionId path string true "Session id"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/{id}/sessions/{sessionId} [delete]
+func (h *Handler) RevokeUserSession(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Некорректный идентификатор пользователя", err.Error()))
+	}
+
+	if err := h.sessions.Revoke(ctx, userId, c.Param("sessionId")); err != nil {
+		if errors.Is(err, sessionErrors.ErrSessionNotFound) {
+			return c.JSON(http.StatusNotFound, response.NewBadResponse[any]("Сессия не найдена", err.Error()))
+		}
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось завершить сессию", err.Error()))
+	}
+
+	payload := struct{}{}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&payload))
+}
+
+// ForceLogout godoc
+// @Summary Terminate all sessions of a user and invalidate outstanding access tokens
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User id"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/{id}/sessions [delete]
+func (h *Handler) ForceLogout(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Некорректный идентификатор пользователя", err.Error()))
+	}
+
+	if err := h.sessions.ForceLogout(ctx, userId, currentUserId(ctx)); err != nil {
+		if errors.Is(err, authErrors.ErrUserNotFound) {
+			return c.JSON(http.StatusNotFound, response.NewBadResponse[any]("Пользователь не найден", err.Error()))
+		}
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось завершить сессии", err.Error()))
+	}
+
+	payload := struct{}{}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&payload))
+}
+
+// ResetMFA godoc
+// @Summary Disable TOTP of a user who lost both the phone and the recovery codes
+// @Description Удаляет секрет TOTP и коды восстановления пользователя и записывает mfa_reset_by_admin в журнал событий. Ключи WebAuthn не затрагиваются.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User id"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/{id}/mfa [delete]
+func (h *Handler) ResetMFA(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Некорректный идентификатор пользователя", err.Error()))
+	}
+
+	meta := domain.SessionMeta{UserAgent: c.Request().UserAgent(), IpAddress: c.RealIP()}
+	if err := h.mfa.ResetMFA(ctx, userId, currentUserId(ctx), meta); err != nil {
+		if errors.Is(err, authErrors.ErrUserNotFound) {
+			return c.JSON(http.StatusNotFound, response.NewBadResponse[any]("Пользователь не найден", err.Error()))
+		}
+		if errors.Is(err, mfaErrors.ErrNotEnrolled) {
+			return c.JSON(http.StatusNotFound, response.NewBadResponse[any]("Второй фактор не подключён", err.Error()))
+		}
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось отключить второй фактор", err.Error()))
+	}
+
+	payload := struct{}{}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&payload))
+}
+
+// ListUserEvents godoc
+// @Summary List authentication events of a user
+// @Description Журнал событий аутентификации: подключение и отключение второго фактора, в том числе администратором, использование кодов восстановления. Новые — первыми.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User id"
+// @Param limit query int false "Max events (default 100, max 500)"
+// @Success 200 {array} eventModels.AuthEventResponse
+// @Router /admin/users/{id}/events [get]
+func (h *Handler) ListUserEvents(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Некорректный идентификатор пользователя", err.Error()))
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+
+	events, err := h.events.List(ctx, userId, limit)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось получить события", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&events))
+}
+
+func currentUserId(ctx context.Context) int64 {
+	userId, _ := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	return userId
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	authModels "github.com/EtoNeAnanasbI95/sso/internal/dto/auth"
+	federationModels "github.com/EtoNeAnanasbI95/sso/internal/dto/federation"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/response"
+	authErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/auth"
+	clientErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/client"
+	federationErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/federation"
+	"github.com/labstack/echo/v4"
+)
+
+type FederationService interface {
+	Providers() []federationModels.ProviderResponse
+	Login(ctx context.Context, providerId, clientId, returnTo string) (string, string, error)
+	Callback(ctx context.Context, providerId, state, code string, meta domain.SessionMeta) (*authModels.AuthResponse, string, error)
+}
+
+// cookie привязывает state входа через провайдера к браузеру, который этот вход начал
+const upstreamStateCookieName = "upstream_state"
+
+// ExternalProviders godoc
+// @Summary List upstream identity providers
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/external [get]
+func (h *Handler) ExternalProviders(c echo.Context) error {
+	payload := h.federation.Providers()
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&payload))
+}
+
+// ExternalLogin godoc
+// @Summary Start login through an upstream identity provider
+// @Description Перенаправляет браузер к провайдеру. После входа callback выдаёт токены для client_id и возвращает браузер на return_to.
+// @Tags auth
+// @Param provider path string true "Provider id"
+// @Param client_id query string false "Client id, jwt.default_client by default"
+// @Param return_to query string false "Path on SSO or URL with an origin allowed for the client"
+// @Success 302
+// @Router /auth/external/{provider}/login [get]
+func (h *Handler) ExternalLogin(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	location, state, err := h.federation.Login(ctx, c.Param("provider"), c.QueryParam("client_id"), c.QueryParam("return_to"))
+	if err != nil {
+		return externalError(c, err)
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     upstreamStateCookieName,
+		Value:    state,
+		Path:     "/auth/external",
+		HttpOnly: true,
+		Secure:   false,
+		// провайдер возвращает браузер обычной навигацией, Lax cookie при этом отправляется
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.Redirect(http.StatusFound, location)
+}
+
+// ExternalCallback godoc
+// @Summary Upstream identity provider callback
+// @Description Связывает учётную запись провайдера с пользователем (или создаёт его) и выдаёт токены. С return_to перенаправляет браузер туда, иначе отвечает токенами.
+// @Description При включённом втором факторе вместо токенов всегда отвечает mfa_required и mfa_token.
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Provider id"
+// @Param code query string false "Authorization code from the provider"
+// @Param state query string true "State issued by /auth/external/{provider}/login"
+// @Param error query string false "Error from the provider"
+// @Success 200 {object} authModels.AuthResponse
+// @Success 302
+// @Router /auth/external/{provider}/callback [get]
+func (h *Handler) ExternalCallback(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	cookie, cookieErr := c.Cookie(upstreamStateCookieName)
+	clearUpstreamStateCookie(c)
+
+	if upstreamErr := c.QueryParam("error"); upstreamErr != "" {
+		details := upstreamErr
+		if description := c.QueryParam("error_description"); description != "" {
+			details += ": " + description
+		}
+		return c.JSON(http.StatusBadRequest, response.NewBadResponse[any]("Вход отклонён провайдером", details))
+	}
+
+	state, code := c.QueryParam("state"), c.QueryParam("code")
+	if state == "" || code == "" {
+		return c.JSON(http.StatusBadRequest, response.NewBadResponse[any]("Отсутствует аргумент", "state и code обязательны"))
+	}
+	if cookieErr != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		return externalError(c, federationErrors.ErrInvalidState)
+	}
+
+	result, returnTo, err := h.federation.Callback(ctx, c.Param("provider"), state, code, sessionMeta(c))
+	if err != nil {
+		return externalError(c, err)
+	}
+	// mfa_token нужно отдать странице, которая запросит второй фактор, поэтому return_to не используется
+	if result.MfaRequired {
+		return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+	}
+
+	setRefreshTokenCookie(c, result.RefreshToken, result.RefreshExpiresAt)
+	if returnTo != "" {
+		return c.Redirect(http.StatusFound, returnTo)
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+func externalError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, federationErrors.ErrUnknownProvider):
+		status = http.StatusNotFound
+	case errors.Is(err, federationErrors.ErrInvalidReturnTo),
+		errors.Is(err, federationErrors.ErrInvalidState),
+		errors.Is(err, clientErrors.ErrUnknownClient),
+		errors.Is(err, clientErrors.ErrGrantNotAllowed):
+		status = http.StatusBadRequest
+	case errors.Is(err, federationErrors.ErrUpstreamFailed):
+		status = http.StatusBadGateway
+	case errors.Is(err, federationErrors.ErrIdentityNotLinked),
+		errors.Is(err, federationErrors.ErrLoginTaken),
+		errors.Is(err, federationErrors.ErrMissingLogin),
+		errors.Is(err, authErrors.ErrUserNotFound):
+		status = http.StatusForbidden
+	}
+	return c.JSON(status, response.NewBadResponse[any]("Ошибка входа через внешний провайдер", err.Error()))
+}
+
+func clearUpstreamStateCookie(c echo.Context) {
+	c.SetCookie(&http.Cookie{
+		Name:     upstreamStateCookieName,
+		Value:    "",
+		Path:     "/auth/external",
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+}
@@ -2,33 +2,45 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
 	authModels "github.com/EtoNeAnanasbI95/sso/internal/dto/auth"
 	"github.com/EtoNeAnanasbI95/sso/internal/dto/response"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/passwordpolicy"
 	"github.com/labstack/echo/v4"
 )
 
 type AuthService interface {
-	Auth(ctx context.Context, request authModels.AuthRequest, isNew bool) (*authModels.AuthResponse, error)
-	Refresh(ctx context.Context, refreshToken string) (*authModels.AuthResponse, error)
+	Auth(ctx context.Context, request authModels.AuthRequest, isNew bool, meta domain.SessionMeta) (*authModels.AuthResponse, error)
+	VerifyMFA(ctx context.Context, request authModels.MfaVerifyRequest, meta domain.SessionMeta) (*authModels.AuthResponse, error)
+	PasskeyService
+	PasswordlessService
+	TelegramAuth(ctx context.Context, request authModels.TelegramAuthRequest, meta domain.SessionMeta) (*authModels.AuthResponse, error)
+	TelegramWebAppAuth(ctx context.Context, initData string, meta domain.SessionMeta) (*authModels.AuthResponse, error)
+	Refresh(ctx context.Context, refreshToken string, meta domain.SessionMeta) (*authModels.AuthResponse, error)
 	RequestPasswordReset(ctx context.Context, login string) (string, error)
 	CompletePasswordReset(ctx context.Context, token, newPassword string) error
+	Logout(ctx context.Context, refreshToken string) error
 }
 
 type Handler struct {
-	s AuthService
+	s          AuthService
+	federation FederationService
 }
 
-func NewHandler(auth AuthService) *Handler {
+func NewHandler(auth AuthService, federation FederationService) *Handler {
 	return &Handler{
-		s: auth,
+		s:          auth,
+		federation: federation,
 	}
 }
 
 // LogIn godoc
 // @Summary Login user
+// @Description Если у пользователя включён второй фактор, токенов в ответе нет: приходят mfa_required, mfa_token для /auth/mfa/verify и mfa_methods.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -41,6 +53,7 @@ func (h *Handler) LogIn(c echo.Context) error {
 
 // SignUp godoc
 // @Summary Register user
+// @Description Если пароль не проходит политику паролей, data содержит authModels.PasswordPolicyViolations — список нарушенных правил.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -51,6 +64,101 @@ func (h *Handler) SignUp(c echo.Context) error {
 	return h.auth(c, true)
 }
 
+// VerifyMFA godoc
+// @Summary Complete login with a second factor code
+// @Description Принимает code из приложения-аутентификатора, ответ ключа webauthn или одноразовый recovery_code.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.MfaVerifyRequest true "MFA token + code or recovery code"
+// @Success 200 {object} authModels.AuthResponse
+// @Router /auth/mfa/verify [post]
+func (h *Handler) VerifyMFA(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.MfaVerifyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.MfaToken == "" || (req.Code == "" && req.RecoveryCode == "" && req.WebAuthn == nil) {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "mfa_token и code, recovery_code или webauthn обязательны"))
+	}
+
+	result, err := h.s.VerifyMFA(ctx, req, sessionMeta(c))
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка авторизации", err.Error()))
+	}
+
+	setRefreshTokenCookie(c, result.RefreshToken, result.RefreshExpiresAt)
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// TelegramAuth godoc
+// @Summary Login with Telegram Login Widget
+// @Description Принимает данные виджета как есть. Пользователь ищется по подтверждённому telegram_chat_id, привязать Telegram можно через POST /me/telegram.
+// @Description При включённом втором факторе вместо токенов возвращаются mfa_required и mfa_token.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.TelegramAuthRequest true "Login Widget payload"
+// @Success 200 {object} authModels.AuthResponse
+// @Router /auth/telegram [post]
+func (h *Handler) TelegramAuth(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.TelegramAuthRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.ID == 0 || req.AuthDate == 0 || req.Hash == "" {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "id, auth_date и hash обязательны"))
+	}
+
+	result, err := h.s.TelegramAuth(ctx, req, sessionMeta(c))
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка авторизации", err.Error()))
+	}
+	if result.MfaRequired {
+		return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+	}
+
+	setRefreshTokenCookie(c, result.RefreshToken, result.RefreshExpiresAt)
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// TelegramWebAppAuth godoc
+// @Summary Login from Telegram Mini App
+// @Description Проверяет initData Mini App и выпускает токены для клиента telegram.mini_app_client.
+// @Description При включённом втором факторе вместо токенов возвращаются mfa_required и mfa_token.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.TelegramWebAppAuthRequest true "Mini App initData"
+// @Success 200 {object} authModels.AuthResponse
+// @Router /auth/telegram/webapp [post]
+func (h *Handler) TelegramWebAppAuth(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.TelegramWebAppAuthRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.InitData == "" {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "init_data обязателен"))
+	}
+
+	result, err := h.s.TelegramWebAppAuth(ctx, req.InitData, sessionMeta(c))
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка авторизации", err.Error()))
+	}
+	if result.MfaRequired {
+		return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+	}
+
+	setRefreshTokenCookie(c, result.RefreshToken, result.RefreshExpiresAt)
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
 // Refresh godoc
 // @Summary Refresh access token
 // @Tags auth
@@ -66,13 +174,13 @@ func (h *Handler) Refresh(c echo.Context) error {
 	}
 	refreshToken := cookie.Value
 
-	result, err := h.s.Refresh(ctx, refreshToken)
+	result, err := h.s.Refresh(ctx, refreshToken, sessionMeta(c))
 	if err != nil {
 		clearRefreshTokenCookie(c)
 		return c.JSON(http.StatusUnauthorized, response.NewBadResponse[any]("Ошибка обновления токена", err.Error()))
 	}
 
-	setRefreshTokenCookie(c, result.RefreshToken)
+	setRefreshTokenCookie(c, result.RefreshToken, result.RefreshExpiresAt)
 	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
 }
 
@@ -109,6 +217,7 @@ func (h *Handler) RequestPasswordReset(c echo.Context) error {
 
 // CompletePasswordReset godoc
 // @Summary Complete password reset
+// @Description Если пароль не проходит политику паролей, data содержит authModels.PasswordPolicyViolations — список нарушенных правил.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -127,6 +236,10 @@ func (h *Handler) CompletePasswordReset(c echo.Context) error {
 	}
 
 	if err := h.s.CompletePasswordReset(ctx, req.Token, req.NewPassword); err != nil {
+		var policyErr *passwordpolicy.Error
+		if errors.As(err, &policyErr) {
+			return c.JSON(http.StatusOK, passwordPolicyResponse("Не удалось сбросить пароль", policyErr))
+		}
 		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось сбросить пароль", err.Error()))
 	}
 
@@ -135,12 +248,20 @@ func (h *Handler) CompletePasswordReset(c echo.Context) error {
 }
 
 // Logout godoc
-// @Summary Logout user and clear refresh cookie
+// @Summary Logout user, revoke refresh token family and clear refresh cookie
 // @Tags auth
 // @Produce json
 // @Success 200 {object} map[string]interface{}
 // @Router /auth/logout [post]
 func (h *Handler) Logout(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	if cookie, err := c.Cookie(refreshCookieName); err == nil && cookie.Value != "" {
+		if err := h.s.Logout(ctx, cookie.Value); err != nil {
+			return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось завершить сессию", err.Error()))
+		}
+	}
+
 	clearRefreshTokenCookie(c)
 	payload := struct{}{}
 	return c.JSON(http.StatusOK, response.NewSuccessResponse(&payload))
@@ -164,18 +285,45 @@ func (h *Handler) auth(c echo.Context, isNew bool) error {
 		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Пароль обязателен"))
 	}
 
-	result, err := h.s.Auth(ctx, req, isNew)
+	result, err := h.s.Auth(ctx, req, isNew, sessionMeta(c))
 	if err != nil {
+		var policyErr *passwordpolicy.Error
+		if errors.As(err, &policyErr) {
+			return c.JSON(http.StatusOK, passwordPolicyResponse("Ошибка авторизации", policyErr))
+		}
 		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка авторизации", err.Error()))
 	}
+	if result.MfaRequired {
+		return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+	}
 
-	setRefreshTokenCookie(c, result.RefreshToken)
+	setRefreshTokenCookie(c, result.RefreshToken, result.RefreshExpiresAt)
 	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
 }
 
 const refreshCookieName = "refresh_token"
 
-func setRefreshTokenCookie(c echo.Context, token string) {
+// passwordPolicyResponse — отказ с нарушенными правилами в data, чтобы фронтенд показал их у поля пароля
+func passwordPolicyResponse(message string, err *passwordpolicy.Error) response.ApiResponse[authModels.PasswordPolicyViolations] {
+	violations := make([]authModels.PasswordPolicyViolation, len(err.Violations))
+	for i, violation := range err.Violations {
+		violations[i] = authModels.PasswordPolicyViolation{Rule: violation.Rule, Message: violation.Message}
+	}
+	result := response.NewBadResponse[authModels.PasswordPolicyViolations](message, err.Error())
+	result.Data = &authModels.PasswordPolicyViolations{Violations: violations}
+	return result
+}
+
+// sessionMeta собирает сведения об устройстве для списка сессий
+func sessionMeta(c echo.Context) domain.SessionMeta {
+	return domain.SessionMeta{
+		UserAgent: c.Request().UserAgent(),
+		IpAddress: c.RealIP(),
+	}
+}
+
+// setRefreshTokenCookie выставляет cookie ровно на срок жизни refresh токена
+func setRefreshTokenCookie(c echo.Context, token string, expiresAt time.Time) {
 	cookie := &http.Cookie{
 		Name:     refreshCookieName,
 		Value:    token,
@@ -183,7 +331,8 @@ func setRefreshTokenCookie(c echo.Context, token string) {
 		HttpOnly: true,
 		Secure:   false,
 		SameSite: http.SameSiteLaxMode,
-		MaxAge:   int((time.Hour * 24 * 30).Seconds()),
+		Expires:  expiresAt,
+		MaxAge:   int(time.Until(expiresAt).Seconds()),
 	}
 	c.SetCookie(cookie)
 }
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	authModels "github.com/EtoNeAnanasbI95/sso/internal/dto/auth"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/response"
+	passwordlessErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/passwordless"
+	"github.com/labstack/echo/v4"
+)
+
+// PasswordlessService — вход по одноразовому коду без пароля
+type PasswordlessService interface {
+	RequestLoginCode(ctx context.Context, request authModels.LoginCodeRequest) (*authModels.LoginCodeResponse, error)
+	LoginWithCode(ctx context.Context, request authModels.LoginCodeVerifyRequest, meta domain.SessionMeta) (*authModels.AuthResponse, error)
+}
+
+// RequestLoginCode godoc
+// @Summary Send a one-time login code
+// @Description Отправляет код в Telegram или на почту пользователя. Ответ одинаковый, есть такой пользователь или нет.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.LoginCodeRequest true "Login"
+// @Success 200 {object} authModels.LoginCodeResponse
+// @Router /auth/code/request [post]
+func (h *Handler) RequestLoginCode(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.LoginCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.Login == "" {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Логин обязателен"))
+	}
+
+	result, err := h.s.RequestLoginCode(ctx, req)
+	if err != nil {
+		if errors.Is(err, passwordlessErrors.ErrTooManyRequests) {
+			return c.JSON(http.StatusTooManyRequests, response.NewBadResponse[any]("Слишком частые запросы", err.Error()))
+		}
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось отправить код", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// LoginWithCode godoc
+// @Summary Login with a one-time code
+// @Description Если у пользователя включён второй фактор, токенов в ответе нет: приходят mfa_required, mfa_token для /auth/mfa/verify и mfa_methods.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.LoginCodeVerifyRequest true "Code"
+// @Success 200 {object} authModels.AuthResponse
+// @Router /auth/code/verify [post]
+func (h *Handler) LoginWithCode(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.LoginCodeVerifyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.LoginToken == "" || req.Code == "" {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "login_token и code обязательны"))
+	}
+
+	result, err := h.s.LoginWithCode(ctx, req, sessionMeta(c))
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка авторизации", err.Error()))
+	}
+	if result.MfaRequired {
+		return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+	}
+
+	setRefreshTokenCookie(c, result.RefreshToken, result.RefreshExpiresAt)
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	authModels "github.com/EtoNeAnanasbI95/sso/internal/dto/auth"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/response"
+	webauthnModels "github.com/EtoNeAnanasbI95/sso/internal/dto/webauthn"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/webauthn"
+	"github.com/labstack/echo/v4"
+)
+
+// PasskeyService — вход по ключам WebAuthn: без пароля и вторым фактором после него
+type PasskeyService interface {
+	MfaWebAuthnOptions(ctx context.Context, mfaToken string) (*webauthn.RequestOptions, error)
+	WebAuthnLoginOptions(ctx context.Context, clientId string) (*webauthn.RequestOptions, error)
+	WebAuthnLogin(ctx context.Context, response webauthn.AssertionResponse, meta domain.SessionMeta) (*authModels.AuthResponse, error)
+}
+
+// WebAuthnLoginOptions godoc
+// @Summary Start passwordless login with a passkey
+// @Description Возвращает параметры для navigator.credentials.get. allowCredentials пуст: браузер предложит passkey этого сайта.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body webauthnModels.LoginOptionsRequest false "Client"
+// @Success 200 {object} webauthn.RequestOptions
+// @Router /auth/webauthn/login/options [post]
+func (h *Handler) WebAuthnLoginOptions(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req webauthnModels.LoginOptionsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+
+	options, err := h.s.WebAuthnLoginOptions(ctx, req.ClientID)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось начать вход по ключу", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(options))
+}
+
+// WebAuthnLogin godoc
+// @Summary Complete passwordless login with a passkey
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body webauthnModels.LoginRequest true "Assertion"
+// @Success 200 {object} authModels.AuthResponse
+// @Router /auth/webauthn/login [post]
+func (h *Handler) WebAuthnLogin(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req webauthnModels.LoginRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if len(req.Credential.RawID) == 0 {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "credential обязателен"))
+	}
+
+	result, err := h.s.WebAuthnLogin(ctx, req.Credential, sessionMeta(c))
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка авторизации", err.Error()))
+	}
+
+	setRefreshTokenCookie(c, result.RefreshToken, result.RefreshExpiresAt)
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// MfaWebAuthnOptions godoc
+// @Summary Start second factor check with a WebAuthn key
+// @Description Возвращает параметры для navigator.credentials.get с ключами пользователя. Ответ ключа передаётся в /auth/mfa/verify в поле webauthn.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body authModels.MfaOptionsRequest true "MFA token"
+// @Success 200 {object} webauthn.RequestOptions
+// @Router /auth/mfa/webauthn/options [post]
+func (h *Handler) MfaWebAuthnOptions(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.MfaOptionsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.MfaToken == "" {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "mfa_token обязателен"))
+	}
+
+	options, err := h.s.MfaWebAuthnOptions(ctx, req.MfaToken)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось начать проверку ключа", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(options))
+}
@@ -0,0 +1,419 @@
+package me
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	authModels "github.com/EtoNeAnanasbI95/sso/internal/dto/auth"
+	mfaModels "github.com/EtoNeAnanasbI95/sso/internal/dto/mfa"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/response"
+	sessionModels "github.com/EtoNeAnanasbI95/sso/internal/dto/session"
+	webauthnModels "github.com/EtoNeAnanasbI95/sso/internal/dto/webauthn"
+	mfaErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/mfa"
+	sessionErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/session"
+	telegramErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/telegram"
+	webauthnErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/webauthn"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/webauthn"
+	"github.com/EtoNeAnanasbI95/sso/pkg/contextkeys"
+	"github.com/labstack/echo/v4"
+)
+
+type SessionService interface {
+	List(ctx context.Context, userId int64, currentId string) ([]sessionModels.SessionResponse, error)
+	Revoke(ctx context.Context, userId int64, sessionId string) error
+	RevokeAll(ctx context.Context, userId int64) error
+}
+
+type MFAService interface {
+	EnrollTOTP(ctx context.Context, userId int64) (*mfaModels.TOTPEnrollmentResponse, error)
+	ConfirmTOTP(ctx context.Context, userId int64, code string, meta domain.SessionMeta) (*mfaModels.RecoveryCodesResponse, error)
+	DisableTOTP(ctx context.Context, userId int64, proof mfaModels.FactorProofRequest, meta domain.SessionMeta) error
+	RegenerateRecoveryCodes(ctx context.Context, userId int64, proof mfaModels.FactorProofRequest, meta domain.SessionMeta) (*mfaModels.RecoveryCodesResponse, error)
+	ProofOptions(ctx context.Context, userId int64) (*webauthn.RequestOptions, error)
+	RegisterPasskey(ctx context.Context, userId int64, request webauthnModels.RegisterRequest, meta domain.SessionMeta) (*webauthnModels.RegisterResponse, error)
+}
+
+type PasskeyService interface {
+	BeginRegistration(ctx context.Context, userId int64) (*webauthn.CreationOptions, error)
+	Credentials(ctx context.Context, userId int64) ([]webauthnModels.CredentialResponse, error)
+	DeleteCredential(ctx context.Context, userId, id int64, meta domain.SessionMeta) error
+}
+
+// TelegramService привязывает аккаунт Telegram, подпись которого проверена токеном бота
+type TelegramService interface {
+	LinkTelegram(ctx context.Context, userId int64, request authModels.TelegramAuthRequest) error
+	LinkTelegramWebApp(ctx context.Context, userId int64, initData string) error
+}
+
+type Handler struct {
+	sessions SessionService
+	mfa      MFAService
+	passkeys PasskeyService
+	telegram TelegramService
+}
+
+func NewHandler(sessions SessionService, mfa MFAService, passkeys PasskeyService, telegram TelegramService) *Handler {
+	return &Handler{
+		sessions: sessions,
+		mfa:      mfa,
+		passkeys: passkeys,
+		telegram: telegram,
+	}
+}
+
+// ListSessions godoc
+// @Summary List my active sessions
+// @Tags me
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} sessionModels.SessionResponse
+// @Router /me/sessions [get]
+func (h *Handler) ListSessions(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	sessions, err := h.sessions.List(ctx, currentUserId(ctx), currentSessionId(ctx))
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось получить сессии", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&sessions))
+}
+
+// RevokeSession godoc
+// @Summary Revoke one of my sessions
+// @Tags me
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session id"
+// @Success 200 {object} map[string]interface{}
+// @Router /me/sessions/{id} [delete]
+func (h *Handler) RevokeSession(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	if err := h.sessions.Revoke(ctx, currentUserId(ctx), c.Param("id")); err != nil {
+		if errors.Is(err, sessionErrors.ErrSessionNotFound) {
+			return c.JSON(http.StatusNotFound, response.NewBadResponse[any]("Сессия не найдена", err.Error()))
+		}
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось завершить сессию", err.Error()))
+	}
+
+	payload := struct{}{}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&payload))
+}
+
+// RevokeAllSessions godoc
+// @Summary Log out everywhere
+// @Tags me
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /me/sessions [delete]
+func (h *Handler) RevokeAllSessions(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	if err := h.sessions.RevokeAll(ctx, currentUserId(ctx)); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось завершить сессии", err.Error()))
+	}
+
+	payload := struct{}{}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&payload))
+}
+
+// EnrollTOTP godoc
+// @Summary Start TOTP enrollment
+// @Description Возвращает секрет и ссылку otpauth:// для приложения-аутентификатора. Второй фактор включится после подтверждения первым кодом.
+// @Tags me
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} mfaModels.TOTPEnrollmentResponse
+// @Router /me/mfa/totp [post]
+func (h *Handler) EnrollTOTP(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	enrollment, err := h.mfa.EnrollTOTP(ctx, currentUserId(ctx))
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось подключить второй фактор", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(enrollment))
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm TOTP enrollment with the first code
+// @Description Включает второй фактор и возвращает одноразовые коды восстановления. Коды показываются один раз.
+// @Tags me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body mfaModels.TOTPCodeRequest true "Code"
+// @Success 200 {object} mfaModels.RecoveryCodesResponse
+// @Router /me/mfa/totp/confirm [post]
+func (h *Handler) ConfirmTOTP(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req mfaModels.TOTPCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.Code == "" {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "Код обязателен"))
+	}
+
+	codes, err := h.mfa.ConfirmTOTP(ctx, currentUserId(ctx), req.Code, requestMeta(c))
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось подтвердить второй фактор", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(codes))
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Issue a new set of recovery codes
+// @Description Прежние коды восстановления перестают действовать. Нужен текущий код из приложения-аутентификатора, ответ ключа webauthn (параметры из /me/mfa/webauthn/options) или неиспользованный код восстановления.
+// @Tags me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body mfaModels.FactorProofRequest true "Current code, WebAuthn assertion or recovery code"
+// @Success 200 {object} mfaModels.RecoveryCodesResponse
+// @Router /me/mfa/recovery-codes [post]
+func (h *Handler) RegenerateRecoveryCodes(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req mfaModels.FactorProofRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.Code == "" && req.RecoveryCode == "" && req.WebAuthn == nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "code, recovery_code или webauthn обязателен"))
+	}
+
+	codes, err := h.mfa.RegenerateRecoveryCodes(ctx, currentUserId(ctx), req, requestMeta(c))
+	if err != nil {
+		if errors.Is(err, mfaErrors.ErrNotEnrolled) {
+			return c.JSON(http.StatusNotFound, response.NewBadResponse[any]("Второй фактор не подключён", err.Error()))
+		}
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось выпустить коды восстановления", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(codes))
+}
+
+// MFAWebAuthnOptions godoc
+// @Summary Start confirming a sensitive MFA change with a WebAuthn key
+// @Description Возвращает параметры для navigator.credentials.get. Ответ ключа отправляется полем webauthn в /me/mfa/recovery-codes или DELETE /me/mfa/totp.
+// @Tags me
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} webauthn.RequestOptions
+// @Router /me/mfa/webauthn/options [post]
+func (h *Handler) MFAWebAuthnOptions(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	options, err := h.mfa.ProofOptions(ctx, currentUserId(ctx))
+	if err != nil {
+		if errors.Is(err, webauthnErrors.ErrCredentialNotFound) {
+			return c.JSON(http.StatusNotFound, response.NewBadResponse[any]("Ключи не зарегистрированы", err.Error()))
+		}
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось начать проверку ключа", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(options))
+}
+
+// DisableTOTP godoc
+// @Summary Disable TOTP
+// @Description Отключает второй фактор и, если не осталось ключей WebAuthn, удаляет коды восстановления. Нужен текущий код из приложения-аутентификатора, ответ ключа webauthn или неиспользованный код восстановления.
+// @Tags me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body mfaModels.FactorProofRequest true "Current code, WebAuthn assertion or recovery code"
+// @Success 200 {object} map[string]interface{}
+// @Router /me/mfa/totp [delete]
+func (h *Handler) DisableTOTP(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req mfaModels.FactorProofRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.Code == "" && req.RecoveryCode == "" && req.WebAuthn == nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "code, recovery_code или webauthn обязателен"))
+	}
+
+	if err := h.mfa.DisableTOTP(ctx, currentUserId(ctx), req, requestMeta(c)); err != nil {
+		if errors.Is(err, mfaErrors.ErrNotEnrolled) {
+			return c.JSON(http.StatusNotFound, response.NewBadResponse[any]("Второй фактор не подключён", err.Error()))
+		}
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось отключить второй фактор", err.Error()))
+	}
+
+	payload := struct{}{}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&payload))
+}
+
+// WebAuthnRegisterOptions godoc
+// @Summary Start registering a WebAuthn key or passkey
+// @Description Возвращает параметры для navigator.credentials.create. Зарегистрированный ключ работает и для входа без пароля, и как второй фактор. Требует недавнего входа (step_up.account_max_age), иначе 401 insufficient_user_authentication.
+// @Tags me
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} webauthn.CreationOptions
+// @Router /me/webauthn/register/options [post]
+func (h *Handler) WebAuthnRegisterOptions(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	options, err := h.passkeys.BeginRegistration(ctx, currentUserId(ctx))
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось начать регистрацию ключа", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(options))
+}
+
+// WebAuthnRegister godoc
+// @Summary Complete registering a WebAuthn key or passkey
+// @Description Если это первый второй фактор пользователя, в ответе recovery_codes — одноразовые коды восстановления, они показываются один раз. Требует недавнего входа (step_up.account_max_age), иначе 401 insufficient_user_authentication.
+// @Tags me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body webauthnModels.RegisterRequest true "Attestation"
+// @Success 200 {object} webauthnModels.RegisterResponse
+// @Router /me/webauthn/register [post]
+func (h *Handler) WebAuthnRegister(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req webauthnModels.RegisterRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if len(req.Credential.RawID) == 0 {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "credential обязателен"))
+	}
+
+	credential, err := h.mfa.RegisterPasskey(ctx, currentUserId(ctx), req, requestMeta(c))
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось зарегистрировать ключ", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(credential))
+}
+
+// ListWebAuthnCredentials godoc
+// @Summary List my WebAuthn keys
+// @Tags me
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} webauthnModels.CredentialResponse
+// @Router /me/webauthn/credentials [get]
+func (h *Handler) ListWebAuthnCredentials(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	credentials, err := h.passkeys.Credentials(ctx, currentUserId(ctx))
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось получить ключи", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&credentials))
+}
+
+// DeleteWebAuthnCredential godoc
+// @Summary Remove one of my WebAuthn keys
+// @Description Требует недавнего входа (step_up.account_max_age), иначе 401 insufficient_user_authentication.
+// @Tags me
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Credential id"
+// @Success 200 {object} map[string]interface{}
+// @Router /me/webauthn/credentials/{id} [delete]
+func (h *Handler) DeleteWebAuthnCredential(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Некорректный идентификатор ключа", err.Error()))
+	}
+
+	if err := h.passkeys.DeleteCredential(ctx, currentUserId(ctx), id, requestMeta(c)); err != nil {
+		if errors.Is(err, webauthnErrors.ErrCredentialNotFound) {
+			return c.JSON(http.StatusNotFound, response.NewBadResponse[any]("Ключ не найден", err.Error()))
+		}
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось удалить ключ", err.Error()))
+	}
+
+	payload := struct{}{}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&payload))
+}
+
+// LinkTelegram godoc
+// @Summary Link my Telegram account
+// @Description Принимает данные Telegram Login Widget. После привязки можно входить через Telegram.
+// @Tags me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body authModels.TelegramAuthRequest true "Login Widget payload"
+// @Success 200 {object} map[string]interface{}
+// @Router /me/telegram [post]
+func (h *Handler) LinkTelegram(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.TelegramAuthRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.ID == 0 || req.AuthDate == 0 || req.Hash == "" {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "id, auth_date и hash обязательны"))
+	}
+
+	return h.telegramLinked(c, h.telegram.LinkTelegram(ctx, currentUserId(ctx), req))
+}
+
+// LinkTelegramWebApp godoc
+// @Summary Link my Telegram account from a Mini App
+// @Tags me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body authModels.TelegramWebAppAuthRequest true "Mini App initData"
+// @Success 200 {object} map[string]interface{}
+// @Router /me/telegram/webapp [post]
+func (h *Handler) LinkTelegramWebApp(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req authModels.TelegramWebAppAuthRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+	if req.InitData == "" {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Отсутствует аргумент", "init_data обязателен"))
+	}
+
+	return h.telegramLinked(c, h.telegram.LinkTelegramWebApp(ctx, currentUserId(ctx), req.InitData))
+}
+
+func (h *Handler) telegramLinked(c echo.Context, err error) error {
+	if errors.Is(err, telegramErrors.ErrAlreadyLinked) {
+		return c.JSON(http.StatusConflict, response.NewBadResponse[any]("Telegram уже привязан", err.Error()))
+	}
+	if err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось привязать Telegram", err.Error()))
+	}
+	payload := struct{}{}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&payload))
+}
+
+func currentUserId(ctx context.Context) int64 {
+	userId, _ := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	return userId
+}
+
+func currentSessionId(ctx context.Context) string {
+	sessionId, _ := ctx.Value(contextkeys.SessionIDCtxKey).(string)
+	return sessionId
+}
+
+// requestMeta — откуда пришёл запрос, для журнала событий
+func requestMeta(c echo.Context) domain.SessionMeta {
+	return domain.SessionMeta{
+		UserAgent: c.Request().UserAgent(),
+		IpAddress: c.RealIP(),
+	}
+}
@@ -0,0 +1,75 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	oauthModels "github.com/EtoNeAnanasbI95/sso/internal/dto/oauth"
+	oauthErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/oauth"
+	"github.com/labstack/echo/v4"
+)
+
+type IntrospectionService interface {
+	AuthenticateClient(ctx context.Context, clientId, clientSecret string) error
+	Introspect(ctx context.Context, token, tokenTypeHint string) (*oauthModels.IntrospectionResponse, error)
+}
+
+type Handler struct {
+	introspection IntrospectionService
+}
+
+func NewHandler(introspection IntrospectionService) *Handler {
+	return &Handler{
+		introspection: introspection,
+	}
+}
+
+// Introspect godoc
+// @Summary Token introspection (RFC 7662)
+// @Description Вызывающий сервис аутентифицируется через HTTP Basic (client_id:client_secret) или полями формы.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Token to introspect"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200 {object} oauthModels.IntrospectionResponse
+// @Failure 400 {object} oauthModels.ErrorResponse
+// @Failure 401 {object} oauthModels.ErrorResponse
+// @Router /oauth/introspect [post]
+func (h *Handler) Introspect(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req oauthModels.IntrospectionRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthError(c, http.StatusBadRequest, oauthErrors.ErrInvalidRequest, err.Error())
+	}
+
+	clientId, clientSecret, ok := c.Request().BasicAuth()
+	if !ok {
+		clientId, clientSecret = req.ClientID, req.ClientSecret
+	}
+	if err := h.introspection.AuthenticateClient(ctx, clientId, clientSecret); err != nil {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="sso"`)
+		return oauthError(c, http.StatusUnauthorized, oauthErrors.ErrInvalidClient, "client authentication failed")
+	}
+
+	result, err := h.introspection.Introspect(ctx, req.Token, req.TokenTypeHint)
+	if err != nil {
+		if errors.Is(err, oauthErrors.ErrInvalidRequest) {
+			return oauthError(c, http.StatusBadRequest, oauthErrors.ErrInvalidRequest, "token is required")
+		}
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, result)
+}
+
+func oauthError(c echo.Context, status int, err error, description string) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(status, oauthModels.ErrorResponse{
+		Error:            err.Error(),
+		ErrorDescription: description,
+	})
+}
@@ -0,0 +1,363 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	oauthModels "github.com/EtoNeAnanasbI95/sso/internal/dto/oauth"
+	oidcModels "github.com/EtoNeAnanasbI95/sso/internal/dto/oidc"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/response"
+	deviceErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/device"
+	oauthErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/oauth"
+	"github.com/EtoNeAnanasbI95/sso/pkg/contextkeys"
+	"github.com/EtoNeAnanasbI95/sso/pkg/echomiddleware"
+	"github.com/labstack/echo/v4"
+)
+
+// cookie сессии SSO, её выставляют /auth/logIn и /auth/refresh
+const refreshCookieName = "refresh_token"
+
+type Provider interface {
+	Authorize(ctx context.Context, request oidcModels.AuthorizeRequest, refreshToken, requestURI string) (string, error)
+	Token(ctx context.Context, request oidcModels.TokenRequest, meta domain.SessionMeta) (*oidcModels.TokenResponse, error)
+	UserInfo(ctx context.Context, userId int64) (*oidcModels.UserInfoResponse, error)
+	Discovery() oidcModels.DiscoveryDocument
+	DeviceAuthorization(ctx context.Context, request oidcModels.DeviceAuthorizationRequest) (*oidcModels.DeviceAuthorizationResponse, error)
+	DeviceInfo(ctx context.Context, userCode string) (*oidcModels.DeviceInfoResponse, error)
+	DeviceSession(ctx context.Context, refreshToken string) *domain.RefreshToken
+	DeviceLoginURL(userCode string) string
+	DecideDevice(ctx context.Context, userCode string, userId int64, authTime time.Time, amr []string, approve bool) error
+}
+
+type Handler struct {
+	provider Provider
+}
+
+func NewHandler(provider Provider) *Handler {
+	return &Handler{
+		provider: provider,
+	}
+}
+
+// Discovery godoc
+// @Summary OpenID Connect discovery document
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} oidcModels.DiscoveryDocument
+// @Router /.well-known/openid-configuration [get]
+func (h *Handler) Discovery(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(http.StatusOK, h.provider.Discovery())
+}
+
+// Authorize godoc
+// @Summary Authorization endpoint (authorization code flow with PKCE)
+// @Description Использует сессию SSO из cookie refresh_token. Без сессии перенаправляет на oidc.login_url с return_to.
+// @Tags oidc
+// @Param response_type query string true "code"
+// @Param client_id query string true "Client id"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param scope query string true "Must include openid"
+// @Param state query string false "Opaque value returned to the client"
+// @Param nonce query string false "Copied into the ID token"
+// @Param code_challenge query string true "BASE64URL(SHA256(code_verifier))"
+// @Param code_challenge_method query string true "S256"
+// @Param prompt query string false "none"
+// @Param max_age query int false "Max seconds since the user authenticated"
+// @Success 302
+// @Failure 400 {object} oauthModels.ErrorResponse
+// @Router /authorize [get]
+func (h *Handler) Authorize(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req oidcModels.AuthorizeRequest
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &req); err != nil {
+		return oauthError(c, http.StatusBadRequest, oauthErrors.ErrInvalidRequest, err.Error())
+	}
+
+	var refreshToken string
+	if cookie, err := c.Cookie(refreshCookieName); err == nil {
+		refreshToken = cookie.Value
+	}
+
+	location, err := h.provider.Authorize(ctx, req, refreshToken, c.Request().URL.RequestURI())
+	if err != nil {
+		switch {
+		case errors.Is(err, oauthErrors.ErrInvalidClient):
+			return oauthError(c, http.StatusBadRequest, oauthErrors.ErrInvalidClient, "unknown client_id")
+		case errors.Is(err, oauthErrors.ErrInvalidRequest):
+			return oauthError(c, http.StatusBadRequest, oauthErrors.ErrInvalidRequest, "redirect_uri is not registered for this client")
+		}
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.Redirect(http.StatusFound, location)
+}
+
+// Token godoc
+// @Summary Token endpoint
+// @Description grant_type=authorization_code (с code_verifier), grant_type=refresh_token или grant_type=client_credentials для конфиденциальных клиентов.
+// @Tags oidc
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code, refresh_token or client_credentials"
+// @Param client_id formData string false "Client id, required without Basic auth"
+// @Param client_secret formData string false "Secret of a confidential client (client_secret_post)"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI used in /authorize"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Param scope formData string false "Space separated scopes for client_credentials"
+// @Param device_code formData string false "Device code for the device_code grant"
+// @Success 200 {object} oidcModels.TokenResponse
+// @Failure 400 {object} oauthModels.ErrorResponse
+// @Failure 401 {object} oauthModels.ErrorResponse
+// @Router /token [post]
+func (h *Handler) Token(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req oidcModels.TokenRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthError(c, http.StatusBadRequest, oauthErrors.ErrInvalidRequest, err.Error())
+	}
+	// client_secret_basic имеет приоритет над client_secret_post
+	if clientId, clientSecret, ok := c.Request().BasicAuth(); ok {
+		if req.ClientID != "" && req.ClientID != clientId {
+			return oauthError(c, http.StatusBadRequest, oauthErrors.ErrInvalidRequest, "client_id does not match Basic auth")
+		}
+		req.ClientID, req.ClientSecret = clientId, clientSecret
+	}
+
+	meta := domain.SessionMeta{
+		UserAgent: c.Request().UserAgent(),
+		IpAddress: c.RealIP(),
+	}
+	result, err := h.provider.Token(ctx, req, meta)
+	if err != nil {
+		for _, oauthErr := range []error{
+			oauthErrors.ErrInvalidRequest,
+			oauthErrors.ErrInvalidGrant,
+			oauthErrors.ErrUnsupportedGrantType,
+			oauthErrors.ErrUnauthorizedClient,
+			oauthErrors.ErrInvalidScope,
+			oauthErrors.ErrAuthorizationPending,
+			oauthErrors.ErrSlowDown,
+			oauthErrors.ErrAccessDenied,
+			oauthErrors.ErrExpiredToken,
+		} {
+			if errors.Is(err, oauthErr) {
+				return oauthError(c, http.StatusBadRequest, oauthErr, "")
+			}
+		}
+		if errors.Is(err, oauthErrors.ErrInvalidClient) {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="sso"`)
+			return oauthError(c, http.StatusUnauthorized, oauthErrors.ErrInvalidClient, "client authentication failed")
+		}
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, result)
+}
+
+// UserInfo godoc
+// @Summary Claims about the authenticated user
+// @Tags oidc
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} oidcModels.UserInfoResponse
+// @Router /userinfo [get]
+func (h *Handler) UserInfo(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, _ := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+	result, err := h.provider.UserInfo(ctx, userId)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// DeviceAuthorization godoc
+// @Summary Device authorization endpoint (RFC 8628)
+// @Description Выдаёт device_code для опроса /oauth/token и user_code, который пользователь подтверждает на verification_uri.
+// @Tags oidc
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param client_id formData string false "Client id, required without Basic auth"
+// @Param client_secret formData string false "Secret of a confidential client"
+// @Param scope formData string false "Space separated scopes"
+// @Success 200 {object} oidcModels.DeviceAuthorizationResponse
+// @Failure 400 {object} oauthModels.ErrorResponse
+// @Failure 401 {object} oauthModels.ErrorResponse
+// @Router /oauth/device/code [post]
+func (h *Handler) DeviceAuthorization(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req oidcModels.DeviceAuthorizationRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthError(c, http.StatusBadRequest, oauthErrors.ErrInvalidRequest, err.Error())
+	}
+	if clientId, clientSecret, ok := c.Request().BasicAuth(); ok {
+		if req.ClientID != "" && req.ClientID != clientId {
+			return oauthError(c, http.StatusBadRequest, oauthErrors.ErrInvalidRequest, "client_id does not match Basic auth")
+		}
+		req.ClientID, req.ClientSecret = clientId, clientSecret
+	}
+
+	result, err := h.provider.DeviceAuthorization(ctx, req)
+	if err != nil {
+		for _, oauthErr := range []error{
+			oauthErrors.ErrUnauthorizedClient,
+			oauthErrors.ErrInvalidScope,
+		} {
+			if errors.Is(err, oauthErr) {
+				return oauthError(c, http.StatusBadRequest, oauthErr, "")
+			}
+		}
+		if errors.Is(err, oauthErrors.ErrInvalidClient) {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="sso"`)
+			return oauthError(c, http.StatusUnauthorized, oauthErrors.ErrInvalidClient, "client authentication failed")
+		}
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, result)
+}
+
+// DeviceInfo godoc
+// @Summary Show which client asks for access by user code
+// @Description Пользователь — владелец access токена или, без заголовка Authorization, сессия SSO из cookie refresh_token. Браузер без сессии перенаправляется на oidc.login_url с return_to на страницу подтверждения.
+// @Tags oidc
+// @Produce json
+// @Security BearerAuth
+// @Param user_code query string true "User code shown by the device"
+// @Success 200 {object} oidcModels.DeviceInfoResponse
+// @Success 302
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /oauth/device [get]
+func (h *Handler) DeviceInfo(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userCode := c.QueryParam("user_code")
+	approver := h.deviceApprover(c)
+	if approver == nil {
+		if location := h.provider.DeviceLoginURL(userCode); location != "" && !hasPrincipal(c) {
+			c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+			return c.Redirect(http.StatusFound, location)
+		}
+		return deviceLoginRequired(c)
+	}
+
+	result, err := h.provider.DeviceInfo(ctx, userCode)
+	if err != nil {
+		if errors.Is(err, deviceErrors.ErrUserCodeNotFound) {
+			return c.JSON(http.StatusNotFound, response.NewBadResponse[any]("Код не найден", err.Error()))
+		}
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось получить запрос устройства", err.Error()))
+	}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(result))
+}
+
+// DecideDevice godoc
+// @Summary Approve or deny a device by user code
+// @Description Устройство получит токены от имени текущего пользователя: владельца access токена или сессии SSO из cookie refresh_token.
+// @Tags oidc
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body oidcModels.DeviceDecisionRequest true "Decision"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /oauth/device [post]
+func (h *Handler) DecideDevice(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req oidcModels.DeviceDecisionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Ошибка чтения json", err.Error()))
+	}
+
+	approver := h.deviceApprover(c)
+	if approver == nil {
+		return deviceLoginRequired(c)
+	}
+
+	if err := h.provider.DecideDevice(ctx, req.UserCode, approver.userId, approver.authTime, approver.amr, req.Approve); err != nil {
+		if errors.Is(err, deviceErrors.ErrUserCodeNotFound) {
+			return c.JSON(http.StatusNotFound, response.NewBadResponse[any]("Код не найден", err.Error()))
+		}
+		return c.JSON(http.StatusOK, response.NewBadResponse[any]("Не удалось сохранить решение", err.Error()))
+	}
+
+	payload := struct{}{}
+	return c.JSON(http.StatusOK, response.NewSuccessResponse(&payload))
+}
+
+// deviceApprover — пользователь, который подтверждает устройство
+type deviceApprover struct {
+	userId   int64
+	authTime time.Time
+	amr      []string
+}
+
+// deviceApprover берёт пользователя из access токена, а без заголовка Authorization — из сессии SSO
+// в cookie refresh_token, как /authorize. nil — пользователя нет, сервисный токен тоже не подходит
+func (h *Handler) deviceApprover(c echo.Context) *deviceApprover {
+	ctx := c.Request().Context()
+
+	if hasPrincipal(c) {
+		principal, _ := ctx.Value(contextkeys.PrincipalTypeCtxKey).(string)
+		if principal != echomiddleware.PrincipalUser {
+			return nil
+		}
+		userId, _ := ctx.Value(contextkeys.UserIDCtxKey).(int64)
+		authTime, _ := ctx.Value(contextkeys.AuthTimeCtxKey).(time.Time)
+		amr, _ := ctx.Value(contextkeys.AmrCtxKey).([]string)
+		return &deviceApprover{userId: userId, authTime: authTime, amr: amr}
+	}
+
+	cookie, err := c.Cookie(refreshCookieName)
+	if err != nil {
+		return nil
+	}
+	session := h.provider.DeviceSession(ctx, cookie.Value)
+	if session == nil {
+		return nil
+	}
+	return &deviceApprover{userId: session.UserId, authTime: session.AuthTime, amr: domain.SplitAmr(session.Amr)}
+}
+
+// hasPrincipal — запрос пришёл с проверенным access токеном
+func hasPrincipal(c echo.Context) bool {
+	_, ok := c.Request().Context().Value(contextkeys.PrincipalTypeCtxKey).(string)
+	return ok
+}
+
+// deviceLoginRequired отвечает, когда подтвердить устройство некому. Сервисному токену — 403, как RequireUser
+func deviceLoginRequired(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	if hasPrincipal(c) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "user token required",
+		})
+	}
+	return c.JSON(http.StatusUnauthorized, map[string]string{
+		"error": "user login required",
+	})
+}
+
+func oauthError(c echo.Context, status int, err error, description string) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(status, oauthModels.ErrorResponse{
+		Error:            err.Error(),
+		ErrorDescription: description,
+	})
+}
@@ -4,7 +4,12 @@ import (
 	"net/http"
 
 	_ "github.com/EtoNeAnanasbI95/sso/docs"
+	"github.com/EtoNeAnanasbI95/sso/internal/application/admin"
 	"github.com/EtoNeAnanasbI95/sso/internal/application/auth"
+	"github.com/EtoNeAnanasbI95/sso/internal/application/me"
+	"github.com/EtoNeAnanasbI95/sso/internal/application/oauth"
+	"github.com/EtoNeAnanasbI95/sso/internal/application/oidc"
+	"github.com/EtoNeAnanasbI95/sso/internal/application/wellknown"
 	"github.com/EtoNeAnanasbI95/sso/internal/config"
 	"github.com/EtoNeAnanasbI95/sso/pkg/echomiddleware"
 	"github.com/labstack/echo/v4"
@@ -12,16 +17,58 @@ import (
 	echoSwagger "github.com/swaggo/echo-swagger"
 )
 
-func SetupHTTPServer(cfg *config.Config, authService auth.AuthService, jwt echomiddleware.Jwt) *echo.Echo {
+const adminRole = "admin"
+
+// RevocationService проверяет токены в middleware и отзывает их по запросу администратора
+type RevocationService interface {
+	admin.RevocationService
+	echomiddleware.Revocations
+}
+
+// SessionService управляет сессиями от имени пользователя и администратора
+type SessionService interface {
+	me.SessionService
+	admin.SessionService
+}
+
+// Origins решает, каким Origin браузера разрешены CORS запросы с cookie
+type Origins interface {
+	IsAllowedOrigin(origin string) bool
+}
+
+// MFAService управляет вторым фактором от имени пользователя и администратора
+type MFAService interface {
+	me.MFAService
+	admin.MFAService
+}
+
+// Services — всё, что нужно HTTP слою
+type Services struct {
+	Auth          auth.AuthService
+	Federation    auth.FederationService
+	Revocations   RevocationService
+	Introspection oauth.IntrospectionService
+	Sessions      SessionService
+	MFA           MFAService
+	Passkeys      me.PasskeyService
+	Telegram      me.TelegramService
+	Events        admin.EventService
+	OIDC          oidc.Provider
+	Jwt           echomiddleware.Jwt
+	Keys          wellknown.KeySet
+	Origins       Origins
+}
+
+func SetupHTTPServer(cfg *config.Config, services Services) *echo.Echo {
 	e := echo.New()
 
 	e.Pre(middleware.RemoveTrailingSlash())
-	e.Use(echomiddleware.JwtValidation(jwt))
+	e.Use(echomiddleware.JwtValidation(services.Jwt, services.Revocations))
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOriginFunc: func(origin string) (bool, error) {
-			// allow any origin but echo will echo back the actual host instead of "*"
-			return true, nil
+			// разрешены только Origin, зарегистрированные у клиентов; echo вернёт сам Origin, а не "*"
+			return services.Origins.IsAllowedOrigin(origin), nil
 		},
 		AllowCredentials: true,
 		AllowMethods:     []string{echo.GET, echo.HEAD, echo.PUT, echo.PATCH, echo.POST, echo.DELETE},
@@ -38,18 +85,100 @@ func SetupHTTPServer(cfg *config.Config, authService auth.AuthService, jwt echom
 		return c.String(http.StatusOK, "JWT IS VALID")
 	})
 
-	registerAuthRoutes(e, authService)
+	registerAuthRoutes(e, services.Auth, services.Federation)
+	registerWellKnownRoutes(e, services.Keys)
+	stepUp := echomiddleware.RequireStepUp(echomiddleware.StepUp{
+		ACR:    cfg.StepUp.ACR,
+		MaxAge: cfg.StepUp.MaxAge,
+	})
+	registerAdminRoutes(e, cfg.JWT.AdminAudience, services.Revocations, services.Sessions, services.Events, services.MFA, stepUp)
+	registerOAuthRoutes(e, services.Introspection)
+	accountStepUp := echomiddleware.RequireStepUp(echomiddleware.StepUp{
+		MaxAge: cfg.StepUp.AccountMaxAge,
+	})
+	registerMeRoutes(e, services.Sessions, services.MFA, services.Passkeys, services.Telegram, accountStepUp)
+	registerOIDCRoutes(e, services.OIDC)
 
 	return e
 }
 
-func registerAuthRoutes(e *echo.Echo, authService auth.AuthService) {
-	authHandler := auth.NewHandler(authService)
+func registerAuthRoutes(e *echo.Echo, authService auth.AuthService, federationService auth.FederationService) {
+	authHandler := auth.NewHandler(authService, federationService)
 	auth := e.Group("/auth")
 	auth.POST("/logIn", authHandler.LogIn)
 	auth.POST("/signUp", authHandler.SignUp)
+	auth.POST("/mfa/verify", authHandler.VerifyMFA)
+	auth.POST("/mfa/webauthn/options", authHandler.MfaWebAuthnOptions)
+	auth.POST("/webauthn/login/options", authHandler.WebAuthnLoginOptions)
+	auth.POST("/webauthn/login", authHandler.WebAuthnLogin)
+	auth.POST("/code/request", authHandler.RequestLoginCode)
+	auth.POST("/code/verify", authHandler.LoginWithCode)
+	auth.POST("/telegram", authHandler.TelegramAuth)
+	auth.POST("/telegram/webapp", authHandler.TelegramWebAppAuth)
 	auth.POST("/refresh", authHandler.Refresh)
 	auth.POST("/logout", authHandler.Logout)
 	auth.POST("/password/request", authHandler.RequestPasswordReset)
 	auth.POST("/password/complete", authHandler.CompletePasswordReset)
+	auth.GET("/external", authHandler.ExternalProviders)
+	auth.GET("/external/:provider/login", authHandler.ExternalLogin)
+	auth.GET("/external/:provider/callback", authHandler.ExternalCallback)
+}
+
+func registerWellKnownRoutes(e *echo.Echo, keys wellknown.KeySet) {
+	wellKnownHandler := wellknown.NewHandler(keys)
+	wellKnown := e.Group("/.well-known")
+	wellKnown.GET("/jwks.json", wellKnownHandler.JWKS)
+}
+
+// registerAdminRoutes регистрирует маршруты администратора. Операции, которые отзывают доступ пользователей,
+// дополнительно требуют свежего и достаточно надёжного входа — stepUp
+func registerAdminRoutes(e *echo.Echo, audience string, revocationService admin.RevocationService, sessionService admin.SessionService, eventService admin.EventService, mfaService admin.MFAService, stepUp echo.MiddlewareFunc) {
+	adminHandler := admin.NewHandler(revocationService, sessionService, eventService, mfaService)
+	admin := e.Group("/admin", echomiddleware.RequireRole(adminRole), echomiddleware.RequireAudience(audience))
+	admin.POST("/tokens/revoke", adminHandler.RevokeTokens, stepUp)
+	admin.GET("/users/:id/sessions", adminHandler.ListUserSessions)
+	admin.DELETE("/users/:id/sessions", adminHandler.ForceLogout, stepUp)
+	admin.DELETE("/users/:id/sessions/:sessionId", adminHandler.RevokeUserSession, stepUp)
+	admin.GET("/users/:id/events", adminHandler.ListUserEvents)
+	admin.DELETE("/users/:id/mfa", adminHandler.ResetMFA, stepUp)
+}
+
+func registerOAuthRoutes(e *echo.Echo, introspectionService oauth.IntrospectionService) {
+	oauthHandler := oauth.NewHandler(introspectionService)
+	oauth := e.Group("/oauth")
+	oauth.POST("/introspect", oauthHandler.Introspect)
+}
+
+func registerMeRoutes(e *echo.Echo, sessionService me.SessionService, mfaService me.MFAService, passkeyService me.PasskeyService, telegramService me.TelegramService, stepUp echo.MiddlewareFunc) {
+	meHandler := me.NewHandler(sessionService, mfaService, passkeyService, telegramService)
+	me := e.Group("/me", echomiddleware.RequireUser())
+	me.GET("/sessions", meHandler.ListSessions)
+	me.DELETE("/sessions", meHandler.RevokeAllSessions)
+	me.DELETE("/sessions/:id", meHandler.RevokeSession)
+	me.POST("/mfa/totp", meHandler.EnrollTOTP)
+	me.POST("/mfa/totp/confirm", meHandler.ConfirmTOTP)
+	me.DELETE("/mfa/totp", meHandler.DisableTOTP)
+	me.POST("/mfa/recovery-codes", meHandler.RegenerateRecoveryCodes)
+	me.POST("/mfa/webauthn/options", meHandler.MFAWebAuthnOptions)
+	me.POST("/webauthn/register/options", meHandler.WebAuthnRegisterOptions, stepUp)
+	me.POST("/webauthn/register", meHandler.WebAuthnRegister, stepUp)
+	me.GET("/webauthn/credentials", meHandler.ListWebAuthnCredentials)
+	me.DELETE("/webauthn/credentials/:id", meHandler.DeleteWebAuthnCredential, stepUp)
+	me.POST("/telegram", meHandler.LinkTelegram)
+	me.POST("/telegram/webapp", meHandler.LinkTelegramWebApp)
+}
+
+func registerOIDCRoutes(e *echo.Echo, provider oidc.Provider) {
+	oidcHandler := oidc.NewHandler(provider)
+	e.GET("/.well-known/openid-configuration", oidcHandler.Discovery)
+	e.GET("/authorize", oidcHandler.Authorize)
+	e.POST("/token", oidcHandler.Token)
+	// адрес из RFC 8628, по которому опрашивают CLI и бот; тот же эндпоинт, что и /token
+	e.POST("/oauth/token", oidcHandler.Token)
+	e.POST("/oauth/device/code", oidcHandler.DeviceAuthorization)
+	// пользователь — из access токена или сессии SSO в cookie, как на /authorize
+	e.GET("/oauth/device", oidcHandler.DeviceInfo)
+	e.POST("/oauth/device", oidcHandler.DecideDevice)
+	e.GET("/userinfo", oidcHandler.UserInfo, echomiddleware.RequireUser())
+	e.POST("/userinfo", oidcHandler.UserInfo, echomiddleware.RequireUser())
 }
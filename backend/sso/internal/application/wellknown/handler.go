@@ -0,0 +1,33 @@
+package wellknown
+
+import (
+	"net/http"
+
+	libjwt "github.com/EtoNeAnanasbI95/sso/internal/lib/jwt"
+	"github.com/labstack/echo/v4"
+)
+
+type KeySet interface {
+	JWKS() libjwt.JWKS
+}
+
+type Handler struct {
+	keys KeySet
+}
+
+func NewHandler(keys KeySet) *Handler {
+	return &Handler{
+		keys: keys,
+	}
+}
+
+// JWKS godoc
+// @Summary Public keys for access token verification
+// @Tags well-known
+// @Produce json
+// @Success 200 {object} libjwt.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *Handler) JWKS(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(http.StatusOK, h.keys.JWKS())
+}
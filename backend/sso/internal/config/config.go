@@ -2,18 +2,33 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Env              string     `mapstructure:"env"`
-	ConnectionString string     `mapstructure:"connection_string"`
-	Secret           string     `mapstructure:"secret"`
-	HTTP             HTTPConfig `mapstructure:"http"`
+	Env               string                  `mapstructure:"env"`
+	ConnectionString  string                  `mapstructure:"connection_string"`
+	Secret            string                  `mapstructure:"secret"`
+	HTTP              HTTPConfig              `mapstructure:"http"`
+	JWT               JWTConfig               `mapstructure:"jwt"`
+	Revocation        RevocationConfig        `mapstructure:"revocation"`
+	ClientRegistry    ClientRegistryConfig    `mapstructure:"client_registry"`
+	BackchannelLogout BackchannelLogoutConfig `mapstructure:"backchannel_logout"`
+	Clients           []ClientConfig          `mapstructure:"clients"`
+	OIDC              OIDCConfig              `mapstructure:"oidc"`
+	Federation        FederationConfig        `mapstructure:"federation"`
+	Telegram          TelegramConfig          `mapstructure:"telegram"`
+	MFA               MFAConfig               `mapstructure:"mfa"`
+	WebAuthn          WebAuthnConfig          `mapstructure:"webauthn"`
+	StepUp            StepUpConfig            `mapstructure:"step_up"`
+	Passwordless      PasswordlessConfig      `mapstructure:"passwordless"`
+	Password          PasswordConfig          `mapstructure:"password"`
 }
 
 type HTTPConfig struct {
@@ -21,6 +36,219 @@ type HTTPConfig struct {
 	Timeout time.Duration `mapstructure:"timeout"`
 }
 
+// JWTConfig описывает, чем подписываются токены.
+// Для RS256/ES256/EdDSA нужен PEM с приватным ключом, для HS256 используется Secret.
+// KeysDir — каталог, где хранятся ключи, выпущенные при ротации.
+// Issuer пишется в iss, Leeway — допуск расхождения часов при проверке exp/nbf/iat.
+// DefaultClient используется для запросов, в которых не передан client_id.
+// AdminAudience — aud, без которого токен не принимается маршрутами /admin; пустой не проверяется.
+type JWTConfig struct {
+	Issuer         string         `mapstructure:"issuer"`
+	Leeway         time.Duration  `mapstructure:"leeway"`
+	DefaultClient  string         `mapstructure:"default_client"`
+	AdminAudience  string         `mapstructure:"admin_audience"`
+	Algorithm      string         `mapstructure:"algorithm"`
+	PrivateKeyPath string         `mapstructure:"private_key_path"`
+	KeysDir        string         `mapstructure:"keys_dir"`
+	Rotation       RotationConfig `mapstructure:"rotation"`
+	// Сроки жизни токенов по умолчанию и их переопределения для ролей
+	Lifetime LifetimeConfig            `mapstructure:",squash"`
+	Roles    map[string]LifetimeConfig `mapstructure:"roles"`
+}
+
+// LifetimeConfig — сроки жизни access и refresh токенов. Нулевое значение — не переопределять.
+// RefreshTTL работает как таймаут простоя сессии, MaxSessionTTL — абсолютный предел от входа.
+type LifetimeConfig struct {
+	AccessTTL     time.Duration `mapstructure:"access_ttl"`
+	RefreshTTL    time.Duration `mapstructure:"refresh_ttl"`
+	MaxSessionTTL time.Duration `mapstructure:"max_session_ttl"`
+}
+
+// RotationConfig — расписание ротации ключей подписи.
+// Interval 0 отключает плановую ротацию, Retention 0 хранит старые ключи бессрочно.
+// Retention должен быть не меньше времени жизни refresh токена, иначе сессии оборвутся.
+type RotationConfig struct {
+	Interval  time.Duration `mapstructure:"interval"`
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+// RevocationConfig — как часто инстанс перечитывает список отозванных токенов из БД
+type RevocationConfig struct {
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+}
+
+// ClientRegistryConfig — как часто инстанс перечитывает реестр клиентов из БД
+type ClientRegistryConfig struct {
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+}
+
+// BackchannelLogoutConfig — доставка logout токенов клиентам.
+// PollInterval — как часто проверять очередь, Timeout — таймаут одного запроса к клиенту (меньше минуты,
+// на которую уведомление занимается инстансом), MaxAttempts — после скольких неудачных попыток уведомление бросается.
+type BackchannelLogoutConfig struct {
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	Timeout      time.Duration `mapstructure:"timeout"`
+	MaxAttempts  int           `mapstructure:"max_attempts"`
+}
+
+// ClientConfig — приложение, которое регистрируется в таблице clients при старте, если его там ещё нет.
+// Audience по умолчанию совпадает с ID. Secret задаётся только конфиденциальным клиентам, в БД хранится его хэш.
+// Сроки жизни клиента могут только сократить значения из jwt и из переопределений для ролей.
+// RedirectURIs — куда /authorize может вернуть код, AllowedOrigins — кому разрешён CORS; сравнение точное.
+type ClientConfig struct {
+	ID             string   `mapstructure:"id"`
+	Name           string   `mapstructure:"name"`
+	Secret         string   `mapstructure:"secret"`
+	Audience       []string `mapstructure:"audience"`
+	RedirectURIs   []string `mapstructure:"redirect_uris"`
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	GrantTypes     []string `mapstructure:"grant_types"`
+	Scopes         []string `mapstructure:"scopes"`
+	// BackchannelLogoutURI — адрес OIDC back-channel logout клиента
+	BackchannelLogoutURI string         `mapstructure:"backchannel_logout_uri"`
+	Lifetime             LifetimeConfig `mapstructure:",squash"`
+}
+
+// OIDCConfig — параметры OpenID Connect провайдера.
+// LoginURL — страница входа, куда /authorize отправляет пользователя без сессии SSO (с параметром return_to).
+// DeviceVerificationURI — страница, где вошедший пользователь подтверждает user_code устройства (RFC 8628).
+type OIDCConfig struct {
+	LoginURL              string        `mapstructure:"login_url"`
+	CodeTTL               time.Duration `mapstructure:"code_ttl"`
+	DeviceVerificationURI string        `mapstructure:"device_verification_uri"`
+	DeviceCodeTTL         time.Duration `mapstructure:"device_code_ttl"`
+	DevicePollInterval    time.Duration `mapstructure:"device_poll_interval"`
+}
+
+// TelegramConfig — вход через Telegram. BotToken — токен бота, для которого настроены Login Widget и Mini App,
+// им проверяются подписи Telegram. AuthMaxAge и InitDataMaxAge — насколько старые данные виджета и Mini App принимаются.
+// MiniAppClient — клиент, для которого выпускаются токены Mini App; без него вход из Mini App выключен.
+type TelegramConfig struct {
+	BotToken       string        `mapstructure:"bot_token"`
+	AuthMaxAge     time.Duration `mapstructure:"auth_max_age"`
+	InitDataMaxAge time.Duration `mapstructure:"init_data_max_age"`
+	MiniAppClient  string        `mapstructure:"mini_app_client"`
+}
+
+// MFAConfig — второй фактор TOTP. EncryptionKey — 32 байта в base64, ими шифруются секреты TOTP в базе;
+// без ключа подключить второй фактор нельзя. Issuer — подпись учётной записи в приложении-аутентификаторе.
+// ChallengeTTL и MaxAttempts — сколько живёт второй шаг входа и сколько кодов в нём можно ввести.
+type MFAConfig struct {
+	EncryptionKey string        `mapstructure:"encryption_key"`
+	Issuer        string        `mapstructure:"issuer"`
+	ChallengeTTL  time.Duration `mapstructure:"challenge_ttl"`
+	MaxAttempts   int           `mapstructure:"max_attempts"`
+}
+
+// WebAuthnConfig — ключи безопасности и passkey. RPID — домен, к которому браузер привязывает ключ,
+// Origins — адреса страниц, с которых разрешены церемонии. Если jwt.issuer — URL, оба берутся из него.
+// Timeout — сколько живёт выданный challenge.
+type WebAuthnConfig struct {
+	RPID    string        `mapstructure:"rp_id"`
+	RPName  string        `mapstructure:"rp_name"`
+	Origins []string      `mapstructure:"origins"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// StepUpConfig — требования к входу администратора для чувствительных операций: отзыва токенов,
+// принудительного выхода и снятия второго фактора. ACR — минимальный уровень acr ("2" — вход со вторым фактором), пустой не проверяется.
+// MaxAge — сколько может пройти со входа, 0 — не проверять.
+// AccountMaxAge — то же для пользователя, меняющего свои способы входа (регистрация и удаление ключей WebAuthn):
+// украденный access токен не должен позволять добавить злоумышленнику свой ключ. 0 — не проверять.
+type StepUpConfig struct {
+	ACR           string        `mapstructure:"acr"`
+	MaxAge        time.Duration `mapstructure:"max_age"`
+	AccountMaxAge time.Duration `mapstructure:"account_max_age"`
+}
+
+// PasswordlessConfig — вход по одноразовому коду. Notifiers — каналы доставки по порядку: telegram (бот
+// telegram.bot_token, в подтверждённый чат telegram_chat_id), email (на логин, если это адрес почты), а для разработки
+// log и file. Без каналов вход выключен. LinkURL — страница фронтенда, которой в сообщении передаются login_token и code.
+type PasswordlessConfig struct {
+	Notifiers      []string      `mapstructure:"notifiers"`
+	CodeTTL        time.Duration `mapstructure:"code_ttl"`
+	MaxAttempts    int           `mapstructure:"max_attempts"`
+	ResendInterval time.Duration `mapstructure:"resend_interval"`
+	LinkURL        string        `mapstructure:"link_url"`
+	FilePath       string        `mapstructure:"file_path"`
+	SMTP           SMTPConfig    `mapstructure:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+// PasswordConfig — хеширование паролей. Algorithm — argon2id (по умолчанию) или bcrypt. Хеш, сделанный
+// другим алгоритмом или с другими параметрами, пересчитывается при следующем входе пользователя.
+type PasswordConfig struct {
+	Algorithm  string               `mapstructure:"algorithm"`
+	Argon2     Argon2Config         `mapstructure:"argon2"`
+	BcryptCost int                  `mapstructure:"bcrypt_cost"`
+	Policy     PasswordPolicyConfig `mapstructure:"policy"`
+}
+
+// PasswordPolicyConfig — требования к паролю при регистрации и сбросе. RequiredClasses — обязательные классы
+// символов: lowercase, uppercase, digit, symbol. BreachedPath — файл или каталог с SHA-1 утёкших паролей
+// в формате Have I Been Pwned, пустой — не проверять. Логин и имя в пароле запрещены всегда.
+type PasswordPolicyConfig struct {
+	MinLength       int      `mapstructure:"min_length"`
+	MaxLength       int      `mapstructure:"max_length"`
+	RequiredClasses []string `mapstructure:"required_classes"`
+	BreachedPath    string   `mapstructure:"breached_path"`
+}
+
+// Argon2Config — параметры argon2id, Memory — в КиБ.
+type Argon2Config struct {
+	Memory      uint32 `mapstructure:"memory"`
+	Iterations  uint32 `mapstructure:"iterations"`
+	Parallelism uint8  `mapstructure:"parallelism"`
+	SaltLength  uint32 `mapstructure:"salt_length"`
+	KeyLength   uint32 `mapstructure:"key_length"`
+}
+
+// FederationConfig — вход через внешних провайдеров удостоверений.
+// LoginTTL — сколько ждать возвращения пользователя от провайдера.
+type FederationConfig struct {
+	LoginTTL  time.Duration            `mapstructure:"login_ttl"`
+	Providers []UpstreamProviderConfig `mapstructure:"providers"`
+}
+
+// UpstreamProviderConfig — внешний провайдер OpenID Connect или OAuth 2.0.
+// Для OpenID Connect достаточно Issuer, для OAuth 2.0 без discovery задаются authorization_url, token_url и userinfo_url.
+// RedirectURL по умолчанию — {jwt.issuer}/auth/external/{id}/callback, его регистрируют у провайдера.
+// AutoCreate создаёт пользователя при первом входе, LinkByLogin связывает с существующим по подтверждённому логину.
+type UpstreamProviderConfig struct {
+	ID               string             `mapstructure:"id"`
+	Name             string             `mapstructure:"name"`
+	Issuer           string             `mapstructure:"issuer"`
+	ClientID         string             `mapstructure:"client_id"`
+	ClientSecret     string             `mapstructure:"client_secret"`
+	RedirectURL      string             `mapstructure:"redirect_url"`
+	Scopes           []string           `mapstructure:"scopes"`
+	AuthorizationURL string             `mapstructure:"authorization_url"`
+	TokenURL         string             `mapstructure:"token_url"`
+	UserInfoURL      string             `mapstructure:"userinfo_url"`
+	JWKSURL          string             `mapstructure:"jwks_url"`
+	TokenAuthMethod  string             `mapstructure:"token_auth_method"`
+	Claims           ClaimMappingConfig `mapstructure:"claims"`
+	AutoCreate       bool               `mapstructure:"auto_create"`
+	LinkByLogin      bool               `mapstructure:"link_by_login"`
+	RoleID           int64              `mapstructure:"role_id"`
+}
+
+// ClaimMappingConfig — имена claims провайдера. По умолчанию sub, email и name;
+// Verified (например, email_verified) не проверяется, если не задан.
+type ClaimMappingConfig struct {
+	Subject  string `mapstructure:"subject"`
+	Login    string `mapstructure:"login"`
+	FullName string `mapstructure:"full_name"`
+	Verified string `mapstructure:"verified"`
+}
+
 func LoadConfig() (*Config, error) {
 	return LoadConfigFrom("")
 }
@@ -43,9 +271,147 @@ func LoadConfigFrom(path string) (*Config, error) {
 		return nil, fmt.Errorf("error while unmarshaling config file: %w", err)
 	}
 	overrideFromEnv(&cfg)
+	applyDefaults(&cfg)
 	return &cfg, nil
 }
 
+func applyDefaults(cfg *Config) {
+	if cfg.JWT.Issuer == "" {
+		cfg.JWT.Issuer = "sso"
+	}
+	if cfg.JWT.Lifetime.AccessTTL <= 0 {
+		cfg.JWT.Lifetime.AccessTTL = time.Hour
+	}
+	if cfg.JWT.Lifetime.RefreshTTL <= 0 {
+		cfg.JWT.Lifetime.RefreshTTL = 30 * 24 * time.Hour
+	}
+	if cfg.JWT.Lifetime.MaxSessionTTL <= 0 {
+		cfg.JWT.Lifetime.MaxSessionTTL = 90 * 24 * time.Hour
+	}
+
+	if cfg.OIDC.CodeTTL <= 0 {
+		cfg.OIDC.CodeTTL = time.Minute
+	}
+	if cfg.OIDC.DeviceVerificationURI == "" {
+		cfg.OIDC.DeviceVerificationURI = strings.TrimRight(cfg.JWT.Issuer, "/") + "/device"
+	}
+	if cfg.OIDC.DeviceCodeTTL <= 0 {
+		cfg.OIDC.DeviceCodeTTL = 10 * time.Minute
+	}
+	if cfg.OIDC.DevicePollInterval <= 0 {
+		cfg.OIDC.DevicePollInterval = 5 * time.Second
+	}
+
+	if cfg.Telegram.AuthMaxAge <= 0 {
+		cfg.Telegram.AuthMaxAge = 24 * time.Hour
+	}
+	if cfg.Telegram.InitDataMaxAge <= 0 {
+		cfg.Telegram.InitDataMaxAge = time.Hour
+	}
+
+	if cfg.MFA.Issuer == "" {
+		cfg.MFA.Issuer = "SSO"
+	}
+	if cfg.MFA.ChallengeTTL <= 0 {
+		cfg.MFA.ChallengeTTL = 5 * time.Minute
+	}
+	if cfg.MFA.MaxAttempts <= 0 {
+		cfg.MFA.MaxAttempts = 5
+	}
+
+	if issuer, err := url.Parse(cfg.JWT.Issuer); err == nil && issuer.Hostname() != "" {
+		if cfg.WebAuthn.RPID == "" {
+			cfg.WebAuthn.RPID = issuer.Hostname()
+		}
+		if len(cfg.WebAuthn.Origins) == 0 {
+			cfg.WebAuthn.Origins = []string{issuer.Scheme + "://" + issuer.Host}
+		}
+	}
+	if cfg.WebAuthn.RPName == "" {
+		cfg.WebAuthn.RPName = cfg.MFA.Issuer
+	}
+	if cfg.WebAuthn.Timeout <= 0 {
+		cfg.WebAuthn.Timeout = 5 * time.Minute
+	}
+
+	if cfg.Passwordless.CodeTTL <= 0 {
+		cfg.Passwordless.CodeTTL = 10 * time.Minute
+	}
+	if cfg.Passwordless.MaxAttempts <= 0 {
+		cfg.Passwordless.MaxAttempts = 5
+	}
+	if cfg.Passwordless.ResendInterval <= 0 {
+		cfg.Passwordless.ResendInterval = time.Minute
+	}
+
+	if cfg.Password.Algorithm == "" {
+		cfg.Password.Algorithm = "argon2id"
+	}
+	if cfg.Password.Argon2.Memory == 0 {
+		cfg.Password.Argon2.Memory = 64 * 1024
+	}
+	if cfg.Password.Argon2.Iterations == 0 {
+		cfg.Password.Argon2.Iterations = 3
+	}
+	if cfg.Password.Argon2.Parallelism == 0 {
+		cfg.Password.Argon2.Parallelism = 2
+	}
+	if cfg.Password.Argon2.SaltLength == 0 {
+		cfg.Password.Argon2.SaltLength = 16
+	}
+	if cfg.Password.Argon2.KeyLength == 0 {
+		cfg.Password.Argon2.KeyLength = 32
+	}
+	if cfg.Password.BcryptCost <= 0 {
+		cfg.Password.BcryptCost = 10
+	}
+	if cfg.Password.Policy.MinLength <= 0 {
+		cfg.Password.Policy.MinLength = 8
+	}
+	if cfg.Password.Policy.MaxLength <= 0 {
+		cfg.Password.Policy.MaxLength = 128
+	}
+
+	if cfg.Federation.LoginTTL <= 0 {
+		cfg.Federation.LoginTTL = 10 * time.Minute
+	}
+	for i := range cfg.Federation.Providers {
+		provider := &cfg.Federation.Providers[i]
+		if provider.Name == "" {
+			provider.Name = provider.ID
+		}
+		if provider.RedirectURL == "" {
+			provider.RedirectURL = strings.TrimRight(cfg.JWT.Issuer, "/") + "/auth/external/" + provider.ID + "/callback"
+		}
+		if len(provider.Scopes) == 0 && provider.Issuer != "" {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+	}
+
+	if cfg.Revocation.ReloadInterval <= 0 {
+		cfg.Revocation.ReloadInterval = 30 * time.Second
+	}
+
+	if cfg.ClientRegistry.ReloadInterval <= 0 {
+		cfg.ClientRegistry.ReloadInterval = 30 * time.Second
+	}
+
+	if cfg.BackchannelLogout.PollInterval <= 0 {
+		cfg.BackchannelLogout.PollInterval = 5 * time.Second
+	}
+	if cfg.BackchannelLogout.Timeout <= 0 {
+		cfg.BackchannelLogout.Timeout = 5 * time.Second
+	}
+	if cfg.BackchannelLogout.MaxAttempts <= 0 {
+		cfg.BackchannelLogout.MaxAttempts = 8
+	}
+}
+
+// ClientSecretEnv — переменная окружения с секретом клиента clientId
+func ClientSecretEnv(clientId string) string {
+	return "SSO_CLIENT_" + strings.ToUpper(strings.ReplaceAll(clientId, "-", "_")) + "_SECRET"
+}
+
 func overrideFromEnv(cfg *Config) {
 	if env := os.Getenv("SSO_ENV"); env != "" {
 		cfg.Env = env
@@ -70,4 +436,109 @@ func overrideFromEnv(cfg *Config) {
 			cfg.HTTP.Timeout = duration
 		}
 	}
+
+	if issuer := os.Getenv("SSO_JWT_ISSUER"); issuer != "" {
+		cfg.JWT.Issuer = issuer
+	}
+
+	if adminAudience := os.Getenv("SSO_JWT_ADMIN_AUDIENCE"); adminAudience != "" {
+		cfg.JWT.AdminAudience = adminAudience
+	}
+
+	if leewayStr := os.Getenv("SSO_JWT_LEEWAY"); leewayStr != "" {
+		if duration, err := time.ParseDuration(leewayStr); err == nil {
+			cfg.JWT.Leeway = duration
+		}
+	}
+
+	if accessStr := os.Getenv("SSO_JWT_ACCESS_TTL"); accessStr != "" {
+		if duration, err := time.ParseDuration(accessStr); err == nil {
+			cfg.JWT.Lifetime.AccessTTL = duration
+		}
+	}
+
+	if refreshStr := os.Getenv("SSO_JWT_REFRESH_TTL"); refreshStr != "" {
+		if duration, err := time.ParseDuration(refreshStr); err == nil {
+			cfg.JWT.Lifetime.RefreshTTL = duration
+		}
+	}
+
+	if sessionStr := os.Getenv("SSO_JWT_MAX_SESSION_TTL"); sessionStr != "" {
+		if duration, err := time.ParseDuration(sessionStr); err == nil {
+			cfg.JWT.Lifetime.MaxSessionTTL = duration
+		}
+	}
+
+	if loginURL := os.Getenv("SSO_OIDC_LOGIN_URL"); loginURL != "" {
+		cfg.OIDC.LoginURL = loginURL
+	}
+
+	if verificationURI := os.Getenv("SSO_OIDC_DEVICE_VERIFICATION_URI"); verificationURI != "" {
+		cfg.OIDC.DeviceVerificationURI = verificationURI
+	}
+
+	if algorithm := os.Getenv("SSO_JWT_ALGORITHM"); algorithm != "" {
+		cfg.JWT.Algorithm = algorithm
+	}
+
+	if keyPath := os.Getenv("SSO_JWT_PRIVATE_KEY_PATH"); keyPath != "" {
+		cfg.JWT.PrivateKeyPath = keyPath
+	}
+
+	if keysDir := os.Getenv("SSO_JWT_KEYS_DIR"); keysDir != "" {
+		cfg.JWT.KeysDir = keysDir
+	}
+
+	if intervalStr := os.Getenv("SSO_JWT_ROTATION_INTERVAL"); intervalStr != "" {
+		if duration, err := time.ParseDuration(intervalStr); err == nil {
+			cfg.JWT.Rotation.Interval = duration
+		}
+	}
+
+	if retentionStr := os.Getenv("SSO_JWT_ROTATION_RETENTION"); retentionStr != "" {
+		if duration, err := time.ParseDuration(retentionStr); err == nil {
+			cfg.JWT.Rotation.Retention = duration
+		}
+	}
+
+	if reloadStr := os.Getenv("SSO_REVOCATION_RELOAD_INTERVAL"); reloadStr != "" {
+		if duration, err := time.ParseDuration(reloadStr); err == nil {
+			cfg.Revocation.ReloadInterval = duration
+		}
+	}
+
+	if reloadStr := os.Getenv("SSO_CLIENT_REGISTRY_RELOAD_INTERVAL"); reloadStr != "" {
+		if duration, err := time.ParseDuration(reloadStr); err == nil {
+			cfg.ClientRegistry.ReloadInterval = duration
+		}
+	}
+
+	if botToken := os.Getenv("SSO_TELEGRAM_BOT_TOKEN"); botToken != "" {
+		cfg.Telegram.BotToken = botToken
+	}
+
+	if mfaKey := os.Getenv("SSO_MFA_ENCRYPTION_KEY"); mfaKey != "" {
+		cfg.MFA.EncryptionKey = mfaKey
+	}
+
+	if smtpPassword := os.Getenv("SSO_SMTP_PASSWORD"); smtpPassword != "" {
+		cfg.Passwordless.SMTP.Password = smtpPassword
+	}
+
+	// секреты клиентов задаются окружением: SSO_CLIENT_SHOP_MINIAPP_SECRET для id "shop-miniapp"
+	for i := range cfg.Clients {
+		client := &cfg.Clients[i]
+		if secret := os.Getenv(ClientSecretEnv(client.ID)); secret != "" {
+			client.Secret = secret
+		}
+	}
+
+	// секреты провайдеров не обязательно держать в файле: SSO_FEDERATION_CORP_CLIENT_SECRET для id "corp"
+	for i := range cfg.Federation.Providers {
+		provider := &cfg.Federation.Providers[i]
+		name := "SSO_FEDERATION_" + strings.ToUpper(strings.ReplaceAll(provider.ID, "-", "_")) + "_CLIENT_SECRET"
+		if secret := os.Getenv(name); secret != "" {
+			provider.ClientSecret = secret
+		}
+	}
 }
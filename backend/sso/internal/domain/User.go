@@ -4,14 +4,19 @@ import (
 	"database/sql"
 	"errors"
 	"time"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 var (
 	ErrInvalidOldPassword error = errors.New("cтарый пароль не совпадает с текущим")
 )
 
+// PasswordHasher хеширует пароли пользователей. Verify возвращает outdated, если пароль верен,
+// но хеш сделан устаревшим алгоритмом или с прежними параметрами
+type PasswordHasher interface {
+	Hash(password string) ([]byte, error)
+	Verify(hash []byte, password string) (valid bool, outdated bool, err error)
+}
+
 type User struct {
 	Id               int64          `db:"id"`
 	RoleId           int64          `db:"role_id"`
@@ -27,9 +32,10 @@ type User struct {
 	IsArchived       bool           `db:"is_archived"`
 	IsDeleted        bool           `db:"is_deleted"`
 	RoleName         string         `db:"role_name"`
+	TokenVersion     int64          `db:"token_version"`
 }
 
-func NewUser(login, telegramUsername, password, fullName string, telegramChatId *int64, roleId *int64, isArchived *bool) *User {
+func NewUser(hasher PasswordHasher, login, telegramUsername, password, fullName string, telegramChatId *int64, roleId *int64, isArchived *bool) (*User, error) {
 	user := &User{
 		Login:            login,
 		TelegramUsername: telegramUsername,
@@ -41,7 +47,9 @@ func NewUser(login, telegramUsername, password, fullName string, telegramChatId
 	if telegramChatId != nil {
 		user.TelegramChatId = sql.NullInt64{Int64: *telegramChatId, Valid: true}
 	}
-	user.PasswordHash, _ = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err := user.setPassword(hasher, password); err != nil {
+		return nil, err
+	}
 	if roleId != nil {
 		user.RoleId = *roleId
 	} else {
@@ -57,12 +65,23 @@ func NewUser(login, telegramUsername, password, fullName string, telegramChatId
 	user.IsDeleted = false
 	user.TelegramVerified = false
 
-	return user
+	return user, nil
+}
+
+// CheckPassword проверяет пароль. Испорченный хеш считается несовпадением.
+// outdated — пароль верен, но хеш стоит пересчитать через RehashPassword
+func (u *User) CheckPassword(hasher PasswordHasher, password string) (valid bool, outdated bool) {
+	valid, outdated, err := hasher.Verify(u.PasswordHash, password)
+	if err != nil {
+		return false, false
+	}
+	return valid, outdated
 }
 
-func (u *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password))
-	return err == nil
+// RehashPassword пересчитывает хеш уже проверенного пароля текущим алгоритмом. Дата изменения
+// пользователя не меняется: сам пароль остаётся прежним
+func (u *User) RehashPassword(hasher PasswordHasher, password string) error {
+	return u.setPassword(hasher, password)
 }
 
 func (u *User) UpdateLogin(login string) {
@@ -70,10 +89,12 @@ func (u *User) UpdateLogin(login string) {
 	u.updateDateTime()
 }
 
-func (u *User) UpdatePassword(oldPass, newPass string) error {
-	oldCorrect := u.CheckPassword(oldPass)
+func (u *User) UpdatePassword(hasher PasswordHasher, oldPass, newPass string) error {
+	oldCorrect, _ := u.CheckPassword(hasher, oldPass)
 	if oldCorrect {
-		u.PasswordHash, _ = bcrypt.GenerateFromPassword([]byte(newPass), bcrypt.DefaultCost)
+		if err := u.setPassword(hasher, newPass); err != nil {
+			return err
+		}
 		u.updateDateTime()
 		return nil
 	} else {
@@ -81,6 +102,15 @@ func (u *User) UpdatePassword(oldPass, newPass string) error {
 	}
 }
 
+func (u *User) setPassword(hasher PasswordHasher, password string) error {
+	hash, err := hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = hash
+	return nil
+}
+
 func (u *User) ChangeArchiveStatus(status bool) {
 	u.IsArchived = status
 	u.updateDateTime()
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// Типы событий журнала аутентификации
+const (
+	AuthEventTOTPEnabled              = "mfa_totp_enabled"
+	AuthEventTOTPDisabled             = "mfa_totp_disabled"
+	AuthEventRecoveryCodeUsed         = "mfa_recovery_code_used"
+	AuthEventRecoveryCodesRegenerated = "mfa_recovery_codes_regenerated"
+	AuthEventMFAReset                 = "mfa_reset_by_admin"
+	AuthEventWebAuthnRegistered       = "webauthn_credential_registered"
+	AuthEventWebAuthnRemoved          = "webauthn_credential_removed"
+)
+
+// AuthEvent — запись журнала событий аутентификации пользователя. ClientId пуст у событий вне входа в клиент
+type AuthEvent struct {
+	Id        int64     `db:"id"`
+	UserId    int64     `db:"user_id"`
+	Type      string    `db:"event_type"`
+	ClientId  string    `db:"client_id"`
+	UserAgent string    `db:"user_agent"`
+	IpAddress string    `db:"ip_address"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func NewAuthEvent(userId int64, eventType, clientId string, meta SessionMeta) *AuthEvent {
+	return &AuthEvent{
+		UserId:    userId,
+		Type:      eventType,
+		ClientId:  clientId,
+		UserAgent: meta.UserAgent,
+		IpAddress: meta.IpAddress,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+)
+
+// Способы аутентификации для claim amr (RFC 8176). Коды восстановления — тоже одноразовые пароли, поэтому otp
+const (
+	AmrPassword  = "pwd"
+	AmrOTP       = "otp"
+	AmrWebAuthn  = "webauthn"
+	AmrTelegram  = "telegram"
+	AmrFederated = "fed"
+	// AmrMFA добавляется, когда вход подтверждён несколькими факторами
+	AmrMFA = "mfa"
+)
+
+// Уровни claim acr: чем больше число, тем надёжнее вход
+const (
+	AcrSingleFactor = "1"
+	AcrMultiFactor  = "2"
+)
+
+// MultiFactorAmr — amr входа, подтверждённого несколькими факторами. Повторяющиеся способы пишутся один раз
+func MultiFactorAmr(methods ...string) []string {
+	amr := make([]string, 0, len(methods)+1)
+	for _, method := range methods {
+		if !slices.Contains(amr, method) {
+			amr = append(amr, method)
+		}
+	}
+	return append(amr, AmrMFA)
+}
+
+// Acr — уровень входа по его способам
+func Acr(amr []string) string {
+	if slices.Contains(amr, AmrMFA) {
+		return AcrMultiFactor
+	}
+	return AcrSingleFactor
+}
+
+// JoinAmr и SplitAmr переводят amr в строку через пробел и обратно: так он хранится в базе, как scope
+func JoinAmr(amr []string) string {
+	return strings.Join(amr, " ")
+}
+
+func SplitAmr(amr string) []string {
+	return strings.Fields(amr)
+}
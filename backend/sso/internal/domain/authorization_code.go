@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const CodeChallengeMethodS256 = "S256"
+
+// AuthorizationCode — одноразовый код OIDC authorization code flow. Хранится только хэш кода
+type AuthorizationCode struct {
+	Id       int64  `db:"id"`
+	CodeHash []byte `db:"code_hash"`
+	ClientId string `db:"client_id"`
+	UserId   int64  `db:"user_id"`
+	// SessionId — сессия SSO, в которой пользователь разрешил доступ
+	SessionId     *string   `db:"session_id"`
+	RedirectUri   string    `db:"redirect_uri"`
+	Scope         string    `db:"scope"`
+	Nonce         string    `db:"nonce"`
+	CodeChallenge string    `db:"code_challenge"`
+	AuthTime      time.Time `db:"auth_time"`
+	// Amr — способы входа в сессию SSO через пробел
+	Amr        string     `db:"amr"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	ConsumedAt *time.Time `db:"consumed_at"`
+}
+
+// NewAuthorizationCode генерирует код и готовит запись для хранилища. Возвращает сам код для редиректа
+func NewAuthorizationCode(clientId string, userId int64, sessionId string, redirectUri, scope, nonce, codeChallenge string, authTime time.Time, amr []string, ttl time.Duration) (string, *AuthorizationCode, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("generate authorization code: %w", err)
+	}
+	code := base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now()
+	return code, &AuthorizationCode{
+		CodeHash:      HashAuthorizationCode(code),
+		ClientId:      clientId,
+		UserId:        userId,
+		SessionId:     &sessionId,
+		RedirectUri:   redirectUri,
+		Scope:         scope,
+		Nonce:         nonce,
+		CodeChallenge: codeChallenge,
+		AuthTime:      authTime,
+		Amr:           JoinAmr(amr),
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(ttl),
+	}, nil
+}
+
+func HashAuthorizationCode(code string) []byte {
+	sum := sha256.Sum256([]byte(code))
+	return sum[:]
+}
+
+// VerifyCodeVerifier проверяет PKCE: BASE64URL(SHA256(code_verifier)) == code_challenge (RFC 7636)
+func (c *AuthorizationCode) VerifyCodeVerifier(verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(challenge), []byte(c.CodeChallenge)) == 1
+}
+
+func (c *AuthorizationCode) IsExpired(now time.Time) bool {
+	return !c.ExpiresAt.After(now)
+}
+
+// HasScope проверяет, что scope был запрошен
+func (c *AuthorizationCode) HasScope(scope string) bool {
+	for _, s := range strings.Fields(c.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
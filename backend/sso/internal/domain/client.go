@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"slices"
+
+	libjwt "github.com/EtoNeAnanasbI95/sso/internal/lib/jwt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Гранты, которые можно разрешить клиенту
+const (
+	GrantTypePassword          = "password"
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// Client — зарегистрированное приложение, для которого SSO выпускает токены (shop, adminer, api, ...)
+type Client struct {
+	Id   string
+	Name string
+	// SecretHash — bcrypt от секрета; пустой у публичных клиентов (SPA), которые не могут хранить секрет
+	SecretHash     []byte
+	Audience       []string
+	RedirectURIs   []string
+	AllowedOrigins []string
+	GrantTypes     []string
+	Scopes         []string
+	// BackchannelLogoutURI — куда отправлять logout токен при завершении сессии; пустой — клиент не уведомляется
+	BackchannelLogoutURI string
+	// Lifetime может только сократить сроки жизни токенов, заданные в конфигурации
+	Lifetime libjwt.Lifetime
+}
+
+// HashClientSecret хэширует секрет клиента для хранения
+func HashClientSecret(secret string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+}
+
+// IsConfidential — клиент аутентифицируется секретом
+func (c *Client) IsConfidential() bool {
+	return len(c.SecretHash) > 0
+}
+
+func (c *Client) CheckSecret(secret string) bool {
+	if !c.IsConfidential() || secret == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(c.SecretHash, []byte(secret)) == nil
+}
+
+// AllowsRedirectURI — redirect_uri должен в точности совпадать с одним из зарегистрированных
+func (c *Client) AllowsRedirectURI(uri string) bool {
+	return slices.Contains(c.RedirectURIs, uri)
+}
+
+// AllowsOrigin — Origin браузера, которому разрешены CORS запросы с cookie
+func (c *Client) AllowsOrigin(origin string) bool {
+	return slices.Contains(c.AllowedOrigins, origin)
+}
+
+func (c *Client) AllowsGrant(grantType string) bool {
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+// AllowsScopes проверяет, что клиенту разрешены все запрошенные scope
+func (c *Client) AllowsScopes(scopes []string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(c.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
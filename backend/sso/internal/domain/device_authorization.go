@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Состояния запроса авторизации устройства
+const (
+	DeviceStatusPending  = "pending"
+	DeviceStatusApproved = "approved"
+	DeviceStatusDenied   = "denied"
+	DeviceStatusConsumed = "consumed"
+)
+
+// userCodeAlphabet — без гласных и похожих символов, чтобы код было легко ввести и он не складывался в слова (RFC 8628, 6.1)
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+const userCodeLength = 8
+
+// DeviceAuthorization — запрос авторизации устройства без браузера (RFC 8628).
+// device_code хранится только хэшем, user_code — нормализованным, без дефиса
+type DeviceAuthorization struct {
+	Id             int64      `db:"id"`
+	DeviceCodeHash []byte     `db:"device_code_hash"`
+	UserCode       string     `db:"user_code"`
+	ClientId       string     `db:"client_id"`
+	Scope          string     `db:"scope"`
+	Status         string     `db:"status"`
+	UserId         *int64     `db:"user_id"`
+	AuthTime       *time.Time `db:"auth_time"`
+	// Amr — способы входа в сессию, в которой пользователь подтвердил код, через пробел
+	Amr          string     `db:"amr"`
+	Interval     int64      `db:"interval_seconds"`
+	LastPolledAt *time.Time `db:"last_polled_at"`
+	CreatedAt    time.Time  `db:"created_at"`
+	ExpiresAt    time.Time  `db:"expires_at"`
+}
+
+// NewDeviceAuthorization генерирует device_code и user_code и готовит запись для хранилища.
+// Возвращает сам device_code для клиента
+func NewDeviceAuthorization(clientId, scope string, interval time.Duration, ttl time.Duration) (string, *DeviceAuthorization, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("generate device code: %w", err)
+	}
+	deviceCode := base64.RawURLEncoding.EncodeToString(b)
+
+	userCode, err := newUserCode()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	return deviceCode, &DeviceAuthorization{
+		DeviceCodeHash: HashDeviceCode(deviceCode),
+		UserCode:       userCode,
+		ClientId:       clientId,
+		Scope:          scope,
+		Status:         DeviceStatusPending,
+		Interval:       int64(interval / time.Second),
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(ttl),
+	}, nil
+}
+
+func newUserCode() (string, error) {
+	b := make([]byte, userCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate user code: %w", err)
+	}
+	code := make([]byte, userCodeLength)
+	for i := range b {
+		// 256 не делится на 20 нацело, но небольшой перекос для одноразового кода с коротким TTL не важен
+		code[i] = userCodeAlphabet[int(b[i])%len(userCodeAlphabet)]
+	}
+	return string(code), nil
+}
+
+func HashDeviceCode(code string) []byte {
+	sum := sha256.Sum256([]byte(code))
+	return sum[:]
+}
+
+// NormalizeUserCode приводит введённый пользователем код к виду, в котором он хранится: без дефисов и пробелов, заглавными
+func NormalizeUserCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+}
+
+// FormatUserCode показывает код пользователю в виде XXXX-XXXX
+func FormatUserCode(code string) string {
+	if len(code) != userCodeLength {
+		return code
+	}
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
+
+func (d *DeviceAuthorization) IsExpired(now time.Time) bool {
+	return !d.ExpiresAt.After(now)
+}
+
+// PolledTooOften — клиент опрашивает /token чаще, чем разрешено interval
+func (d *DeviceAuthorization) PolledTooOften(now time.Time) bool {
+	if d.LastPolledAt == nil {
+		return false
+	}
+	return now.Sub(*d.LastPolledAt) < time.Duration(d.Interval)*time.Second
+}
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// loginCodeDigits — длина одноразового кода входа; подбор ограничен числом попыток и сроком жизни
+const loginCodeDigits = 6
+
+// LoginCode — вход без пароля по одноразовому коду, отправленному пользователю. Запрос входа определяется
+// токеном, который получает клиент, код приходит пользователю отдельно. Хранятся только хэши обоих
+type LoginCode struct {
+	Id         int64      `db:"id"`
+	TokenHash  []byte     `db:"token_hash"`
+	CodeHash   []byte     `db:"code_hash"`
+	UserId     int64      `db:"user_id"`
+	ClientId   string     `db:"client_id"`
+	Attempts   int        `db:"attempts"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	ConsumedAt *time.Time `db:"consumed_at"`
+}
+
+// NewLoginCode генерирует токен запроса и код и готовит запись для хранилища. Возвращает токен и код
+func NewLoginCode(userId int64, clientId string, ttl time.Duration) (string, string, *LoginCode, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", "", nil, fmt.Errorf("generate login token: %w", err)
+	}
+	code, err := newLoginCodeDigits()
+	if err != nil {
+		return "", "", nil, fmt.Errorf("generate login code: %w", err)
+	}
+	now := time.Now()
+	return token, code, &LoginCode{
+		TokenHash: HashLoginToken(token),
+		CodeHash:  hashLoginCode(token, code),
+		UserId:    userId,
+		ClientId:  clientId,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, nil
+}
+
+// NewDecoyLoginToken — токен для несуществующего пользователя: выглядит как настоящий, но ни к чему не ведёт
+func NewDecoyLoginToken() (string, error) {
+	return randomToken()
+}
+
+// HashLoginRequestKey — ключ ограничения частоты запросов кода: логин без регистра, пробелов и @ перед тегом Telegram.
+// Логины хранятся только хэшем, в том числе несуществующие
+func HashLoginRequestKey(login string) []byte {
+	normalized := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(login), "@"))
+	sum := sha256.Sum256([]byte(normalized))
+	return sum[:]
+}
+
+func HashLoginToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// Matches сравнивает код за постоянное время. Код хэшируется вместе с токеном: без токена, которого
+// в базе нет, перебрать миллион кодов по утёкшим хэшам нельзя
+func (c *LoginCode) Matches(token, code string) bool {
+	return subtle.ConstantTimeCompare(c.CodeHash, hashLoginCode(token, code)) == 1
+}
+
+func hashLoginCode(token, code string) []byte {
+	sum := sha256.Sum256([]byte(token + ":" + code))
+	return sum[:]
+}
+
+func newLoginCodeDigits() (string, error) {
+	limit := big.NewInt(1)
+	for range loginCodeDigits {
+		limit.Mul(limit, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", loginCodeDigits, n), nil
+}
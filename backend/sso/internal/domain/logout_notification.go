@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// LogoutNotification — уведомление клиента о завершении сессии (OIDC Back-Channel Logout).
+// Ставится в очередь в той же транзакции, что и отзыв сессии, и доставляется с повторами
+type LogoutNotification struct {
+	Id            int64      `db:"id"`
+	ClientId      string     `db:"client_id"`
+	LogoutUri     string     `db:"logout_uri"`
+	UserId        int64      `db:"user_id"`
+	SessionId     string     `db:"session_id"`
+	Attempts      int        `db:"attempts"`
+	LastError     string     `db:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+	DeliveredAt   *time.Time `db:"delivered_at"`
+	FailedAt      *time.Time `db:"failed_at"`
+}
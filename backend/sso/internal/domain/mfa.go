@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// UserTOTP — TOTP второй фактор пользователя. Секрет хранится зашифрованным,
+// до подтверждения первым кодом фактор не действует
+type UserTOTP struct {
+	UserId          int64      `db:"user_id"`
+	SecretEncrypted []byte     `db:"secret_encrypted"`
+	ConfirmedAt     *time.Time `db:"confirmed_at"`
+	// LastUsedStep — последний принятый шаг TOTP; коды этого и более ранних шагов повторно не принимаются
+	LastUsedStep *int64    `db:"last_used_step"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+func (t *UserTOTP) IsConfirmed() bool {
+	return t.ConfirmedAt != nil
+}
+
+// MfaChallenge — вход, ожидающий второго фактора: пароль уже проверен, токены ещё не выпущены.
+// Хранится только хэш токена
+type MfaChallenge struct {
+	Id        int64  `db:"id"`
+	TokenHash []byte `db:"token_hash"`
+	UserId    int64  `db:"user_id"`
+	ClientId  string `db:"client_id"`
+	// Amr — способы первого шага входа через пробел
+	Amr        string     `db:"amr"`
+	Attempts   int        `db:"attempts"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	ConsumedAt *time.Time `db:"consumed_at"`
+}
+
+// NewMfaChallenge генерирует токен второго шага входа и готовит запись для хранилища. Возвращает сам токен.
+// amr — способы, которыми пройден первый шаг
+func NewMfaChallenge(userId int64, clientId string, amr []string, ttl time.Duration) (string, *MfaChallenge, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("generate mfa token: %w", err)
+	}
+	now := time.Now()
+	return token, &MfaChallenge{
+		TokenHash: HashMfaToken(token),
+		UserId:    userId,
+		ClientId:  clientId,
+		Amr:       JoinAmr(amr),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, nil
+}
+
+// FirstFactorAmr — способы первого шага. Входы, начатые до появления amr, начинались с пароля
+func (c *MfaChallenge) FirstFactorAmr() []string {
+	if c.Amr == "" {
+		return []string{AmrPassword}
+	}
+	return SplitAmr(c.Amr)
+}
+
+func HashMfaToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// RecoveryCodeCount — сколько кодов восстановления выдаётся за раз
+const RecoveryCodeCount = 10
+
+// recoveryCodeLength — 10 символов base32, 50 бит случайности на код
+const recoveryCodeLength = 10
+
+// recoveryCodeAlphabet — base32 в нижнем регистре; 256 делится на 32, поэтому символы равновероятны
+const recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+// RecoveryCode — одноразовый код восстановления, заменяющий второй фактор при входе.
+// Хранится только bcrypt от кода, как пароль
+type RecoveryCode struct {
+	Id        int64      `db:"id"`
+	UserId    int64      `db:"user_id"`
+	CodeHash  []byte     `db:"code_hash"`
+	CreatedAt time.Time  `db:"created_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
+
+// NewRecoveryCodes генерирует набор кодов вида xxxxx-xxxxx. Возвращает коды для показа пользователю
+// и записи для хранилища
+func NewRecoveryCodes(userId int64) ([]string, []RecoveryCode, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	records := make([]RecoveryCode, 0, RecoveryCodeCount)
+	now := time.Now()
+	for range RecoveryCodeCount {
+		raw := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("generate recovery code: %w", err)
+		}
+		for i := range raw {
+			raw[i] = recoveryCodeAlphabet[raw[i]%32]
+		}
+		code := string(raw)
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, fmt.Errorf("hash recovery code: %w", err)
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+		records = append(records, RecoveryCode{
+			UserId:    userId,
+			CodeHash:  hash,
+			CreatedAt: now,
+		})
+	}
+	return codes, records, nil
+}
+
+// Matches сравнивает код с сохранённым. Регистр, дефисы и пробелы при вводе не важны
+func (c *RecoveryCode) Matches(code string) bool {
+	return bcrypt.CompareHashAndPassword(c.CodeHash, []byte(normalizeRecoveryCode(code))) == nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"time"
+)
+
+type RefreshToken struct {
+	Id        int64     `db:"id"`
+	FamilyId  string    `db:"family_id"`
+	UserId    int64     `db:"user_id"`
+	TokenHash []byte    `db:"token_hash"`
+	ParentId  *int64    `db:"parent_id"`
+	AuthTime  time.Time `db:"auth_time"`
+	// Amr — способы исходного входа через пробел, как и AuthTime общие для всей цепочки
+	Amr       string     `db:"amr"`
+	IssuedAt  time.Time  `db:"issued_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	RotatedAt *time.Time `db:"rotated_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+// NewRefreshToken готовит запись для хранилища. Сам токен не сохраняется, только его хэш.
+// authTime и amr — момент и способы исходного входа, одинаковые для всей цепочки
+func NewRefreshToken(userId int64, familyId, token string, authTime time.Time, amr []string, expiresAt time.Time, parentId *int64) *RefreshToken {
+	return &RefreshToken{
+		FamilyId:  familyId,
+		UserId:    userId,
+		TokenHash: HashRefreshToken(token),
+		ParentId:  parentId,
+		AuthTime:  authTime,
+		Amr:       JoinAmr(amr),
+		IssuedAt:  time.Now(),
+		ExpiresAt: expiresAt,
+	}
+}
+
+// HashRefreshToken — refresh токен высокоэнтропийный, поэтому достаточно sha256 без соли
+func HashRefreshToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// NewTokenFamilyId генерирует UUID v4 для новой цепочки refresh токенов
+func NewTokenFamilyId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// IsUUID проверяет, что id записан как UUID (8-4-4-4-12 шестнадцатеричных цифр). Идентификаторы сессий и цепочек
+// хранятся в столбцах uuid, и Postgres отклоняет запрос с другой строкой ошибкой, а не пустым результатом
+func IsUUID(id string) bool {
+	if len(id) != 36 {
+		return false
+	}
+	for i, r := range id {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (t *RefreshToken) IsRotated() bool {
+	return t.RotatedAt != nil
+}
+
+func (t *RefreshToken) IsExpired(now time.Time) bool {
+	return !t.ExpiresAt.After(now)
+}
+
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
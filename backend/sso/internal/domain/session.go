@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/useragent"
+)
+
+// Session — один вход пользователя на одном устройстве. Идентификатор совпадает с family_id
+// цепочки refresh токенов, поэтому отзыв сессии делает её refresh токен непригодным
+type Session struct {
+	Id       string `db:"id"`
+	UserId   int64  `db:"user_id"`
+	ClientId string `db:"client_id"`
+	// SsoSessionId — сессия SSO в браузере, из которой получена сессия клиента; nil у самостоятельных сессий
+	SsoSessionId    *string    `db:"sso_session_id"`
+	Device          string     `db:"device"`
+	UserAgent       string     `db:"user_agent"`
+	IpAddress       string     `db:"ip_address"`
+	CreatedAt       time.Time  `db:"created_at"`
+	LastRefreshedAt time.Time  `db:"last_refreshed_at"`
+	ExpiresAt       time.Time  `db:"expires_at"`
+	RevokedAt       *time.Time `db:"revoked_at"`
+}
+
+// SessionMeta — откуда пришёл запрос на вход или обновление токенов
+type SessionMeta struct {
+	UserAgent string
+	IpAddress string
+}
+
+// NewSession готовит запись о сессии. createdAt — момент входа (auth_time), одинаковый для всех обновлений
+func NewSession(id string, userId int64, clientId string, meta SessionMeta, createdAt, expiresAt time.Time) *Session {
+	return &Session{
+		Id:              id,
+		UserId:          userId,
+		ClientId:        clientId,
+		Device:          useragent.Describe(meta.UserAgent),
+		UserAgent:       meta.UserAgent,
+		IpAddress:       meta.IpAddress,
+		CreatedAt:       createdAt,
+		LastRefreshedAt: time.Now(),
+		ExpiresAt:       expiresAt,
+	}
+}
@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// RevokedToken — отзыв конкретного токена по jti
+type RevokedToken struct {
+	Jti       string    `db:"jti"`
+	UserId    *int64    `db:"user_id"`
+	ExpiresAt time.Time `db:"expires_at"`
+	RevokedAt time.Time `db:"revoked_at"`
+	RevokedBy *int64    `db:"revoked_by"`
+	Reason    *string   `db:"reason"`
+}
+
+// UserTokenVersion — текущая версия токенов пользователя, токены с меньшей версией недействительны
+type UserTokenVersion struct {
+	UserId       int64 `db:"id"`
+	TokenVersion int64 `db:"token_version"`
+}
+
+// RevocationCutoff отзывает все токены, выпущенные раньше RevokedBefore.
+// UserId == nil — отсечение для всех пользователей.
+type RevocationCutoff struct {
+	Id            int64     `db:"id"`
+	UserId        *int64    `db:"user_id"`
+	RevokedBefore time.Time `db:"revoked_before"`
+	CreatedAt     time.Time `db:"created_at"`
+	CreatedBy     *int64    `db:"created_by"`
+	Reason        *string   `db:"reason"`
+}
@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// UserIdentity связывает локального пользователя с учётной записью внешнего провайдера.
+// Subject — постоянный идентификатор пользователя у провайдера, логин у провайдера может меняться
+type UserIdentity struct {
+	Id          int64      `db:"id"`
+	Provider    string     `db:"provider"`
+	Subject     string     `db:"subject"`
+	UserId      int64      `db:"user_id"`
+	Login       string     `db:"login"`
+	CreatedAt   time.Time  `db:"created_at"`
+	LastLoginAt *time.Time `db:"last_login_at"`
+}
+
+func NewUserIdentity(provider, subject string, userId int64, login string) *UserIdentity {
+	now := time.Now()
+	return &UserIdentity{
+		Provider:    provider,
+		Subject:     subject,
+		UserId:      userId,
+		Login:       login,
+		CreatedAt:   now,
+		LastLoginAt: &now,
+	}
+}
+
+// UpstreamLogin — незавершённый вход через внешнего провайдера: от редиректа к провайдеру до callback.
+// state хранится только хэшем; nonce и code_verifier нужны при обмене кода и провайдеру без них бесполезны
+type UpstreamLogin struct {
+	Id           int64  `db:"id"`
+	StateHash    []byte `db:"state_hash"`
+	Provider     string `db:"provider"`
+	ClientId     string `db:"client_id"`
+	Nonce        string `db:"nonce"`
+	CodeVerifier string `db:"code_verifier"`
+	// ReturnTo — куда вернуть браузер после входа; пустой — ответить токенами
+	ReturnTo   string     `db:"return_to"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	ConsumedAt *time.Time `db:"consumed_at"`
+}
+
+// NewUpstreamLogin генерирует state, nonce и PKCE code_verifier и готовит запись для хранилища.
+// Возвращает сам state для редиректа
+func NewUpstreamLogin(provider, clientId, returnTo string, ttl time.Duration) (string, *UpstreamLogin, error) {
+	state, err := randomToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("generate state: %w", err)
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("generate code verifier: %w", err)
+	}
+
+	now := time.Now()
+	return state, &UpstreamLogin{
+		StateHash:    HashUpstreamState(state),
+		Provider:     provider,
+		ClientId:     clientId,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ReturnTo:     returnTo,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(ttl),
+	}, nil
+}
+
+func HashUpstreamState(state string) []byte {
+	sum := sha256.Sum256([]byte(state))
+	return sum[:]
+}
+
+// CodeChallenge — PKCE S256 для code_verifier (RFC 7636)
+func (l *UpstreamLogin) CodeChallenge() string {
+	sum := sha256.Sum256([]byte(l.CodeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (l *UpstreamLogin) IsExpired(now time.Time) bool {
+	return !l.ExpiresAt.After(now)
+}
+
+// randomToken — 32 случайных байта в base64url, годится и как PKCE code_verifier (43 символа)
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"time"
+)
+
+// Виды церемоний WebAuthn
+const (
+	WebAuthnCeremonyRegistration = "registration"
+	// WebAuthnCeremonyLogin — вход по passkey без пароля
+	WebAuthnCeremonyLogin = "login"
+	// WebAuthnCeremonySecondFactor — ключ как второй фактор после пароля
+	WebAuthnCeremonySecondFactor = "second_factor"
+)
+
+// WebAuthnCredential — зарегистрированный ключ WebAuthn (passkey или аппаратный ключ) пользователя
+type WebAuthnCredential struct {
+	Id           int64  `db:"id"`
+	UserId       int64  `db:"user_id"`
+	CredentialId []byte `db:"credential_id"`
+	// PublicKey — публичный ключ в формате COSE_Key
+	PublicKey []byte `db:"public_key"`
+	// SignCount — счётчик подписей с последнего входа; не растущий счётчик выдаёт копию ключа
+	SignCount  int64    `db:"sign_count"`
+	Transports []string `db:"-"`
+	Name       string   `db:"name"`
+	// BackupEligible — ключ синхронизируется между устройствами
+	BackupEligible bool       `db:"backup_eligible"`
+	CreatedAt      time.Time  `db:"created_at"`
+	LastUsedAt     *time.Time `db:"last_used_at"`
+}
+
+// WebAuthnCeremony — начатая церемония WebAuthn. Хранится sha256 от вызова: ответ аутентификатора
+// содержит вызов, по нему церемония и находится. UserId пуст у входа по passkey, пользователь ещё не известен
+type WebAuthnCeremony struct {
+	Id            int64     `db:"id"`
+	ChallengeHash []byte    `db:"challenge_hash"`
+	Kind          string    `db:"kind"`
+	UserId        *int64    `db:"user_id"`
+	ClientId      string    `db:"client_id"`
+	CreatedAt     time.Time `db:"created_at"`
+	ExpiresAt     time.Time `db:"expires_at"`
+}
+
+func NewWebAuthnCeremony(kind string, userId *int64, clientId string, challenge []byte, ttl time.Duration) *WebAuthnCeremony {
+	now := time.Now()
+	return &WebAuthnCeremony{
+		ChallengeHash: HashWebAuthnChallenge(challenge),
+		Kind:          kind,
+		UserId:        userId,
+		ClientId:      clientId,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(ttl),
+	}
+}
+
+func HashWebAuthnChallenge(challenge []byte) []byte {
+	sum := sha256.Sum256(challenge)
+	return sum[:]
+}
+
+// WebAuthnUserHandle — user.id в WebAuthn. Аутентификатор возвращает его при входе по passkey;
+// пользователь ищется по ключу, а user handle только сверяется с ним
+func WebAuthnUserHandle(userId int64) []byte {
+	return []byte(strconv.FormatInt(userId, 10))
+}
@@ -0,0 +1,19 @@
+package admin
+
+import "time"
+
+// RevokeTokensRequest описывает, какие токены отозвать.
+// Либо jti конкретного токена, либо user_id и/или issued_before:
+// user_id — все токены пользователя (выпущенные до issued_before или до текущего момента),
+// только issued_before — все токены всех пользователей, выпущенные раньше.
+// swagger:model RevokeTokensRequest
+type RevokeTokensRequest struct {
+	// Идентификатор токена (claim jti)
+	Jti string `json:"jti,omitempty" example:"3q2-7wEAAAAAAAAAAAAAAA"`
+	// Идентификатор пользователя
+	UserID *int64 `json:"user_id,omitempty" example:"42"`
+	// Отозвать токены, выпущенные раньше этого момента
+	IssuedBefore *time.Time `json:"issued_before,omitempty" example:"2025-01-01T00:00:00Z"`
+	// Причина отзыва для аудита
+	Reason string `json:"reason,omitempty" example:"account compromised"`
+}
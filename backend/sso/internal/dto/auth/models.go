@@ -1,5 +1,11 @@
 package auth
 
+import (
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/webauthn"
+)
+
 // AuthRequest содержит учетные данные для авторизации
 // swagger:model AuthRequest
 type AuthRequest struct {
@@ -7,12 +13,12 @@ type AuthRequest struct {
 	Login string `json:"login" example:"user@example.com"`
 	// Telegram username (используется при регистрации)
 	TelegramUsername string `json:"telegram_username,omitempty" example:"my_telegram"`
-	// Telegram chat ID для работы с ботом
-	TelegramChatID *int64 `json:"telegram_chat_id,omitempty" example:"123456789"`
 	// Пароль пользователя
 	Password string `json:"password" example:"P@ssw0rd!"`
 	// Полное имя (используется при регистрации)
 	FullName string `json:"full_name,omitempty" example:"Иван Иванов"`
+	// Идентификатор клиента, для которого выпускаются токены (по умолчанию jwt.default_client)
+	ClientID string `json:"client_id,omitempty" example:"shop"`
 }
 
 // AuthResponse возвращает JWT токены после успешной аутентификации
@@ -26,6 +32,93 @@ type AuthResponse struct {
 	UserID int64 `json:"user_id"`
 	// Название роли пользователя
 	Role string `json:"role"`
+	// Идентификатор сессии (совпадает с claim sid)
+	SessionID string `json:"session_id"`
+	// Через сколько секунд истечёт access токен
+	ExpiresIn int64 `json:"expires_in"`
+	// Время истечения refresh токена, по нему же выставляется срок жизни cookie
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	// Нужен второй фактор: токенов нет, вход завершается через /auth/mfa/verify
+	MfaRequired bool `json:"mfa_required,omitempty"`
+	// Токен второго шага входа
+	MfaToken string `json:"mfa_token,omitempty"`
+	// Чем можно пройти второй шаг: totp, webauthn, recovery_code
+	MfaMethods []string `json:"mfa_methods,omitempty"`
+}
+
+// MfaVerifyRequest — второй шаг входа по паролю
+// swagger:model MfaVerifyRequest
+type MfaVerifyRequest struct {
+	// Токен из ответа /auth/logIn
+	MfaToken string `json:"mfa_token"`
+	// Код из приложения-аутентификатора
+	Code string `json:"code,omitempty" example:"123456"`
+	// Код восстановления вместо кода из приложения
+	RecoveryCode string `json:"recovery_code,omitempty" example:"abcde-fghij"`
+	// Ответ ключа WebAuthn на параметры из /auth/mfa/webauthn/options
+	WebAuthn *webauthn.AssertionResponse `json:"webauthn,omitempty"`
+}
+
+// MfaOptionsRequest — начало проверки ключа WebAuthn на втором шаге входа
+// swagger:model MfaOptionsRequest
+type MfaOptionsRequest struct {
+	// Токен из ответа /auth/logIn
+	MfaToken string `json:"mfa_token"`
+}
+
+// LoginCodeRequest — запрос одноразового кода для входа без пароля
+// swagger:model LoginCodeRequest
+type LoginCodeRequest struct {
+	// Логин пользователя
+	Login string `json:"login" example:"user@example.com"`
+	// Клиент, для которого будут выпущены токены; пустой — клиент по умолчанию
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// LoginCodeResponse — токен запроса входа; код приходит пользователю в Telegram или на почту
+// swagger:model LoginCodeResponse
+type LoginCodeResponse struct {
+	// Передаётся в /auth/code/verify вместе с кодом
+	LoginToken string `json:"login_token"`
+	// Через сколько секунд код перестанет действовать
+	ExpiresIn int64 `json:"expires_in" example:"600"`
+}
+
+// LoginCodeVerifyRequest — вход по одноразовому коду
+// swagger:model LoginCodeVerifyRequest
+type LoginCodeVerifyRequest struct {
+	// Токен из ответа /auth/code/request
+	LoginToken string `json:"login_token"`
+	// Код из сообщения
+	Code string `json:"code" example:"123456"`
+}
+
+// TelegramAuthRequest — данные Telegram Login Widget как есть, вместе с hash
+// swagger:model TelegramAuthRequest
+type TelegramAuthRequest struct {
+	// Идентификатор пользователя Telegram, совпадает с telegram_chat_id
+	ID int64 `json:"id" example:"123456789"`
+	// Имя в Telegram
+	FirstName string `json:"first_name,omitempty" example:"Иван"`
+	// Фамилия в Telegram
+	LastName string `json:"last_name,omitempty"`
+	// Тег Telegram без @
+	Username string `json:"username,omitempty" example:"my_telegram"`
+	// Аватар в Telegram
+	PhotoURL string `json:"photo_url,omitempty"`
+	// Время входа в Telegram, unix секунды
+	AuthDate int64 `json:"auth_date" example:"1700000000"`
+	// Подпись Telegram, hex
+	Hash string `json:"hash"`
+	// Идентификатор клиента, для которого выпускаются токены (по умолчанию jwt.default_client)
+	ClientID string `json:"client_id,omitempty" example:"shop"`
+}
+
+// TelegramWebAppAuthRequest — initData Telegram Mini App
+// swagger:model TelegramWebAppAuthRequest
+type TelegramWebAppAuthRequest struct {
+	// Строка Telegram.WebApp.initData без изменений
+	InitData string `json:"init_data" example:"query_id=...&user=%7B%22id%22%3A123456789%7D&auth_date=1700000000&hash=..."`
 }
 
 // swagger:model PasswordResetRequest
@@ -34,6 +127,18 @@ type PasswordResetRequest struct {
 	Login string `json:"login" example:"user123"`
 }
 
+// PasswordPolicyViolations — нарушенные правила политики паролей, приходят в data при отказе в регистрации или сбросе пароля
+type PasswordPolicyViolations struct {
+	Violations []PasswordPolicyViolation `json:"violations"`
+}
+
+type PasswordPolicyViolation struct {
+	// Код правила: min_length, max_length, lowercase, uppercase, digit, symbol, personal_data, breached
+	Rule string `json:"rule" example:"min_length"`
+	// Текст для пользователя
+	Message string `json:"message" example:"Пароль должен быть не короче 8 символов"`
+}
+
 // swagger:model PasswordResetComplete
 type PasswordResetComplete struct {
 	// Токен сброса пароля
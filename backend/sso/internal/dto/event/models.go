@@ -0,0 +1,20 @@
+package event
+
+import "time"
+
+// AuthEventResponse — запись журнала событий аутентификации
+// swagger:model AuthEventResponse
+type AuthEventResponse struct {
+	// Идентификатор записи
+	ID int64 `json:"id"`
+	// Тип события
+	Type string `json:"type" example:"mfa_recovery_code_used"`
+	// Клиент, в который выполнялся вход; пустой у событий вне входа
+	ClientID string `json:"client_id,omitempty" example:"shop"`
+	// User-Agent запроса
+	UserAgent string `json:"user_agent"`
+	// IP адрес запроса
+	IpAddress string `json:"ip_address" example:"203.0.113.7"`
+	// Время события
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,12 @@
+package federation
+
+// ProviderResponse — внешний провайдер, через которого можно войти
+// swagger:model UpstreamProviderResponse
+type ProviderResponse struct {
+	// Идентификатор провайдера
+	ID string `json:"id" example:"corp"`
+	// Название для кнопки входа
+	Name string `json:"name" example:"Корпоративный вход"`
+	// Адрес, с которого начинается вход (принимает client_id и return_to)
+	LoginURL string `json:"login_url" example:"/auth/external/corp/login"`
+}
@@ -0,0 +1,38 @@
+package mfa
+
+import "github.com/EtoNeAnanasbI95/sso/internal/lib/webauthn"
+
+// TOTPEnrollmentResponse — новый TOTP секрет, который пользователь добавляет в приложение-аутентификатор
+// swagger:model TOTPEnrollmentResponse
+type TOTPEnrollmentResponse struct {
+	// Секрет в base32 для ручного ввода
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	// Ссылка otpauth:// для QR кода
+	URI string `json:"otpauth_uri" example:"otpauth://totp/SSO:user@example.com?secret=...&issuer=SSO"`
+}
+
+// TOTPCodeRequest — код из приложения-аутентификатора
+// swagger:model TOTPCodeRequest
+type TOTPCodeRequest struct {
+	// Шестизначный код
+	Code string `json:"code" example:"123456"`
+}
+
+// FactorProofRequest — подтверждение второго фактора перед его отключением или выпуском новых кодов восстановления:
+// код из приложения-аутентификатора, ответ ключа WebAuthn или, если телефон потерян, неиспользованный код восстановления
+// swagger:model FactorProofRequest
+type FactorProofRequest struct {
+	// Шестизначный код из приложения
+	Code string `json:"code,omitempty" example:"123456"`
+	// Код восстановления; после проверки больше не принимается
+	RecoveryCode string `json:"recovery_code,omitempty" example:"abcde-fghij"`
+	// Ответ ключа WebAuthn на параметры из /me/mfa/webauthn/options
+	WebAuthn *webauthn.AssertionResponse `json:"webauthn,omitempty"`
+}
+
+// RecoveryCodesResponse — новые коды восстановления. Показываются один раз, прежний набор больше не действует
+// swagger:model RecoveryCodesResponse
+type RecoveryCodesResponse struct {
+	// Одноразовые коды, каждый заменяет код из приложения при одном входе
+	RecoveryCodes []string `json:"recovery_codes" example:"abcde-fghij,klmno-pqrst"`
+}
@@ -0,0 +1,60 @@
+package oauth
+
+// IntrospectionRequest — тело запроса RFC 7662 (application/x-www-form-urlencoded)
+// swagger:model IntrospectionRequest
+type IntrospectionRequest struct {
+	// Проверяемый токен
+	Token string `form:"token"`
+	// Подсказка о типе токена: access_token или refresh_token
+	TokenTypeHint string `form:"token_type_hint"`
+	// Идентификатор вызывающего клиента, если он не передан через Basic auth
+	ClientID string `form:"client_id"`
+	// Секрет вызывающего клиента, если он не передан через Basic auth
+	ClientSecret string `form:"client_secret"`
+}
+
+// IntrospectionResponse — ответ RFC 7662. Для недействительного токена заполнено только active
+// swagger:model IntrospectionResponse
+type IntrospectionResponse struct {
+	// Действителен ли токен прямо сейчас
+	Active bool `json:"active"`
+	// Владелец токена: user или service
+	Ptype string `json:"ptype,omitempty" example:"user"`
+	// Идентификатор пользователя, у сервисного токена — идентификатор клиента
+	Sub string `json:"sub,omitempty" example:"42"`
+	// Разрешения сервисного токена через пробел
+	Scope string `json:"scope,omitempty" example:"users:register"`
+	// Логин пользователя
+	Username string `json:"username,omitempty" example:"user@example.com"`
+	// Роль пользователя
+	Role string `json:"role,omitempty" example:"customer"`
+	// Тип токена: access_token или refresh_token
+	TokenType string `json:"token_type,omitempty" example:"access_token"`
+	// Клиент, которому выпущен токен
+	ClientID string `json:"client_id,omitempty" example:"shop"`
+	// Издатель токена
+	Iss string `json:"iss,omitempty" example:"https://sso.example.com"`
+	// Получатели токена
+	Aud []string `json:"aud,omitempty" example:"shop"`
+	// Время истечения (unix)
+	Exp int64 `json:"exp,omitempty"`
+	// Время выпуска (unix)
+	Iat int64 `json:"iat,omitempty"`
+	// Время исходной аутентификации пользователя (unix)
+	AuthTime int64 `json:"auth_time,omitempty"`
+	// Способы исходной аутентификации (RFC 8176)
+	Amr []string `json:"amr,omitempty" example:"pwd,otp,mfa"`
+	// Уровень исходной аутентификации: 1 — один фактор, 2 — несколько
+	Acr string `json:"acr,omitempty" example:"2"`
+	// Идентификатор токена
+	Jti string `json:"jti,omitempty"`
+}
+
+// ErrorResponse — ошибка в формате RFC 6749 (раздел 5.2)
+// swagger:model OAuthErrorResponse
+type ErrorResponse struct {
+	// Код ошибки
+	Error string `json:"error" example:"invalid_client"`
+	// Описание ошибки
+	ErrorDescription string `json:"error_description,omitempty"`
+}
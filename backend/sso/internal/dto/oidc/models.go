@@ -0,0 +1,151 @@
+package oidc
+
+// AuthorizeRequest — параметры /authorize (OpenID Connect Core, 3.1.2.1)
+// swagger:model AuthorizeRequest
+type AuthorizeRequest struct {
+	// Должен быть code
+	ResponseType string `query:"response_type"`
+	// Идентификатор клиента
+	ClientID string `query:"client_id"`
+	// Один из зарегистрированных redirect_uris клиента
+	RedirectURI string `query:"redirect_uri"`
+	// Должен содержать openid
+	Scope string `query:"scope"`
+	// Значение возвращается клиенту без изменений
+	State string `query:"state"`
+	// Попадает в ID токен
+	Nonce string `query:"nonce"`
+	// BASE64URL(SHA256(code_verifier))
+	CodeChallenge string `query:"code_challenge"`
+	// Поддерживается только S256
+	CodeChallengeMethod string `query:"code_challenge_method"`
+	// none — не показывать страницу входа, вернуть login_required
+	Prompt string `query:"prompt"`
+	// Сколько секунд может пройти с входа пользователя; если больше — войти заново
+	MaxAge string `query:"max_age"`
+}
+
+// TokenRequest — тело запроса /token (application/x-www-form-urlencoded)
+// swagger:model TokenRequest
+type TokenRequest struct {
+	// authorization_code, refresh_token, client_credentials или urn:ietf:params:oauth:grant-type:device_code
+	GrantType string `form:"grant_type"`
+	// Код из /authorize
+	Code string `form:"code"`
+	// Тот же redirect_uri, что и в /authorize
+	RedirectURI string `form:"redirect_uri"`
+	// Идентификатор клиента
+	ClientID string `form:"client_id"`
+	// Секрет конфиденциального клиента (client_secret_post); вместо него можно передать Basic авторизацию
+	ClientSecret string `form:"client_secret"`
+	// PKCE code_verifier
+	CodeVerifier string `form:"code_verifier"`
+	// Refresh токен для grant_type=refresh_token
+	RefreshToken string `form:"refresh_token"`
+	// Запрашиваемые scope через пробел для grant_type=client_credentials
+	Scope string `form:"scope"`
+	// device_code из /oauth/device/code
+	DeviceCode string `form:"device_code"`
+}
+
+// TokenResponse — ответ /token (RFC 6749, 5.1)
+// swagger:model TokenResponse
+type TokenResponse struct {
+	// Access токен
+	AccessToken string `json:"access_token"`
+	// Всегда Bearer
+	TokenType string `json:"token_type" example:"Bearer"`
+	// Через сколько секунд истечёт access токен
+	ExpiresIn int64 `json:"expires_in" example:"3600"`
+	// Refresh токен, не выдаётся для client_credentials
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// ID токен OpenID Connect
+	IDToken string `json:"id_token,omitempty"`
+	// Выданный scope
+	Scope string `json:"scope,omitempty" example:"openid profile"`
+}
+
+// UserInfoResponse — ответ /userinfo
+// swagger:model UserInfoResponse
+type UserInfoResponse struct {
+	// Идентификатор пользователя
+	Sub string `json:"sub" example:"42"`
+	// Логин пользователя
+	PreferredUsername string `json:"preferred_username" example:"user@example.com"`
+	// Полное имя
+	Name string `json:"name,omitempty" example:"Иван Иванов"`
+	// Роль пользователя
+	Role string `json:"role" example:"customer"`
+}
+
+// DiscoveryDocument — /.well-known/openid-configuration (OpenID Connect Discovery 1.0)
+// swagger:model DiscoveryDocument
+type DiscoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	BackchannelLogoutSupported        bool     `json:"backchannel_logout_supported"`
+	BackchannelLogoutSessionSupported bool     `json:"backchannel_logout_session_supported"`
+}
+
+// DeviceAuthorizationRequest — тело запроса /oauth/device/code (RFC 8628, 3.1)
+// swagger:model DeviceAuthorizationRequest
+type DeviceAuthorizationRequest struct {
+	// Идентификатор клиента
+	ClientID string `form:"client_id"`
+	// Секрет конфиденциального клиента, если он не передан через Basic авторизацию
+	ClientSecret string `form:"client_secret"`
+	// Запрашиваемые scope через пробел
+	Scope string `form:"scope"`
+}
+
+// DeviceAuthorizationResponse — ответ /oauth/device/code (RFC 8628, 3.2)
+// swagger:model DeviceAuthorizationResponse
+type DeviceAuthorizationResponse struct {
+	// Код, которым устройство опрашивает /token
+	DeviceCode string `json:"device_code"`
+	// Код, который пользователь вводит на странице подтверждения
+	UserCode string `json:"user_code" example:"WDJB-MJHT"`
+	// Страница подтверждения
+	VerificationURI string `json:"verification_uri" example:"https://shop.example.com/device"`
+	// Страница подтверждения с уже подставленным кодом
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	// Через сколько секунд коды истекут
+	ExpiresIn int64 `json:"expires_in" example:"600"`
+	// Минимальный интервал опроса /token в секундах
+	Interval int64 `json:"interval" example:"5"`
+}
+
+// DeviceInfoResponse — что увидит пользователь перед подтверждением устройства
+// swagger:model DeviceInfoResponse
+type DeviceInfoResponse struct {
+	// Код устройства
+	UserCode string `json:"user_code" example:"WDJB-MJHT"`
+	// Клиент, который просит доступ
+	ClientID string `json:"client_id" example:"bot"`
+	// Название клиента
+	ClientName string `json:"client_name" example:"Telegram бот"`
+	// Запрошенные scope
+	Scope string `json:"scope,omitempty"`
+}
+
+// DeviceDecisionRequest — решение пользователя по запросу устройства
+// swagger:model DeviceDecisionRequest
+type DeviceDecisionRequest struct {
+	// Код, показанный устройством
+	UserCode string `json:"user_code" example:"WDJB-MJHT"`
+	// true — разрешить доступ, false — отклонить
+	Approve bool `json:"approve"`
+}
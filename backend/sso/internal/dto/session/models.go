@@ -0,0 +1,26 @@
+package session
+
+import "time"
+
+// SessionResponse — активная сессия пользователя
+// swagger:model SessionResponse
+type SessionResponse struct {
+	// Идентификатор сессии
+	ID string `json:"id" example:"7c9e6679-7425-40de-944b-e07fc1f90ae7"`
+	// Клиент, в котором выполнен вход
+	ClientID string `json:"client_id" example:"shop"`
+	// Описание устройства, полученное из User-Agent
+	Device string `json:"device" example:"Chrome on Windows"`
+	// Исходный User-Agent
+	UserAgent string `json:"user_agent"`
+	// IP адрес последнего входа или обновления токенов
+	IpAddress string `json:"ip_address" example:"203.0.113.7"`
+	// Время входа
+	CreatedAt time.Time `json:"created_at"`
+	// Время последнего обновления токенов
+	LastRefreshedAt time.Time `json:"last_refreshed_at"`
+	// Когда сессия истечёт, если её не обновлять
+	ExpiresAt time.Time `json:"expires_at"`
+	// Сессия, из которой выполнен текущий запрос
+	Current bool `json:"current"`
+}
@@ -0,0 +1,56 @@
+package webauthn
+
+import (
+	"time"
+
+	libwebauthn "github.com/EtoNeAnanasbI95/sso/internal/lib/webauthn"
+)
+
+// RegisterRequest — ответ navigator.credentials.create и название ключа
+// swagger:model WebAuthnRegisterRequest
+type RegisterRequest struct {
+	// Название ключа, чтобы отличать его в списке
+	Name string `json:"name,omitempty" example:"MacBook Touch ID"`
+	// PublicKeyCredential в JSON (бинарные поля в base64url)
+	Credential libwebauthn.RegistrationResponse `json:"credential"`
+}
+
+// LoginOptionsRequest — начало входа по passkey
+// swagger:model WebAuthnLoginOptionsRequest
+type LoginOptionsRequest struct {
+	// Идентификатор клиента, для которого выпускаются токены (по умолчанию jwt.default_client)
+	ClientID string `json:"client_id,omitempty" example:"shop"`
+}
+
+// LoginRequest — ответ navigator.credentials.get
+// swagger:model WebAuthnLoginRequest
+type LoginRequest struct {
+	// PublicKeyCredential в JSON (бинарные поля в base64url)
+	Credential libwebauthn.AssertionResponse `json:"credential"`
+}
+
+// RegisterResponse — зарегистрированный ключ. Если это первый второй фактор пользователя, в ответе и первый набор
+// кодов восстановления: он показывается один раз
+// swagger:model WebAuthnRegisterResponse
+type RegisterResponse struct {
+	CredentialResponse
+	// Одноразовые коды восстановления, только при подключении первого второго фактора
+	RecoveryCodes []string `json:"recovery_codes,omitempty" example:"abcde-fghij,klmno-pqrst"`
+}
+
+// CredentialResponse — зарегистрированный ключ пользователя
+// swagger:model WebAuthnCredentialResponse
+type CredentialResponse struct {
+	// Идентификатор ключа в SSO
+	ID int64 `json:"id"`
+	// Название ключа
+	Name string `json:"name" example:"MacBook Touch ID"`
+	// Способы связи с аутентификатором: internal, usb, nfc, ble, hybrid
+	Transports []string `json:"transports"`
+	// Ключ синхронизируется между устройствами
+	BackupEligible bool `json:"backup_eligible"`
+	// Время регистрации
+	CreatedAt time.Time `json:"created_at"`
+	// Время последнего входа этим ключом
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
@@ -7,4 +7,7 @@ var (
 	ErrUserAlreadyExists      = errors.New("пользователь уже существует")
 	ErrUserNotFound           = errors.New("пользователь не существует")
 	ErrInvalidResetToken      = errors.New("токен сброса пароля недействителен или истёк")
+	ErrInvalidRefreshToken    = errors.New("refresh токен недействителен или отозван")
+	ErrRefreshTokenReused     = errors.New("refresh токен уже использован, сессия завершена")
+	ErrSessionExpired         = errors.New("сессия истекла, войдите заново")
 )
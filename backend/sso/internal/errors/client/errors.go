@@ -0,0 +1,9 @@
+package client
+
+import "errors"
+
+var (
+	ErrUnknownClient       = errors.New("неизвестный клиент")
+	ErrInvalidClientSecret = errors.New("неверный секрет клиента")
+	ErrGrantNotAllowed     = errors.New("клиенту не разрешён этот способ получения токенов")
+)
@@ -0,0 +1,7 @@
+package device
+
+import "errors"
+
+var (
+	ErrUserCodeNotFound = errors.New("код устройства не найден или истёк")
+)
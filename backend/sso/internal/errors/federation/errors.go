@@ -0,0 +1,13 @@
+package federation
+
+import "errors"
+
+var (
+	ErrUnknownProvider   = errors.New("внешний провайдер не найден")
+	ErrInvalidReturnTo   = errors.New("адрес возврата не разрешён для клиента")
+	ErrInvalidState      = errors.New("вход через внешний провайдер не найден или истёк, начните заново")
+	ErrUpstreamFailed    = errors.New("внешний провайдер не подтвердил вход")
+	ErrIdentityNotLinked = errors.New("учётная запись провайдера не связана с пользователем")
+	ErrLoginTaken        = errors.New("логин уже занят другим пользователем")
+	ErrMissingLogin      = errors.New("провайдер не передал логин пользователя")
+)
@@ -2,7 +2,8 @@ package jwt
 
 import "errors"
 
-
 var (
-	ErrInvalidToken = errors.New("invalid token")
-)
\ No newline at end of file
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrInvalidAudience = errors.New("token is not intended for this audience")
+	ErrSessionExpired  = errors.New("session has reached its maximum lifetime")
+)
@@ -0,0 +1,12 @@
+package mfa
+
+import "errors"
+
+var (
+	ErrNotConfigured       = errors.New("двухфакторная аутентификация не настроена")
+	ErrAlreadyEnrolled     = errors.New("двухфакторная аутентификация уже включена")
+	ErrNotEnrolled         = errors.New("двухфакторная аутентификация не включена")
+	ErrInvalidCode         = errors.New("неверный код подтверждения")
+	ErrInvalidRecoveryCode = errors.New("неверный или уже использованный код восстановления")
+	ErrInvalidChallenge    = errors.New("вход истёк или превышено число попыток, войдите заново")
+)
@@ -0,0 +1,22 @@
+package oauth
+
+import "errors"
+
+var (
+	ErrInvalidClient  = errors.New("invalid_client")
+	ErrInvalidRequest = errors.New("invalid_request")
+	ErrInvalidGrant   = errors.New("invalid_grant")
+	ErrInvalidScope   = errors.New("invalid_scope")
+	ErrLoginRequired  = errors.New("login_required")
+
+	ErrUnauthorizedClient = errors.New("unauthorized_client")
+
+	// ответы опроса /token по RFC 8628
+	ErrAuthorizationPending = errors.New("authorization_pending")
+	ErrSlowDown             = errors.New("slow_down")
+	ErrAccessDenied         = errors.New("access_denied")
+	ErrExpiredToken         = errors.New("expired_token")
+
+	ErrUnsupportedGrantType    = errors.New("unsupported_grant_type")
+	ErrUnsupportedResponseType = errors.New("unsupported_response_type")
+)
@@ -0,0 +1,9 @@
+package passwordless
+
+import "errors"
+
+var (
+	ErrNotConfigured   = errors.New("вход по одноразовому коду не настроен")
+	ErrInvalidCode     = errors.New("неверный или истёкший код входа")
+	ErrTooManyRequests = errors.New("код уже отправлен, запросите новый немного позже")
+)
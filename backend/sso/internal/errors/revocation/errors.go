@@ -0,0 +1,9 @@
+package revocation
+
+import "errors"
+
+var (
+	ErrEmptyRevocationRequest = errors.New("не указано, что отзывать: jti, user_id или issued_before")
+	ErrAmbiguousRevocation    = errors.New("jti нельзя совмещать с user_id и issued_before")
+	ErrIssuedBeforeInFuture   = errors.New("issued_before не может быть в будущем")
+)
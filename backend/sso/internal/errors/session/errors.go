@@ -0,0 +1,7 @@
+package session
+
+import "errors"
+
+var (
+	ErrSessionNotFound = errors.New("сессия не найдена или уже завершена")
+)
@@ -0,0 +1,11 @@
+package telegram
+
+import "errors"
+
+var (
+	ErrNotConfigured    = errors.New("вход через Telegram не настроен")
+	ErrInvalidSignature = errors.New("подпись данных Telegram недействительна")
+	ErrExpired          = errors.New("данные Telegram устарели, войдите заново")
+	ErrNotLinked        = errors.New("Telegram аккаунт не связан с пользователем")
+	ErrAlreadyLinked    = errors.New("этот Telegram аккаунт уже привязан к другому пользователю")
+)
@@ -0,0 +1,11 @@
+package webauthn
+
+import "errors"
+
+var (
+	ErrNotConfigured      = errors.New("вход по ключам WebAuthn не настроен")
+	ErrInvalidCeremony    = errors.New("запрос к ключу истёк или уже использован, начните заново")
+	ErrVerificationFailed = errors.New("не удалось проверить ответ ключа")
+	ErrCredentialNotFound = errors.New("ключ не найден")
+	ErrCredentialExists   = errors.New("ключ уже зарегистрирован")
+)
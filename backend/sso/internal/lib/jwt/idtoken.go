@@ -0,0 +1,79 @@
+package jwt
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IDTokenParams — данные для ID токена OpenID Connect
+type IDTokenParams struct {
+	UserID    int64
+	ClientID  string
+	SessionID string
+	AuthTime  time.Time
+	Amr       []string
+	Acr       string
+	ExpiresAt time.Time
+	// Nonce из запроса /authorize, пустой — не передавался
+	Nonce string
+	// AccessToken, выданный вместе с ID токеном, для at_hash
+	AccessToken string
+	// Claims — дополнительные claims профиля (name, preferred_username, ...)
+	Claims map[string]any
+}
+
+// NewIDToken подписывает ID токен активным ключом. aud — идентификатор клиента
+func (j *JwtLib) NewIDToken(params IDTokenParams) (string, error) {
+	issuedAt := time.Now()
+	claims := jwt.MapClaims{}
+	for name, value := range params.Claims {
+		claims[name] = value
+	}
+	claims["iss"] = j.issuer
+	// по OpenID Connect sub — строка
+	claims["sub"] = strconv.FormatInt(params.UserID, 10)
+	claims["aud"] = params.ClientID
+	claims["azp"] = params.ClientID
+	claims["iat"] = issuedAt.Unix()
+	claims["exp"] = params.ExpiresAt.Unix()
+	claims["auth_time"] = params.AuthTime.Unix()
+	if params.SessionID != "" {
+		claims["sid"] = params.SessionID
+	}
+	if len(params.Amr) > 0 {
+		claims["amr"] = params.Amr
+	}
+	if params.Acr != "" {
+		claims["acr"] = params.Acr
+	}
+	if params.Nonce != "" {
+		claims["nonce"] = params.Nonce
+	}
+	if params.AccessToken != "" {
+		claims["at_hash"] = accessTokenHash(params.AccessToken)
+	}
+
+	key := j.keys.Active()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.signKey)
+}
+
+// Issuer — значение iss во всех выпускаемых токенах
+func (j *JwtLib) Issuer() string {
+	return j.issuer
+}
+
+// Algorithms — алгоритмы, которыми подписаны принимаемые токены, для discovery документа
+func (j *JwtLib) Algorithms() []string {
+	return j.keys.Algorithms()
+}
+
+// accessTokenHash — левая половина SHA-256 от access токена (OpenID Connect Core, 3.1.3.6)
+func accessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return encodeBase64URL(sum[:len(sum)/2])
+}
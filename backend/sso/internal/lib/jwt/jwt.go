@@ -1,11 +1,14 @@
 package jwt
+
 import (
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	jwtErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/jwt"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 type TokenType string
@@ -16,56 +19,193 @@ const (
 )
 
 type TokenClaims struct {
-	UserID int64
-	Role   string
-	Type   TokenType
+	ID string
+	// PrincipalType — пользователь или сервис; у сервиса UserID пуст, а sub совпадает с ClientID
+	PrincipalType PrincipalType
+	UserID        int64
+	Role          string
+	ClientID      string
+	// Scopes — разрешения сервисного токена (claim scope)
+	Scopes    []string
+	SessionID string
+	// TokenVersion — версия токенов пользователя на момент выпуска (claim ver)
+	TokenVersion int64
+	Type         TokenType
+	Issuer       string
+	Audience     []string
+	IssuedAt     time.Time
+	ExpiresAt    time.Time
+	// AuthTime — когда пользователь ввёл учётные данные; не меняется при обновлении токенов
+	AuthTime time.Time
+	// Amr — способы аутентификации, Acr — достигнутый ими уровень; как и AuthTime, не меняются при обновлении
+	Amr []string
+	Acr string
+}
+
+// Options — параметры выпуска и проверки токенов
+type Options struct {
+	// Issuer пишется в iss и обязателен при проверке
+	Issuer string
+	// Audiences — известные aud; токен должен быть выпущен хотя бы для одного из них. nil — проверка aud отключена
+	Audiences Audiences
+	// Leeway — допустимое расхождение часов при проверке exp, nbf и iat
+	Leeway time.Duration
+	// Lifetimes — сроки жизни токенов с учётом роли и клиента
+	Lifetimes LifetimePolicy
+}
+
+// Audiences сообщает, выпускаются ли токены для aud. Реестр клиентов может меняться во время работы
+type Audiences interface {
+	IsKnownAudience(aud string) bool
+}
+
+// TokenParams — для кого и для какого клиента выпускаются токены
+type TokenParams struct {
+	UserID   int64
+	Role     string
+	ClientID string
+	Audience []string
+	// AuthTime — момент исходной аутентификации, нулевое значение означает «сейчас»
+	AuthTime time.Time
+	// Amr и Acr — способы и уровень исходной аутентификации, пустые не пишутся в токен
+	Amr []string
+	Acr string
+	// ClientLifetime — ограничения сроков жизни, заданные клиенту
+	ClientLifetime Lifetime
+	// SessionID пишется в sid, чтобы по access токену можно было найти сессию
+	SessionID string
+	// TokenVersion пишется в ver; принудительный выход увеличивает версию пользователя
+	TokenVersion int64
 }
 
 type JwtLib struct {
-	accessDuration  time.Duration
-	refreshDuration time.Duration
-	secret          []byte
+	issuer    string
+	audiences Audiences
+	leeway    time.Duration
+	lifetimes LifetimePolicy
+	keys      *KeySet
 }
 
-func NewJwtLib(accessDuration time.Duration, secret []byte) *JwtLib {
+func NewJwtLib(keys *KeySet, opts Options) *JwtLib {
 	return &JwtLib{
-		accessDuration:  accessDuration,
-		refreshDuration: time.Hour * 24 * 30,
-		secret:          secret,
+		issuer:    opts.Issuer,
+		audiences: opts.Audiences,
+		leeway:    opts.Leeway,
+		lifetimes: opts.Lifetimes,
+		keys:      keys,
 	}
 }
 
-func (j *JwtLib) NewTokens(userId int64, role string) (accessToken string, refreshToken string, err error) {
-	accessToken, err = j.signToken(userId, role, TokenTypeAccess, j.accessDuration)
+// TokenPair — выпущенная пара токенов и срок жизни refresh токена для хранилища и cookie
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+func (j *JwtLib) NewTokens(params TokenParams) (*TokenPair, error) {
+	now := time.Now()
+	if params.AuthTime.IsZero() {
+		params.AuthTime = now
+	}
+
+	lifetime := j.lifetimes.Resolve(params.Role, params.ClientLifetime)
+	accessExpiresAt := now.Add(lifetime.Access)
+	refreshExpiresAt := now.Add(lifetime.Refresh)
+	// ни один токен не переживает абсолютный предел сессии
+	if lifetime.Session > 0 {
+		sessionEnd := params.AuthTime.Add(lifetime.Session)
+		if !sessionEnd.After(now) {
+			return nil, jwtErrors.ErrSessionExpired
+		}
+		accessExpiresAt = minTime(accessExpiresAt, sessionEnd)
+		refreshExpiresAt = minTime(refreshExpiresAt, sessionEnd)
+	}
+
+	accessToken, err := j.signToken(params, TokenTypeAccess, now, accessExpiresAt)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-	refreshToken, err = j.signToken(userId, role, TokenTypeRefresh, j.refreshDuration)
+	refreshToken, err := j.signToken(params, TokenTypeRefresh, now, refreshExpiresAt)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-	return
+	return &TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
 }
 
-func (j *JwtLib) signToken(userId int64, role string, tokenType TokenType, duration time.Duration) (string, error) {
+func (j *JwtLib) signToken(params TokenParams, tokenType TokenType, issuedAt, expiresAt time.Time) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
 	claims := jwt.MapClaims{
-		"sub":  userId,
-		"role": role,
-		"typ":  string(tokenType),
-		"exp":  time.Now().Add(duration).Unix(),
+		"jti":       jti,
+		"iss":       j.issuer,
+		"sub":       params.UserID,
+		"aud":       params.Audience,
+		"role":      params.Role,
+		"typ":       string(tokenType),
+		"iat":       issuedAt.Unix(),
+		"nbf":       issuedAt.Unix(),
+		"exp":       expiresAt.Unix(),
+		"auth_time": params.AuthTime.Unix(),
+		"ver":       params.TokenVersion,
+		"ptype":     string(PrincipalUser),
+	}
+	if params.ClientID != "" {
+		claims["client_id"] = params.ClientID
+	}
+	if params.SessionID != "" {
+		claims["sid"] = params.SessionID
+	}
+	if len(params.Amr) > 0 {
+		claims["amr"] = params.Amr
+	}
+	if params.Acr != "" {
+		claims["acr"] = params.Acr
+	}
+
+	key := j.keys.Active()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	signed, err := token.SignedString(key.signKey)
+	if err != nil {
+		return "", err
+	}
+	return signed, nil
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
 	}
+	return a
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secret)
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token id: %w", err)
+	}
+	return encodeBase64URL(b), nil
 }
 
+// ParseToken проверяет подпись, exp/nbf/iat с учётом leeway, iss и aud
 func (j *JwtLib) ParseToken(tokenString string, expectedType TokenType) (*TokenClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return j.secret, nil
-	})
+	token, err := jwt.Parse(tokenString, j.verificationKey,
+		jwt.WithValidMethods(j.keys.Algorithms()),
+		jwt.WithIssuer(j.issuer),
+		jwt.WithLeeway(j.leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("token parse error: %s", err.Error())
 	}
@@ -83,16 +223,130 @@ func (j *JwtLib) ParseToken(tokenString string, expectedType TokenType) (*TokenC
 		return nil, jwtErrors.ErrInvalidToken
 	}
 
-	sub, ok := claims["sub"]
-	if !ok {
-		return nil, errors.New("can't get sub from claims")
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return nil, jwtErrors.ErrInvalidToken
+	}
+
+	audience, err := claims.GetAudience()
+	if err != nil || !j.isAcceptedAudience(audience) {
+		return nil, jwtErrors.ErrInvalidAudience
 	}
 
 	role, _ := claims["role"].(string)
+	jti, _ := claims["jti"].(string)
+	clientId, _ := claims["client_id"].(string)
+	sessionId, _ := claims["sid"].(string)
+	issuer, _ := claims.GetIssuer()
 
-	return &TokenClaims{
-		UserID: int64(sub.(float64)),
-		Role:   role,
-		Type:   TokenType(tokenType),
-	}, nil
+	// токены, выпущенные до появления ptype, принадлежат пользователям
+	principal := PrincipalUser
+	if ptype, _ := claims["ptype"].(string); ptype != "" {
+		principal = PrincipalType(ptype)
+	}
+
+	result := &TokenClaims{
+		ID:            jti,
+		PrincipalType: principal,
+		Role:          role,
+		ClientID:      clientId,
+		SessionID:     sessionId,
+		Type:          TokenType(tokenType),
+		Issuer:        issuer,
+		Audience:      audience,
+		IssuedAt:      issuedAt.Time,
+	}
+	switch principal {
+	case PrincipalUser:
+		sub, ok := claims["sub"].(float64)
+		if !ok {
+			return nil, errors.New("can't get sub from claims")
+		}
+		result.UserID = int64(sub)
+	case PrincipalService:
+		// сервисный токен бывает только access и всегда выпущен самому клиенту
+		sub, _ := claims["sub"].(string)
+		if sub == "" || sub != clientId || result.Type != TokenTypeAccess {
+			return nil, jwtErrors.ErrInvalidToken
+		}
+		if scope, ok := claims["scope"].(string); ok {
+			result.Scopes = strings.Fields(scope)
+		}
+	default:
+		return nil, jwtErrors.ErrInvalidToken
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		result.ExpiresAt = exp.Time
+	}
+	if version, ok := claims["ver"].(float64); ok {
+		result.TokenVersion = int64(version)
+	}
+	if authTime, ok := claims["auth_time"].(float64); ok {
+		result.AuthTime = time.Unix(int64(authTime), 0)
+	}
+	if amr, ok := claims["amr"].([]any); ok {
+		for _, method := range amr {
+			if value, ok := method.(string); ok {
+				result.Amr = append(result.Amr, value)
+			}
+		}
+	}
+	result.Acr, _ = claims["acr"].(string)
+	return result, nil
+}
+
+// isAcceptedAudience — токен выпущен хотя бы для одного зарегистрированного клиента. Предназначен ли он
+// конкретному API, проверяет echomiddleware.RequireAudience на его маршрутах
+func (j *JwtLib) isAcceptedAudience(audience []string) bool {
+	if j.audiences == nil {
+		return true
+	}
+	for _, aud := range audience {
+		if j.audiences.IsKnownAudience(aud) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lifetime возвращает сроки жизни токенов для роли с учётом ограничений клиента
+func (j *JwtLib) Lifetime(role string, client Lifetime) Lifetime {
+	return j.lifetimes.Resolve(role, client)
+}
+
+// MaxLifetime — сколько может прожить любой выпущенный токен. Дольше хранить отзыв токена бессмысленно
+func (j *JwtLib) MaxLifetime() time.Duration {
+	return j.lifetimes.Max()
+}
+
+// JWKS возвращает публичные ключи для проверки токенов сторонними сервисами.
+// При подписи общим секретом (HS256) набор пуст.
+func (j *JwtLib) JWKS() JWKS {
+	return j.keys.JWKS()
+}
+
+// verificationKey выбирает ключ по заголовку kid. Токены без kid выпущены до ротации ключей,
+// их проверяем всеми ключами того же алгоритма.
+func (j *JwtLib) verificationKey(token *jwt.Token) (interface{}, error) {
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		key, found := j.keys.Lookup(kid)
+		if !found {
+			return nil, fmt.Errorf("unknown signing key: %s", kid)
+		}
+		if key.Algorithm() != token.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.verifyKey, nil
+	}
+
+	set := jwt.VerificationKeySet{}
+	for _, key := range j.keys.Keys() {
+		if key.Algorithm() == token.Method.Alg() {
+			set.Keys = append(set.Keys, key.verifyKey)
+		}
+	}
+	if len(set.Keys) == 0 {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return set, nil
 }
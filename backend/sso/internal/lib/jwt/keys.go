@@ -0,0 +1,206 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// SigningKey — ключ, которым подписываются токены, и его публичная часть для проверки
+type SigningKey struct {
+	id        string
+	createdAt time.Time
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewHMACKey создаёт симметричный HS256 ключ из общего секрета
+func NewHMACKey(secret []byte) (*SigningKey, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("hmac secret is empty")
+	}
+	key := &SigningKey{
+		method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+	key.id = key.thumbprint()
+	return key, nil
+}
+
+// LoadSigningKey читает приватный ключ из PEM файла и проверяет, что он подходит под алгоритм.
+// Для HS256 (и пустого алгоритма) используется общий секрет, путь к файлу игнорируется.
+func LoadSigningKey(algorithm, privateKeyPath string, secret []byte) (*SigningKey, error) {
+	algorithm = strings.TrimSpace(algorithm)
+	if algorithm == "" || algorithm == AlgorithmHS256 {
+		return NewHMACKey(secret)
+	}
+	if privateKeyPath == "" {
+		return nil, fmt.Errorf("private key path is required for %s", algorithm)
+	}
+
+	pemBytes, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+	return ParseSigningKey(algorithm, pemBytes)
+}
+
+// ParseSigningKey разбирает PEM приватного ключа для асимметричного алгоритма
+func ParseSigningKey(algorithm string, pemBytes []byte) (*SigningKey, error) {
+	key, err := parseSigningKey(algorithm, pemBytes)
+	if err != nil {
+		return nil, err
+	}
+	key.id = key.thumbprint()
+	return key, nil
+}
+
+func parseSigningKey(algorithm string, pemBytes []byte) (*SigningKey, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse rsa private key: %w", err)
+		}
+		if key.N.BitLen() < 2048 {
+			return nil, errors.New("rsa key must be at least 2048 bits")
+		}
+		return &SigningKey{method: jwt.SigningMethodRS256, signKey: key, verifyKey: &key.PublicKey}, nil
+	case AlgorithmES256:
+		key, err := jwt.ParseECPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse ecdsa private key: %w", err)
+		}
+		if key.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 requires a P-256 key")
+		}
+		return &SigningKey{method: jwt.SigningMethodES256, signKey: key, verifyKey: &key.PublicKey}, nil
+	case AlgorithmEdDSA:
+		key, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse ed25519 private key: %w", err)
+		}
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("EdDSA requires an ed25519 key")
+		}
+		return &SigningKey{method: jwt.SigningMethodEdDSA, signKey: edKey, verifyKey: edKey.Public()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+}
+
+// GenerateSigningKey создаёт новый случайный ключ под алгоритм. Используется при ротации
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	var key *SigningKey
+	switch algorithm {
+	case "", AlgorithmHS256:
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("generate hmac secret: %w", err)
+		}
+		key = &SigningKey{method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}
+	case AlgorithmRS256:
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("generate rsa key: %w", err)
+		}
+		key = &SigningKey{method: jwt.SigningMethodRS256, signKey: private, verifyKey: &private.PublicKey}
+	case AlgorithmES256:
+		private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("generate ecdsa key: %w", err)
+		}
+		key = &SigningKey{method: jwt.SigningMethodES256, signKey: private, verifyKey: &private.PublicKey}
+	case AlgorithmEdDSA:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("generate ed25519 key: %w", err)
+		}
+		key = &SigningKey{method: jwt.SigningMethodEdDSA, signKey: private, verifyKey: public}
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+	key.id = key.thumbprint()
+	key.createdAt = time.Now().UTC()
+	return key, nil
+}
+
+// ID — идентификатор ключа, пишется в заголовок kid каждого токена
+func (k *SigningKey) ID() string {
+	return k.id
+}
+
+// CreatedAt — момент выпуска ключа. У ключа из конфигурации он нулевой, то есть ключ считается самым старым
+func (k *SigningKey) CreatedAt() time.Time {
+	return k.createdAt
+}
+
+// Algorithm возвращает название алгоритма подписи (значение заголовка alg)
+func (k *SigningKey) Algorithm() string {
+	return k.method.Alg()
+}
+
+// IsSymmetric сообщает, что ключ нельзя публиковать в JWKS
+func (k *SigningKey) IsSymmetric() bool {
+	_, ok := k.signKey.([]byte)
+	return ok
+}
+
+// PublicKey возвращает публичную часть асимметричного ключа
+func (k *SigningKey) PublicKey() crypto.PublicKey {
+	switch key := k.verifyKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key
+	default:
+		return nil
+	}
+}
+
+// thumbprint вычисляет kid: RFC 7638 отпечаток для асимметричных ключей
+// и хэш от секрета для HMAC, чтобы kid не раскрывал сам секрет
+func (k *SigningKey) thumbprint() string {
+	var payload []byte
+	if jwk, ok := k.JWK(); ok {
+		// RFC 7638: только обязательные поля в лексикографическом порядке
+		var members map[string]string
+		switch jwk.Kty {
+		case "RSA":
+			members = map[string]string{"e": jwk.E, "kty": jwk.Kty, "n": jwk.N}
+		case "EC":
+			members = map[string]string{"crv": jwk.Crv, "kty": jwk.Kty, "x": jwk.X, "y": jwk.Y}
+		default:
+			members = map[string]string{"crv": jwk.Crv, "kty": jwk.Kty, "x": jwk.X}
+		}
+		payload, _ = json.Marshal(members)
+	} else if secret, ok := k.signKey.([]byte); ok {
+		payload = append([]byte("hmac-kid:"), secret...)
+	}
+	sum := sha256.Sum256(payload)
+	if k.IsSymmetric() {
+		return hex.EncodeToString(sum[:8])
+	}
+	return encodeBase64URL(sum[:])
+}
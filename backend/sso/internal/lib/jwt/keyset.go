@@ -0,0 +1,232 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// KeySet — один активный ключ подписи и несколько выведенных из оборота ключей,
+// которые ещё принимаются при проверке, пока не истечёт retention.
+type KeySet struct {
+	mu         sync.RWMutex
+	algorithm  string
+	retention  time.Duration
+	store      KeyStore
+	configured *SigningKey
+	// startedAt — момент создания набора. С него отсчитывается возраст ключа из конфигурации:
+	// его createdAt нулевой, чтобы он сортировался раньше ключей из хранилища
+	startedAt time.Time
+	// отсортированы по времени выпуска, последний — активный
+	keys []*SigningKey
+}
+
+// NewKeySet собирает набор из ключа конфигурации и ключей из хранилища.
+// store может быть nil — тогда набор состоит только из ключа конфигурации и ротация недоступна.
+func NewKeySet(algorithm string, retention time.Duration, store KeyStore, configured *SigningKey) (*KeySet, error) {
+	if store == nil && configured == nil {
+		return nil, errors.New("either key store or configured key is required")
+	}
+
+	s := &KeySet{
+		algorithm:  algorithm,
+		retention:  retention,
+		store:      store,
+		configured: configured,
+		startedAt:  time.Now().UTC(),
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	if len(s.keys) == 0 {
+		if _, err := s.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Reload перечитывает ключи из хранилища, чтобы подхватить ротацию, сделанную другим инстансом
+func (s *KeySet) Reload() error {
+	keys := make([]*SigningKey, 0)
+	if s.configured != nil {
+		keys = append(keys, s.configured)
+	}
+	if s.store != nil {
+		stored, err := s.store.Load()
+		if err != nil {
+			return fmt.Errorf("load signing keys: %w", err)
+		}
+		keys = append(keys, stored...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(keys) == 0 && len(s.keys) > 0 {
+		return errors.New("key store is empty, keeping loaded keys")
+	}
+	s.keys = uniqueSortedKeys(keys)
+	s.pruneLocked(time.Now())
+	return nil
+}
+
+// Rotate выпускает новый активный ключ. Предыдущий остаётся валидным для проверки в течение retention
+func (s *KeySet) Rotate() (*SigningKey, error) {
+	if s.store == nil {
+		return nil, errors.New("key rotation requires a key store")
+	}
+
+	key, err := GenerateSigningKey(s.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Save(key); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.keys = uniqueSortedKeys(append(s.keys, key))
+	pruned := s.pruneLocked(time.Now())
+	s.mu.Unlock()
+
+	for _, old := range pruned {
+		if err := s.store.Delete(old.id); err != nil {
+			return key, err
+		}
+	}
+	return key, nil
+}
+
+// RunRotation раз в checkPeriod подхватывает чужие ротации и выпускает новый ключ,
+// когда активному исполнилось interval (0 — только ручная ротация). Блокируется до отмены ctx.
+func (s *KeySet) RunRotation(ctx context.Context, interval, checkPeriod time.Duration, onRotate func(*SigningKey, error)) error {
+	ticker := time.NewTicker(checkPeriod)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Reload(); err != nil {
+			onRotate(nil, err)
+		} else if interval > 0 && time.Since(s.activeSince()) >= interval {
+			onRotate(s.Rotate())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// activeSince — с какого момента подписывает активный ключ. Ключ из конфигурации считается выпущенным
+// при старте: иначе при включённой ротации он заменялся бы сразу после запуска
+func (s *KeySet) activeSince() time.Time {
+	active := s.Active()
+	if active.createdAt.IsZero() {
+		return s.startedAt
+	}
+	return active.createdAt
+}
+
+// CanRotate сообщает, сохраняются ли ключи между перезапусками
+func (s *KeySet) CanRotate() bool {
+	return s.store != nil
+}
+
+func (s *KeySet) Active() *SigningKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys[len(s.keys)-1]
+}
+
+func (s *KeySet) Lookup(kid string) (*SigningKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.keys {
+		if key.id == kid {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// Keys возвращает все ключи, которые принимаются при проверке
+func (s *KeySet) Keys() []*SigningKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]*SigningKey, len(s.keys))
+	copy(keys, s.keys)
+	return keys
+}
+
+// Algorithms возвращает алгоритмы всех принимаемых ключей
+func (s *KeySet) Algorithms() []string {
+	seen := make(map[string]struct{})
+	algorithms := make([]string, 0, 1)
+	for _, key := range s.Keys() {
+		if _, ok := seen[key.Algorithm()]; ok {
+			continue
+		}
+		seen[key.Algorithm()] = struct{}{}
+		algorithms = append(algorithms, key.Algorithm())
+	}
+	return algorithms
+}
+
+// JWKS публикует активный и ещё принимаемые асимметричные ключи
+func (s *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	keys := s.Keys()
+	for i := len(keys) - 1; i >= 0; i-- {
+		if jwk, ok := keys[i].JWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// pruneLocked выкидывает ключи, выведенные из оборота раньше, чем retention назад.
+// Ключ считается выведенным в момент выпуска следующего.
+func (s *KeySet) pruneLocked(now time.Time) []*SigningKey {
+	if s.retention <= 0 || len(s.keys) < 2 {
+		return nil
+	}
+
+	kept := make([]*SigningKey, 0, len(s.keys))
+	var pruned []*SigningKey
+	for i, key := range s.keys {
+		if i < len(s.keys)-1 && now.After(s.keys[i+1].createdAt.Add(s.retention)) {
+			pruned = append(pruned, key)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	s.keys = kept
+	return pruned
+}
+
+func uniqueSortedKeys(keys []*SigningKey) []*SigningKey {
+	byID := make(map[string]*SigningKey, len(keys))
+	for _, key := range keys {
+		if existing, ok := byID[key.id]; ok && !existing.createdAt.Before(key.createdAt) {
+			continue
+		}
+		byID[key.id] = key
+	}
+
+	unique := make([]*SigningKey, 0, len(byID))
+	for _, key := range byID {
+		unique = append(unique, key)
+	}
+	sort.Slice(unique, func(i, j int) bool {
+		if unique[i].createdAt.Equal(unique[j].createdAt) {
+			return unique[i].id < unique[j].id
+		}
+		return unique[i].createdAt.Before(unique[j].createdAt)
+	})
+	return unique
+}
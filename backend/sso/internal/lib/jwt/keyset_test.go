@@ -0,0 +1,253 @@
+package jwt
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// memoryKeyStore хранит ключи в памяти, как их хранил бы каталог соседнего инстанса
+type memoryKeyStore struct {
+	keys    map[string]*SigningKey
+	deleted []string
+	err     error
+}
+
+func newMemoryKeyStore(keys ...*SigningKey) *memoryKeyStore {
+	store := &memoryKeyStore{keys: make(map[string]*SigningKey)}
+	for _, key := range keys {
+		store.keys[key.id] = key
+	}
+	return store
+}
+
+func (s *memoryKeyStore) Load() ([]*SigningKey, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	keys := make([]*SigningKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (s *memoryKeyStore) Save(key *SigningKey) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.keys[key.id] = key
+	return nil
+}
+
+func (s *memoryKeyStore) Delete(kid string) error {
+	delete(s.keys, kid)
+	s.deleted = append(s.deleted, kid)
+	return nil
+}
+
+// keyIssued выпускает ключ с заданным временем выпуска
+func keyIssued(t *testing.T, id string, createdAt time.Time) *SigningKey {
+	t.Helper()
+	key, err := GenerateSigningKey(AlgorithmHS256)
+	if err != nil {
+		t.Fatalf("GenerateSigningKey: %v", err)
+	}
+	key.id = id
+	key.createdAt = createdAt
+	return key
+}
+
+func keyIds(keys []*SigningKey) []string {
+	ids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		ids = append(ids, key.id)
+	}
+	return ids
+}
+
+func TestKeySetReloadPrunes(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name      string
+		retention time.Duration
+		issued    map[string]time.Duration
+		want      []string
+	}{
+		{
+			name:      "retired within retention",
+			retention: time.Hour,
+			issued:    map[string]time.Duration{"old": -3 * time.Hour, "new": -30 * time.Minute},
+			want:      []string{"old", "new"},
+		},
+		{
+			// ключ выведен из оборота в момент выпуска следующего, а не в момент своего выпуска
+			name:      "retired past retention",
+			retention: time.Hour,
+			issued:    map[string]time.Duration{"oldest": -5 * time.Hour, "old": -3 * time.Hour, "new": -30 * time.Minute},
+			want:      []string{"old", "new"},
+		},
+		{
+			name:      "active key is never pruned",
+			retention: time.Hour,
+			issued:    map[string]time.Duration{"old": -5 * time.Hour, "new": -3 * time.Hour},
+			want:      []string{"new"},
+		},
+		{
+			name:   "zero retention keeps all",
+			issued: map[string]time.Duration{"oldest": -5 * time.Hour, "old": -3 * time.Hour, "new": -2 * time.Hour},
+			want:   []string{"oldest", "old", "new"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryKeyStore()
+			for id, age := range tt.issued {
+				store.keys[id] = keyIssued(t, id, now.Add(age))
+			}
+			set, err := NewKeySet(AlgorithmHS256, tt.retention, store, nil)
+			if err != nil {
+				t.Fatalf("NewKeySet: %v", err)
+			}
+			if got := keyIds(set.Keys()); !slices.Equal(got, tt.want) {
+				t.Errorf("keys = %v, want %v", got, tt.want)
+			}
+			if active := set.Active().id; active != tt.want[len(tt.want)-1] {
+				t.Errorf("active = %s, want the latest key", active)
+			}
+		})
+	}
+}
+
+func TestKeySetRotate(t *testing.T) {
+	now := time.Now().UTC()
+	expired := keyIssued(t, "expired", now.Add(-5*time.Hour))
+	previous := keyIssued(t, "previous", now.Add(-3*time.Hour))
+	store := newMemoryKeyStore(expired, previous)
+	// набор загружен до того, как истёк retention ключа expired
+	set := &KeySet{
+		algorithm: AlgorithmHS256,
+		retention: time.Hour,
+		store:     store,
+		startedAt: now,
+		keys:      []*SigningKey{expired, previous},
+	}
+
+	key, err := set.Rotate()
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if set.Active() != key {
+		t.Fatal("rotated key is not active")
+	}
+	if _, ok := store.keys[key.id]; !ok {
+		t.Error("rotated key is not saved to the store")
+	}
+	// предыдущий активный ключ ещё принимается, а выведенный раньше retention удаляется и из хранилища
+	if _, ok := set.Lookup("previous"); !ok {
+		t.Error("previous key is not accepted after rotation")
+	}
+	if _, ok := set.Lookup("expired"); ok {
+		t.Error("expired key is still accepted")
+	}
+	if !slices.Equal(store.deleted, []string{"expired"}) {
+		t.Errorf("deleted = %v, want [expired]", store.deleted)
+	}
+}
+
+func TestKeySetRotateWithoutStore(t *testing.T) {
+	configured := keyIssued(t, "configured", time.Time{})
+	set, err := NewKeySet(AlgorithmHS256, time.Hour, nil, configured)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	if set.CanRotate() {
+		t.Error("CanRotate = true without a store")
+	}
+	if _, err := set.Rotate(); err == nil {
+		t.Error("Rotate succeeded without a store")
+	}
+	if set.Active() != configured {
+		t.Error("configured key is not active")
+	}
+}
+
+func TestKeySetConfiguredKey(t *testing.T) {
+	configured := keyIssued(t, "configured", time.Time{})
+	store := newMemoryKeyStore()
+	set, err := NewKeySet(AlgorithmHS256, time.Hour, store, configured)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	// ключ конфигурации не заменяется сразу после запуска: его возраст считается со старта
+	if set.Active() != configured {
+		t.Fatal("configured key is replaced on start")
+	}
+	if time.Since(set.activeSince()) > time.Minute {
+		t.Errorf("activeSince = %v, want the start time", set.activeSince())
+	}
+
+	key, err := set.Rotate()
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if set.Active() != key {
+		t.Error("stored key does not replace the configured one")
+	}
+	if _, ok := set.Lookup("configured"); !ok {
+		t.Error("configured key is not accepted after rotation")
+	}
+}
+
+func TestKeySetReloadKeepsKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(*memoryKeyStore)
+	}{
+		{name: "store error", corrupt: func(s *memoryKeyStore) { s.err = errors.New("permission denied") }},
+		{name: "empty store", corrupt: func(s *memoryKeyStore) { s.keys = make(map[string]*SigningKey) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryKeyStore()
+			set, err := NewKeySet(AlgorithmHS256, time.Hour, store, nil)
+			if err != nil {
+				t.Fatalf("NewKeySet: %v", err)
+			}
+			active := set.Active()
+
+			tt.corrupt(store)
+			if err := set.Reload(); err == nil {
+				t.Fatal("Reload error is swallowed")
+			}
+			if set.Active() != active {
+				t.Error("failed Reload dropped the active key")
+			}
+		})
+	}
+}
+
+func TestKeySetReloadPicksUpRotation(t *testing.T) {
+	store := newMemoryKeyStore()
+	set, err := NewKeySet(AlgorithmHS256, time.Hour, store, nil)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	previous := set.Active()
+
+	// ротация, сделанная другим инстансом
+	rotated := keyIssued(t, "rotated", time.Now().UTC().Add(time.Second))
+	store.keys[rotated.id] = rotated
+	if err := set.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if set.Active() != rotated {
+		t.Error("key rotated by another instance is not active")
+	}
+	if _, ok := set.Lookup(previous.id); !ok {
+		t.Error("previous key is not accepted after reload")
+	}
+}
@@ -0,0 +1,150 @@
+package jwt
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	pemTypePrivateKey = "PRIVATE KEY"
+	pemTypeHMACSecret = "HMAC SECRET"
+
+	pemHeaderKid       = "Kid"
+	pemHeaderAlgorithm = "Algorithm"
+	pemHeaderCreated   = "Created"
+)
+
+// KeyStore хранит ключи подписи между перезапусками и между инстансами сервиса
+type KeyStore interface {
+	Load() ([]*SigningKey, error)
+	Save(key *SigningKey) error
+	Delete(kid string) error
+}
+
+// DirKeyStore хранит каждый ключ в отдельном PEM файле <kid>.pem.
+// kid, алгоритм и время выпуска лежат в заголовках PEM блока.
+type DirKeyStore struct {
+	dir string
+}
+
+func NewDirKeyStore(dir string) (*DirKeyStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create keys dir: %w", err)
+	}
+	return &DirKeyStore{dir: dir}, nil
+}
+
+func (s *DirKeyStore) Load() ([]*SigningKey, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("list keys dir: %w", err)
+	}
+
+	keys := make([]*SigningKey, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read key %s: %w", path, err)
+		}
+		key, err := decodeStoredKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("decode key %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (s *DirKeyStore) Save(key *SigningKey) error {
+	data, err := encodeStoredKey(key)
+	if err != nil {
+		return err
+	}
+	// пишем во временный файл и переименовываем, чтобы соседний инстанс не прочитал половину ключа
+	tmp := filepath.Join(s.dir, "."+key.id+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write key: %w", err)
+	}
+	if err := os.Rename(tmp, s.keyPath(key.id)); err != nil {
+		return fmt.Errorf("store key: %w", err)
+	}
+	return nil
+}
+
+func (s *DirKeyStore) Delete(kid string) error {
+	if err := os.Remove(s.keyPath(kid)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete key: %w", err)
+	}
+	return nil
+}
+
+func (s *DirKeyStore) keyPath(kid string) string {
+	return filepath.Join(s.dir, kid+".pem")
+}
+
+func encodeStoredKey(key *SigningKey) ([]byte, error) {
+	block := &pem.Block{
+		Headers: map[string]string{
+			pemHeaderKid:       key.id,
+			pemHeaderAlgorithm: key.Algorithm(),
+			pemHeaderCreated:   key.createdAt.UTC().Format(time.RFC3339),
+		},
+	}
+
+	if secret, ok := key.signKey.([]byte); ok {
+		block.Type = pemTypeHMACSecret
+		block.Bytes = secret
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key.signKey)
+		if err != nil {
+			return nil, fmt.Errorf("marshal private key: %w", err)
+		}
+		block.Type = pemTypePrivateKey
+		block.Bytes = der
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+func decodeStoredKey(data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no pem block found")
+	}
+
+	algorithm := block.Headers[pemHeaderAlgorithm]
+	createdAt, err := time.Parse(time.RFC3339, block.Headers[pemHeaderCreated])
+	if err != nil {
+		return nil, fmt.Errorf("parse created header: %w", err)
+	}
+
+	var key *SigningKey
+	switch block.Type {
+	case pemTypeHMACSecret:
+		if algorithm != AlgorithmHS256 {
+			return nil, fmt.Errorf("unexpected algorithm %s for hmac secret", algorithm)
+		}
+		key = &SigningKey{method: jwt.SigningMethodHS256, signKey: block.Bytes, verifyKey: block.Bytes}
+	case pemTypePrivateKey:
+		key, err = parseSigningKey(algorithm, data)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unexpected pem block %q", block.Type)
+	}
+
+	key.id = strings.TrimSpace(block.Headers[pemHeaderKid])
+	if key.id == "" {
+		key.id = key.thumbprint()
+	}
+	key.createdAt = createdAt
+	return key, nil
+}
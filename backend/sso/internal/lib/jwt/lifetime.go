@@ -0,0 +1,60 @@
+package jwt
+
+import "time"
+
+// Lifetime — сроки жизни пары токенов. Нулевое поле означает «не переопределено».
+// Refresh — сколько сессия может простаивать, Session — абсолютный предел сессии от auth_time.
+type Lifetime struct {
+	Access  time.Duration
+	Refresh time.Duration
+	Session time.Duration
+}
+
+// LifetimePolicy — сроки жизни по умолчанию и переопределения для ролей.
+// Роль может как сократить, так и продлить сроки; клиент — только сократить.
+type LifetimePolicy struct {
+	Default Lifetime
+	Roles   map[string]Lifetime
+}
+
+// Resolve возвращает сроки жизни токенов пользователя с ролью role для клиента с ограничениями client
+func (p LifetimePolicy) Resolve(role string, client Lifetime) Lifetime {
+	lifetime := p.Default
+	if override, ok := p.Roles[role]; ok {
+		lifetime = Lifetime{
+			Access:  overrideDuration(lifetime.Access, override.Access),
+			Refresh: overrideDuration(lifetime.Refresh, override.Refresh),
+			Session: overrideDuration(lifetime.Session, override.Session),
+		}
+	}
+
+	return Lifetime{
+		Access:  capDuration(lifetime.Access, client.Access),
+		Refresh: capDuration(lifetime.Refresh, client.Refresh),
+		Session: capDuration(lifetime.Session, client.Session),
+	}
+}
+
+// Max — самый долгий срок жизни, который может получить выпущенный токен
+func (p LifetimePolicy) Max() time.Duration {
+	longest := max(p.Default.Access, p.Default.Refresh)
+	for _, lifetime := range p.Roles {
+		longest = max(longest, lifetime.Access, lifetime.Refresh)
+	}
+	return longest
+}
+
+func overrideDuration(current, override time.Duration) time.Duration {
+	if override > 0 {
+		return override
+	}
+	return current
+}
+
+// capDuration сокращает срок до limit; нулевой срок означает «без ограничения» и тоже сокращается
+func capDuration(current, limit time.Duration) time.Duration {
+	if limit > 0 && (current == 0 || limit < current) {
+		return limit
+	}
+	return current
+}
@@ -0,0 +1,124 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLifetimePolicyResolve(t *testing.T) {
+	policy := LifetimePolicy{
+		Default: Lifetime{Access: time.Hour, Refresh: 168 * time.Hour, Session: 2160 * time.Hour},
+		Roles: map[string]Lifetime{
+			// роль сокращает только то, что задано
+			"admin": {Access: 15 * time.Minute, Refresh: 12 * time.Hour},
+			// и может продлить срок по умолчанию
+			"service": {Access: 2 * time.Hour},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		policy LifetimePolicy
+		role   string
+		client Lifetime
+		want   Lifetime
+	}{
+		{
+			name: "default",
+			role: "user",
+			want: Lifetime{Access: time.Hour, Refresh: 168 * time.Hour, Session: 2160 * time.Hour},
+		},
+		{
+			name: "role shortens",
+			role: "admin",
+			want: Lifetime{Access: 15 * time.Minute, Refresh: 12 * time.Hour, Session: 2160 * time.Hour},
+		},
+		{
+			name: "role extends",
+			role: "service",
+			want: Lifetime{Access: 2 * time.Hour, Refresh: 168 * time.Hour, Session: 2160 * time.Hour},
+		},
+		{
+			name:   "client shortens",
+			role:   "user",
+			client: Lifetime{Access: 5 * time.Minute, Session: 24 * time.Hour},
+			want:   Lifetime{Access: 5 * time.Minute, Refresh: 168 * time.Hour, Session: 24 * time.Hour},
+		},
+		{
+			name:   "client cannot extend",
+			role:   "admin",
+			client: Lifetime{Access: time.Hour, Refresh: 720 * time.Hour},
+			want:   Lifetime{Access: 15 * time.Minute, Refresh: 12 * time.Hour, Session: 2160 * time.Hour},
+		},
+		{
+			name:   "client caps role extension",
+			role:   "service",
+			client: Lifetime{Access: 30 * time.Minute},
+			want:   Lifetime{Access: 30 * time.Minute, Refresh: 168 * time.Hour, Session: 2160 * time.Hour},
+		},
+		{
+			// нулевой срок по умолчанию — без ограничения, клиент всё равно его сокращает
+			name:   "client caps unlimited session",
+			policy: LifetimePolicy{Default: Lifetime{Access: time.Hour, Refresh: 168 * time.Hour}},
+			role:   "user",
+			client: Lifetime{Session: 24 * time.Hour},
+			want:   Lifetime{Access: time.Hour, Refresh: 168 * time.Hour, Session: 24 * time.Hour},
+		},
+		{
+			name:   "unlimited session stays unlimited",
+			policy: LifetimePolicy{Default: Lifetime{Access: time.Hour, Refresh: 168 * time.Hour}},
+			role:   "user",
+			want:   Lifetime{Access: time.Hour, Refresh: 168 * time.Hour},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := policy
+			if tt.policy.Default != (Lifetime{}) {
+				p = tt.policy
+			}
+			if got := p.Resolve(tt.role, tt.client); got != tt.want {
+				t.Errorf("Resolve = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLifetimePolicyMax(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy LifetimePolicy
+		want   time.Duration
+	}{
+		{
+			name:   "default refresh",
+			policy: LifetimePolicy{Default: Lifetime{Access: time.Hour, Refresh: 168 * time.Hour}},
+			want:   168 * time.Hour,
+		},
+		{
+			name: "role refresh is longer",
+			policy: LifetimePolicy{
+				Default: Lifetime{Access: time.Hour, Refresh: 168 * time.Hour},
+				Roles:   map[string]Lifetime{"service": {Refresh: 720 * time.Hour}},
+			},
+			want: 720 * time.Hour,
+		},
+		{
+			name: "role access is longer",
+			policy: LifetimePolicy{
+				Default: Lifetime{Access: time.Hour, Refresh: 2 * time.Hour},
+				Roles:   map[string]Lifetime{"service": {Access: 24 * time.Hour}},
+			},
+			want: 24 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Max(); got != tt.want {
+				t.Errorf("Max = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
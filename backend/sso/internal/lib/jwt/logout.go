@@ -0,0 +1,49 @@
+package jwt
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// backchannelLogoutEvent — обязательный ключ events в logout токене
+const backchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// logoutTokenTTL — logout токен нужен только на время доставки; каждая повторная попытка подписывает новый
+const logoutTokenTTL = 2 * time.Minute
+
+// LogoutTokenParams — чья сессия и у какого клиента завершена
+type LogoutTokenParams struct {
+	UserID    int64
+	ClientID  string
+	SessionID string
+}
+
+// NewLogoutToken подписывает logout токен OIDC Back-Channel Logout 1.0, раздел 2.4. aud — идентификатор клиента
+func (j *JwtLib) NewLogoutToken(params LogoutTokenParams) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	issuedAt := time.Now()
+	claims := jwt.MapClaims{
+		"jti":    jti,
+		"iss":    j.issuer,
+		"sub":    strconv.FormatInt(params.UserID, 10),
+		"aud":    params.ClientID,
+		"iat":    issuedAt.Unix(),
+		"exp":    issuedAt.Add(logoutTokenTTL).Unix(),
+		"events": map[string]any{backchannelLogoutEvent: map[string]any{}},
+	}
+	if params.SessionID != "" {
+		claims["sid"] = params.SessionID
+	}
+
+	key := j.keys.Active()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	token.Header["typ"] = "logout+jwt"
+	return token.SignedString(key.signKey)
+}
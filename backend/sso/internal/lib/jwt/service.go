@@ -0,0 +1,61 @@
+package jwt
+
+import (
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// PrincipalType — кому принадлежит токен: пользователю или сервису (claim ptype)
+type PrincipalType string
+
+const (
+	PrincipalUser    PrincipalType = "user"
+	PrincipalService PrincipalType = "service"
+)
+
+// ServiceTokenParams — для какого сервисного клиента выпускается токен client_credentials
+type ServiceTokenParams struct {
+	ClientID string
+	Audience []string
+	Scopes   []string
+	// ClientLifetime — ограничения сроков жизни, заданные клиенту
+	ClientLifetime Lifetime
+}
+
+// NewServiceToken выпускает access токен сервиса: sub — идентификатор клиента, разрешения — в scope.
+// Refresh токен сервису не нужен, он просто запрашивает новый токен
+func (j *JwtLib) NewServiceToken(params ServiceTokenParams) (string, time.Time, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(j.lifetimes.Resolve("", params.ClientLifetime).Access)
+	claims := jwt.MapClaims{
+		"jti":       jti,
+		"iss":       j.issuer,
+		"sub":       params.ClientID,
+		"aud":       params.Audience,
+		"client_id": params.ClientID,
+		"ptype":     string(PrincipalService),
+		"typ":       string(TokenTypeAccess),
+		"iat":       now.Unix(),
+		"nbf":       now.Unix(),
+		"exp":       expiresAt.Unix(),
+	}
+	if len(params.Scopes) > 0 {
+		claims["scope"] = strings.Join(params.Scopes, " ")
+	}
+
+	key := j.keys.Active()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	signed, err := token.SignedString(key.signKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Log пишет сообщения в лог вместо доставки. Только для разработки: в лог попадают коды входа
+type Log struct{}
+
+func (Log) Notify(_ context.Context, to Recipient, message Message) error {
+	slog.Info("notification",
+		"email", to.Email,
+		"telegram_chat_id", to.TelegramChatID,
+		"subject", message.Subject,
+		"text", message.Text,
+	)
+	return nil
+}
+
+// File дописывает сообщения в файл по строке JSON на сообщение. Для разработки и автотестов,
+// которым нужно прочитать присланный код
+type File struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (f *File) Notify(_ context.Context, to Recipient, message Message) error {
+	line, err := json.Marshal(map[string]any{
+		"time":             time.Now().Format(time.RFC3339),
+		"email":            to.Email,
+		"telegram_chat_id": to.TelegramChatID,
+		"subject":          message.Subject,
+		"text":             message.Text,
+	})
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("file notifier: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("file notifier: %w", err)
+	}
+	return nil
+}
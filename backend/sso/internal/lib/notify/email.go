@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailConfig — SMTP сервер для писем. Без Username письма отправляются без авторизации
+type EmailConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Email отправляет письма через SMTP. STARTTLS используется, если сервер его предлагает
+type Email struct {
+	cfg  EmailConfig
+	from *mail.Address
+}
+
+func NewEmail(cfg EmailConfig) (*Email, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("email: smtp host is required")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("email: from: %w", err)
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	return &Email{cfg: cfg, from: from}, nil
+}
+
+func (e *Email) Notify(ctx context.Context, to Recipient, message Message) error {
+	if to.Email == "" {
+		return ErrNoAddress
+	}
+	// логин может и не быть почтой
+	address, err := mail.ParseAddress(to.Email)
+	if err != nil || address.Address != to.Email {
+		return ErrNoAddress
+	}
+
+	var body strings.Builder
+	body.WriteString("From: " + e.from.String() + "\r\n")
+	body.WriteString("To: " + address.String() + "\r\n")
+	body.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", message.Subject) + "\r\n")
+	body.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	body.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(message.Text, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if e.cfg.Username != "" {
+		auth = smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)
+	}
+	addr := net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port))
+
+	// net/smtp не принимает контекст, поэтому отправка идёт в горутине и прерывается по ctx
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, e.from.Address, []string{address.Address}, []byte(body.String()))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("email: send: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
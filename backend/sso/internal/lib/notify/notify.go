@@ -0,0 +1,54 @@
+// Package notify доставляет пользователям короткие сообщения: одноразовые коды и ссылки для входа.
+// Каналы подключаются через интерфейс Notifier и пробуются по очереди в Chain
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// ErrNoAddress — у получателя нет адреса для этого канала, например не привязан Telegram
+var ErrNoAddress = errors.New("notify: recipient has no address for this channel")
+
+// Recipient — адреса пользователя. Нулевое поле означает, что адреса в этом канале нет
+type Recipient struct {
+	// Email — адрес почты; в SSO логин покупателя обычно и есть почта
+	Email          string
+	TelegramChatID int64
+}
+
+// Message — сообщение пользователю. Subject используют только каналы с темой письма
+type Message struct {
+	Subject string
+	Text    string
+}
+
+type Notifier interface {
+	// Notify доставляет сообщение или возвращает ErrNoAddress, если получателю нельзя написать этим каналом
+	Notify(ctx context.Context, to Recipient, message Message) error
+}
+
+// Chain пробует каналы по порядку и останавливается на первом, который доставил сообщение
+type Chain []Notifier
+
+func (c Chain) Notify(ctx context.Context, to Recipient, message Message) error {
+	var failures []string
+	for _, notifier := range c {
+		err := notifier.Notify(ctx, to, message)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrNoAddress) {
+			continue
+		}
+		slog.Warn("notification channel failed", "channel", fmt.Sprintf("%T", notifier), "err", err)
+		failures = append(failures, err.Error())
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("notify: %s", strings.Join(failures, "; "))
+	}
+	return ErrNoAddress
+}
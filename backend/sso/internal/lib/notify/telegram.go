@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const telegramAPI = "https://api.telegram.org"
+
+// Telegram пишет пользователю от имени бота SSO в чат telegram_chat_id. Бот может писать только тем,
+// кто хотя бы раз начал с ним диалог — вход через Telegram это гарантирует
+type Telegram struct {
+	botToken string
+	client   *http.Client
+}
+
+func NewTelegram(botToken string, timeout time.Duration) *Telegram {
+	return &Telegram{
+		botToken: botToken,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (t *Telegram) Notify(ctx context.Context, to Recipient, message Message) error {
+	if to.TelegramChatID == 0 {
+		return ErrNoAddress
+	}
+	body, err := json.Marshal(map[string]any{
+		"chat_id": to.TelegramChatID,
+		"text":    message.Text,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, telegramAPI+"/bot"+t.botToken+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// в url.Error есть адрес запроса, а в нём токен бота
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram: send message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&result); err != nil {
+		return fmt.Errorf("telegram: send message: status %d", resp.StatusCode)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram: send message: %s", result.Description)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Package passhash хеширует пароли. Хеши argon2id пишутся в формате PHC
+// ($argon2id$v=19$m=65536,t=3,p=2$соль$хеш), bcrypt — в своём modular crypt ($2a$10$...).
+// Проверяются оба алгоритма, поэтому старые хеши bcrypt остаются рабочими
+package passhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+// ErrMalformedHash — сохранённый хеш не разобран: неизвестный алгоритм или испорченная запись
+var ErrMalformedHash = errors.New("passhash: malformed hash")
+
+// Argon2Params — параметры argon2id. Memory — в КиБ
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Options — чем хешируются новые пароли. Хеш другим алгоритмом или с другими параметрами считается устаревшим
+type Options struct {
+	Algorithm  string
+	Argon2     Argon2Params
+	BcryptCost int
+}
+
+type Hasher struct {
+	opts Options
+}
+
+func New(opts Options) (*Hasher, error) {
+	switch opts.Algorithm {
+	case Argon2id:
+		p := opts.Argon2
+		if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 || p.KeyLength == 0 {
+			return nil, errors.New("passhash: argon2id memory, iterations, parallelism and key length must be positive")
+		}
+		if p.SaltLength < 8 {
+			return nil, errors.New("passhash: argon2id salt must be at least 8 bytes")
+		}
+	case Bcrypt:
+		if opts.BcryptCost < bcrypt.MinCost || opts.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("passhash: bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return nil, fmt.Errorf("passhash: unsupported algorithm %q", opts.Algorithm)
+	}
+	return &Hasher{opts: opts}, nil
+}
+
+// Hash хеширует пароль текущим алгоритмом
+func (h *Hasher) Hash(password string) ([]byte, error) {
+	if h.opts.Algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.opts.BcryptCost)
+		if err != nil {
+			return nil, fmt.Errorf("hash password: %w", err)
+		}
+		return hash, nil
+	}
+
+	p := h.opts.Argon2
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return []byte(fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		Argon2id, argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	)), nil
+}
+
+// Verify сравнивает пароль с хешем. outdated сообщает, что пароль верен, но хеш сделан не текущим
+// алгоритмом или с другими параметрами и его стоит пересчитать
+func (h *Hasher) Verify(hash []byte, password string) (valid bool, outdated bool, err error) {
+	encoded := string(hash)
+	switch {
+	case strings.HasPrefix(encoded, "$"+Argon2id+"$"):
+		params, salt, key, err := parseArgon2id(encoded)
+		if err != nil {
+			return false, false, err
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+		if subtle.ConstantTimeCompare(computed, key) != 1 {
+			return false, false, nil
+		}
+		return true, h.opts.Algorithm != Argon2id || params != h.opts.Argon2, nil
+
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, fmt.Errorf("%w: %w", ErrMalformedHash, err)
+		}
+		cost, err := bcrypt.Cost(hash)
+		if err != nil {
+			return false, false, fmt.Errorf("%w: %w", ErrMalformedHash, err)
+		}
+		return true, h.opts.Algorithm != Bcrypt || cost != h.opts.BcryptCost, nil
+
+	default:
+		return false, false, ErrMalformedHash
+	}
+}
+
+func parseArgon2id(encoded string) (Argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", соль, хеш
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return Argon2Params{}, nil, nil, ErrMalformedHash
+	}
+	if parts[2] != "v="+strconv.Itoa(argon2.Version) {
+		return Argon2Params{}, nil, nil, fmt.Errorf("%w: unsupported argon2 version %s", ErrMalformedHash, parts[2])
+	}
+
+	var params Argon2Params
+	for _, field := range strings.Split(parts[3], ",") {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return Argon2Params{}, nil, nil, ErrMalformedHash
+		}
+		number, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return Argon2Params{}, nil, nil, ErrMalformedHash
+		}
+		switch name {
+		case "m":
+			params.Memory = uint32(number)
+		case "t":
+			params.Iterations = uint32(number)
+		case "p":
+			if number > 255 {
+				return Argon2Params{}, nil, nil, ErrMalformedHash
+			}
+			params.Parallelism = uint8(number)
+		default:
+			return Argon2Params{}, nil, nil, ErrMalformedHash
+		}
+	}
+	if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return Argon2Params{}, nil, nil, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return Argon2Params{}, nil, nil, ErrMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2Params{}, nil, nil, ErrMalformedHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
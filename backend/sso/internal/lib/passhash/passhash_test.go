@@ -0,0 +1,200 @@
+package passhash
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "correct horse battery staple"
+
+// маленькие параметры, чтобы тесты шли быстро; формат хеша от них не зависит
+var testArgon2 = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func newHasher(t *testing.T, opts Options) *Hasher {
+	t.Helper()
+	hasher, err := New(opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return hasher
+}
+
+func argon2Hasher(t *testing.T, params Argon2Params) *Hasher {
+	return newHasher(t, Options{Algorithm: Argon2id, Argon2: params})
+}
+
+func bcryptHasher(t *testing.T, cost int) *Hasher {
+	return newHasher(t, Options{Algorithm: Bcrypt, BcryptCost: cost})
+}
+
+// bcryptHash — хеш bcrypt с заданным префиксом версии: $2a$, $2b$ или $2y$
+func bcryptHash(t *testing.T, prefix string, cost int) []byte {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), cost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+	return []byte(prefix + strings.TrimPrefix(string(hash), "$2a$"))
+}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	hasher := argon2Hasher(t, testArgon2)
+	hash, err := hasher.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(string(hash), "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("hash = %s, want PHC string with configured params", hash)
+	}
+
+	valid, outdated, err := hasher.Verify(hash, testPassword)
+	if err != nil || !valid || outdated {
+		t.Fatalf("Verify = %v, %v, %v, want valid and current", valid, outdated, err)
+	}
+	valid, _, err = hasher.Verify(hash, testPassword+"!")
+	if err != nil || valid {
+		t.Fatalf("Verify wrong password = %v, %v, want invalid", valid, err)
+	}
+
+	// соль случайная: тот же пароль даёт другой хеш
+	again, err := hasher.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if string(again) == string(hash) {
+		t.Error("two hashes of the same password are equal")
+	}
+}
+
+func TestVerifyOutdated(t *testing.T) {
+	argon2Hash, err := argon2Hasher(t, testArgon2).Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		hasher       *Hasher
+		hash         []byte
+		wantOutdated bool
+	}{
+		{name: "argon2id same params", hasher: argon2Hasher(t, testArgon2), hash: argon2Hash},
+		{
+			name:         "argon2id more iterations",
+			hasher:       argon2Hasher(t, Argon2Params{Memory: 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}),
+			hash:         argon2Hash,
+			wantOutdated: true,
+		},
+		{
+			name:         "argon2id more memory",
+			hasher:       argon2Hasher(t, Argon2Params{Memory: 2048, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}),
+			hash:         argon2Hash,
+			wantOutdated: true,
+		},
+		{
+			name:         "argon2id longer salt",
+			hasher:       argon2Hasher(t, Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 32, KeyLength: 32}),
+			hash:         argon2Hash,
+			wantOutdated: true,
+		},
+		{name: "argon2id under bcrypt", hasher: bcryptHasher(t, bcrypt.MinCost), hash: argon2Hash, wantOutdated: true},
+		{name: "bcrypt under argon2id", hasher: argon2Hasher(t, testArgon2), hash: bcryptHash(t, "$2a$", bcrypt.MinCost), wantOutdated: true},
+		{name: "bcrypt same cost", hasher: bcryptHasher(t, bcrypt.MinCost), hash: bcryptHash(t, "$2a$", bcrypt.MinCost)},
+		{name: "bcrypt other cost", hasher: bcryptHasher(t, bcrypt.MinCost+1), hash: bcryptHash(t, "$2a$", bcrypt.MinCost), wantOutdated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, outdated, err := tt.hasher.Verify(tt.hash, testPassword)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if !valid {
+				t.Fatal("Verify: password rejected")
+			}
+			if outdated != tt.wantOutdated {
+				t.Errorf("outdated = %v, want %v", outdated, tt.wantOutdated)
+			}
+		})
+	}
+}
+
+func TestVerifyLegacyBcrypt(t *testing.T) {
+	hasher := argon2Hasher(t, testArgon2)
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		t.Run(prefix, func(t *testing.T) {
+			hash := bcryptHash(t, prefix, bcrypt.MinCost)
+
+			valid, outdated, err := hasher.Verify(hash, testPassword)
+			if err != nil || !valid || !outdated {
+				t.Fatalf("Verify = %v, %v, %v, want valid and outdated", valid, outdated, err)
+			}
+			valid, outdated, err = hasher.Verify(hash, testPassword+"!")
+			if err != nil || valid || outdated {
+				t.Fatalf("Verify wrong password = %v, %v, %v, want invalid", valid, outdated, err)
+			}
+		})
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{name: "empty", hash: ""},
+		{name: "plain text", hash: testPassword},
+		{name: "unknown algorithm", hash: "$argon2i$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5"},
+		{name: "missing key", hash: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ"},
+		{name: "extra field", hash: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5$"},
+		{name: "old version", hash: "$argon2id$v=16$m=1024,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5"},
+		{name: "unknown param", hash: "$argon2id$v=19$m=1024,t=1,x=1$c2FsdHNhbHQ$a2V5a2V5"},
+		{name: "param without value", hash: "$argon2id$v=19$m=1024,t,p=1$c2FsdHNhbHQ$a2V5a2V5"},
+		{name: "negative param", hash: "$argon2id$v=19$m=-1,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5"},
+		{name: "zero memory", hash: "$argon2id$v=19$m=0,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5"},
+		{name: "missing iterations", hash: "$argon2id$v=19$m=1024,p=1$c2FsdHNhbHQ$a2V5a2V5"},
+		{name: "parallelism overflow", hash: "$argon2id$v=19$m=1024,t=1,p=256$c2FsdHNhbHQ$a2V5a2V5"},
+		{name: "salt not base64", hash: "$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5a2V5"},
+		{name: "padded base64", hash: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ=$a2V5a2V5"},
+		{name: "empty salt", hash: "$argon2id$v=19$m=1024,t=1,p=1$$a2V5a2V5"},
+		{name: "empty key", hash: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ$"},
+		{name: "truncated bcrypt", hash: "$2a$04$short"},
+	}
+
+	hasher := argon2Hasher(t, testArgon2)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, outdated, err := hasher.Verify([]byte(tt.hash), testPassword)
+			if !errors.Is(err, ErrMalformedHash) {
+				t.Fatalf("Verify error = %v, want %v", err, ErrMalformedHash)
+			}
+			if valid || outdated {
+				t.Errorf("Verify = %v, %v, want invalid", valid, outdated)
+			}
+		})
+	}
+}
+
+func TestNewRejectsWeakOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "unknown algorithm", opts: Options{Algorithm: "md5"}},
+		{name: "argon2id without memory", opts: Options{Algorithm: Argon2id, Argon2: Argon2Params{Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}}},
+		{name: "argon2id short salt", opts: Options{Algorithm: Argon2id, Argon2: Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 4, KeyLength: 32}}},
+		{name: "bcrypt cost too low", opts: Options{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost - 1}},
+		{name: "bcrypt cost too high", opts: Options{Algorithm: Bcrypt, BcryptCost: bcrypt.MaxCost + 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.opts); err == nil {
+				t.Fatal("New accepted invalid options")
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Breached — список утёкших паролей
+type Breached interface {
+	Contains(password string) bool
+}
+
+// LoadBreached открывает список утёкших паролей в формате Have I Been Pwned.
+// Каталог — выгрузка по диапазонам (PwnedPasswordsDownloader): файлы XXXXX.txt, где XXXXX — первые
+// 5 символов SHA-1, а строки — остаток хеша и число утечек через двоеточие, как в ответе range API.
+// Каталог читается по одному файлу на проверку, поэтому подходит и для полной базы.
+// Файл — строки с полным SHA-1 (число утечек необязательно), загружается в память целиком
+func LoadBreached(path string) (Breached, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breached passwords: %w", err)
+	}
+	if info.IsDir() {
+		return rangeDir(path), nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breached passwords: %w", err)
+	}
+	defer file.Close()
+
+	set := make(hashSet)
+	err = readHashes(file, func(hash string) error {
+		sum, err := hex.DecodeString(hash)
+		if err != nil || len(sum) != sha1.Size {
+			return fmt.Errorf("expected full SHA-1, got %q", hash)
+		}
+		set[[sha1.Size]byte(sum)] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read breached passwords %s: %w", path, err)
+	}
+	return set, nil
+}
+
+// hashSet — SHA-1 утёкших паролей в памяти
+type hashSet map[[sha1.Size]byte]struct{}
+
+func (s hashSet) Contains(password string) bool {
+	_, ok := s[sha1.Sum([]byte(password))]
+	return ok
+}
+
+// rangeDir — каталог с файлами диапазонов range API
+type rangeDir string
+
+// длина префикса SHA-1 в range API
+const rangePrefixLength = 5
+
+func (d rangeDir) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:rangePrefixLength], hash[rangePrefixLength:]
+
+	file, err := os.Open(filepath.Join(string(d), prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	if err != nil {
+		// недоступный список не должен мешать смене пароля
+		slog.Warn("failed to read breached passwords range", "prefix", prefix, "err", err)
+		return false
+	}
+	defer file.Close()
+
+	found := false
+	err = readHashes(file, func(hash string) error {
+		if strings.EqualFold(hash, suffix) {
+			found = true
+			return io.EOF
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, io.EOF) {
+		slog.Warn("failed to read breached passwords range", "prefix", prefix, "err", err)
+	}
+	return found
+}
+
+// readHashes вызывает fn для хеша из каждой строки вида HASH[:COUNT]. Строки с нулевым числом утечек —
+// заполнение, которое добавляет range API, — и комментарии с # пропускаются. Ошибка fn прерывает чтение
+func readHashes(r io.Reader, fn func(hash string) error) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, count, _ := strings.Cut(line, ":")
+		if strings.TrimSpace(count) == "0" {
+			continue
+		}
+		if err := fn(strings.TrimSpace(hash)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,164 @@
+// Package passwordpolicy проверяет новые пароли: длину, классы символов, личные данные пользователя
+// в пароле и вхождение в локальный список утёкших паролей
+package passwordpolicy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Коды правил. Классы символов в Options.RequiredClasses задаются этими же кодами
+const (
+	RuleMinLength    = "min_length"
+	RuleMaxLength    = "max_length"
+	RuleLowercase    = "lowercase"
+	RuleUppercase    = "uppercase"
+	RuleDigit        = "digit"
+	RuleSymbol       = "symbol"
+	RulePersonalData = "personal_data"
+	RuleBreached     = "breached"
+)
+
+// Violation — нарушенное правило и текст для пользователя
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+// Error — пароль нарушает одно или несколько правил
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Options — правила политики. Нулевая длина не проверяется.
+// BreachedPath — файл или каталог с SHA-1 утёкших паролей (см. LoadBreached), пустой — список не проверяется
+type Options struct {
+	MinLength       int
+	MaxLength       int
+	RequiredClasses []string
+	BreachedPath    string
+}
+
+// класс символов и его проверка
+type characterClass struct {
+	rule    string
+	message string
+	match   func(r rune) bool
+}
+
+var characterClasses = []characterClass{
+	{RuleLowercase, "Пароль должен содержать строчную букву", unicode.IsLower},
+	{RuleUppercase, "Пароль должен содержать заглавную букву", unicode.IsUpper},
+	{RuleDigit, "Пароль должен содержать цифру", unicode.IsDigit},
+	{RuleSymbol, "Пароль должен содержать символ, отличный от буквы и цифры", func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}},
+}
+
+// минимальная длина фрагмента логина или имени, который ищется в пароле
+const minPersonalTokenLength = 4
+
+type Policy struct {
+	opts     Options
+	classes  []characterClass
+	breached Breached
+}
+
+func New(opts Options) (*Policy, error) {
+	if opts.MaxLength > 0 && opts.MaxLength < opts.MinLength {
+		return nil, fmt.Errorf("password policy: max_length %d is less than min_length %d", opts.MaxLength, opts.MinLength)
+	}
+
+	policy := &Policy{opts: opts}
+	for _, rule := range opts.RequiredClasses {
+		class, ok := findClass(rule)
+		if !ok {
+			return nil, fmt.Errorf("password policy: unknown character class %q", rule)
+		}
+		policy.classes = append(policy.classes, class)
+	}
+
+	if opts.BreachedPath != "" {
+		breached, err := LoadBreached(opts.BreachedPath)
+		if err != nil {
+			return nil, fmt.Errorf("password policy: %w", err)
+		}
+		policy.breached = breached
+	}
+	return policy, nil
+}
+
+// Check проверяет пароль и возвращает *Error со всеми нарушениями или nil.
+// personal — логин, имя и другие данные пользователя, которых не должно быть в пароле
+func (p *Policy) Check(password string, personal ...string) error {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if p.opts.MinLength > 0 && length < p.opts.MinLength {
+		violations = append(violations, Violation{RuleMinLength, fmt.Sprintf("Пароль должен быть не короче %d символов", p.opts.MinLength)})
+	}
+	if p.opts.MaxLength > 0 && length > p.opts.MaxLength {
+		violations = append(violations, Violation{RuleMaxLength, fmt.Sprintf("Пароль должен быть не длиннее %d символов", p.opts.MaxLength)})
+	}
+
+	for _, class := range p.classes {
+		if !strings.ContainsFunc(password, class.match) {
+			violations = append(violations, Violation{class.rule, class.message})
+		}
+	}
+
+	if containsPersonalData(password, personal) {
+		violations = append(violations, Violation{RulePersonalData, "Пароль не должен содержать логин или имя"})
+	}
+
+	if p.breached != nil && p.breached.Contains(password) {
+		violations = append(violations, Violation{RuleBreached, "Этот пароль встречается в утечках, выберите другой"})
+	}
+
+	if len(violations) > 0 {
+		return &Error{Violations: violations}
+	}
+	return nil
+}
+
+func findClass(rule string) (characterClass, bool) {
+	for _, class := range characterClasses {
+		if class.rule == rule {
+			return class, true
+		}
+	}
+	return characterClass{}, false
+}
+
+// containsPersonalData ищет в пароле без учёта регистра логин целиком (без домена почты)
+// и его части или слова имени длиной от minPersonalTokenLength
+func containsPersonalData(password string, personal []string) bool {
+	lowered := strings.ToLower(password)
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if local, _, ok := strings.Cut(value, "@"); ok {
+			value = local
+		}
+
+		tokens := strings.FieldsFunc(value, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		tokens = append(tokens, value)
+		for _, token := range tokens {
+			if utf8.RuneCountInString(token) >= minPersonalTokenLength && strings.Contains(lowered, token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package passwordpolicy
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// breachedPassword есть в обоих тестовых списках утечек
+const breachedPassword = "Summer2024!"
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// rules возвращает коды нарушенных правил или nil, если пароль прошёл проверку
+func rules(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var policyErr *Error
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("Check error = %v, want *Error", err)
+	}
+	codes := make([]string, len(policyErr.Violations))
+	for i, violation := range policyErr.Violations {
+		if violation.Message == "" {
+			t.Errorf("violation %s has no message", violation.Rule)
+		}
+		codes[i] = violation.Rule
+	}
+	return codes
+}
+
+func newPolicy(t *testing.T, opts Options) *Policy {
+	t.Helper()
+	policy, err := New(opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return policy
+}
+
+func TestCheckLength(t *testing.T) {
+	policy := newPolicy(t, Options{MinLength: 8, MaxLength: 12})
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{name: "too short", password: "abcdefg", want: []string{RuleMinLength}},
+		{name: "min length", password: "abcdefgh"},
+		{name: "max length", password: "abcdefghijkl"},
+		{name: "too long", password: "abcdefghijklm", want: []string{RuleMaxLength}},
+		// длина считается в символах, а не в байтах
+		{name: "cyrillic counted by runes", password: "пароль12"},
+		{name: "empty", password: "", want: []string{RuleMinLength}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules(t, policy.Check(tt.password)); !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCharacterClasses(t *testing.T) {
+	policy := newPolicy(t, Options{RequiredClasses: []string{RuleLowercase, RuleUppercase, RuleDigit, RuleSymbol}})
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{name: "all classes", password: "aB3$"},
+		{name: "no lowercase", password: "AB3$", want: []string{RuleLowercase}},
+		{name: "no uppercase", password: "ab3$", want: []string{RuleUppercase}},
+		{name: "no digit", password: "aBc$", want: []string{RuleDigit}},
+		{name: "no symbol", password: "aB34", want: []string{RuleSymbol}},
+		{name: "space is a symbol", password: "aB3 "},
+		{name: "cyrillic letters", password: "пР3$"},
+		{name: "only lowercase", password: "abcd", want: []string{RuleUppercase, RuleDigit, RuleSymbol}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules(t, policy.Check(tt.password)); !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPersonalData(t *testing.T) {
+	policy := newPolicy(t, Options{})
+	tests := []struct {
+		name     string
+		password string
+		personal []string
+		want     []string
+	}{
+		{name: "login", password: "xx-ivanov-xx", personal: []string{"ivanov"}, want: []string{RulePersonalData}},
+		{name: "login any case", password: "IvAnOv2024", personal: []string{"ivanov"}, want: []string{RulePersonalData}},
+		{name: "email local part", password: "petrov.ivan!", personal: []string{"petrov.ivan@example.com"}, want: []string{RulePersonalData}},
+		{name: "email domain is ignored", password: "example2024", personal: []string{"petrov@example.com"}},
+		{name: "part of login", password: "ivan_secret", personal: []string{"ivan.petrov"}, want: []string{RulePersonalData}},
+		{name: "word of full name", password: "ПетровСила", personal: []string{"", "Иван Петров"}, want: []string{RulePersonalData}},
+		{name: "telegram tag", password: "my-catlover", personal: []string{"catlover"}, want: []string{RulePersonalData}},
+		{name: "short fragment is ignored", password: "ivan1234", personal: []string{"iva"}},
+		{name: "unrelated", password: "correct horse battery staple", personal: []string{"ivanov", "Иван Иванов"}},
+		{name: "no personal data", password: "ivanov"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules(t, policy.Check(tt.password, tt.personal...)); !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeBreachedFile пишет список полных SHA-1 с числом утечек и без него
+func writeBreachedFile(t *testing.T) string {
+	t.Helper()
+	lines := []string{
+		"# список утечек",
+		sha1Hex(breachedPassword) + ":42",
+		strings.ToLower(sha1Hex("qwerty123")),
+		sha1Hex("padding entry") + ":0",
+		"",
+	}
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		t.Fatalf("write breached list: %v", err)
+	}
+	return path
+}
+
+// writeBreachedDir пишет выгрузку по диапазонам: файл на первые 5 символов SHA-1 с остатками хешей
+func writeBreachedDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	ranges := map[string][]string{}
+	for _, entry := range []struct {
+		password string
+		count    string
+	}{
+		{breachedPassword, "42"},
+		{"qwerty123", "7"},
+		{"padding entry", "0"},
+	} {
+		hash := sha1Hex(entry.password)
+		ranges[hash[:5]] = append(ranges[hash[:5]], hash[5:]+":"+entry.count)
+	}
+	for prefix, lines := range ranges {
+		if err := os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte(strings.Join(lines, "\r\n")), 0o600); err != nil {
+			t.Fatalf("write breached range: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestCheckBreached(t *testing.T) {
+	sources := []struct {
+		name string
+		path func(t *testing.T) string
+	}{
+		{name: "file", path: writeBreachedFile},
+		{name: "range dir", path: writeBreachedDir},
+	}
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{name: "breached", password: breachedPassword, want: []string{RuleBreached}},
+		{name: "breached without count", password: "qwerty123", want: []string{RuleBreached}},
+		{name: "case matters", password: strings.ToLower(breachedPassword)},
+		{name: "padding entry is ignored", password: "padding entry"},
+		{name: "not breached", password: "correct horse battery staple"},
+	}
+
+	for _, source := range sources {
+		policy := newPolicy(t, Options{BreachedPath: source.path(t)})
+		for _, tt := range tests {
+			t.Run(source.name+"/"+tt.name, func(t *testing.T) {
+				if got := rules(t, policy.Check(tt.password)); !slices.Equal(got, tt.want) {
+					t.Errorf("violations = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestCheckReportsAllViolations(t *testing.T) {
+	policy := newPolicy(t, Options{
+		MinLength:       12,
+		RequiredClasses: []string{RuleUppercase, RuleSymbol},
+		BreachedPath:    writeBreachedFile(t),
+	})
+
+	got := rules(t, policy.Check("qwerty123", "qwerty"))
+	want := []string{RuleMinLength, RuleUppercase, RuleSymbol, RulePersonalData, RuleBreached}
+	if !slices.Equal(got, want) {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "max below min", opts: Options{MinLength: 12, MaxLength: 8}},
+		{name: "unknown class", opts: Options{RequiredClasses: []string{"emoji"}}},
+		{name: "missing breached list", opts: Options{BreachedPath: filepath.Join(t.TempDir(), "missing.txt")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.opts); err == nil {
+				t.Fatal("New accepted invalid options")
+			}
+		})
+	}
+}
+
+func TestLoadBreachedRejectsPartialHashes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	// файл должен содержать полные SHA-1; строки диапазона без префикса — ошибка конфигурации
+	if err := os.WriteFile(path, []byte(sha1Hex(breachedPassword)[5:]+":42\n"), 0o600); err != nil {
+		t.Fatalf("write breached list: %v", err)
+	}
+	if _, err := LoadBreached(path); err == nil {
+		t.Fatal("LoadBreached accepted a range file as a full list")
+	}
+}
@@ -0,0 +1,53 @@
+// Package secretbox шифрует небольшие секреты для хранения в БД (AES-256-GCM)
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Box шифрует и расшифровывает секреты одним ключом. Зашифрованное значение — nonce и ciphertext подряд
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New принимает 32-байтный ключ
+func New(key []byte) (*Box, error) {
+	if len(key) != 32 {
+		return nil, errors.New("secretbox key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal шифрует plaintext. additionalData (например, id владельца) не шифруется, но без него значение не расшифровать:
+// так зашифрованный секрет нельзя переложить другому пользователю
+func (b *Box) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return b.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (b *Box) Open(sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < b.aead.NonceSize() {
+		return nil, errors.New("sealed value is too short")
+	}
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("open sealed value: %w", err)
+	}
+	return plaintext, nil
+}
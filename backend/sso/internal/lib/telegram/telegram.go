@@ -0,0 +1,163 @@
+// Package telegram проверяет данные, которые Telegram подписывает для бота SSO.
+// Проверка — чистая криптография без обращений к Telegram
+package telegram
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	telegramErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/telegram"
+)
+
+// clockSkew — насколько auth_date может опережать часы SSO
+const clockSkew = time.Minute
+
+// User — пользователь Telegram из подписанных данных
+type User struct {
+	ID        int64
+	FirstName string
+	LastName  string
+	Username  string
+	PhotoURL  string
+	AuthDate  time.Time
+}
+
+// Verifier проверяет подписи Telegram токеном бота. loginMaxAge и initDataMaxAge — насколько старые данные
+// Login Widget и Mini App принимаются
+type Verifier struct {
+	botToken       string
+	loginMaxAge    time.Duration
+	initDataMaxAge time.Duration
+}
+
+func NewVerifier(botToken string, loginMaxAge, initDataMaxAge time.Duration) *Verifier {
+	return &Verifier{botToken: botToken, loginMaxAge: loginMaxAge, initDataMaxAge: initDataMaxAge}
+}
+
+// VerifyLoginWidget проверяет данные Telegram Login Widget: hash = HMAC-SHA256(data_check_string, SHA256(bot_token)),
+// где data_check_string — все поля, кроме hash, в виде key=value, отсортированные по ключу и разделённые \n
+func (v *Verifier) VerifyLoginWidget(data map[string]string, now time.Time) (*User, error) {
+	if v.botToken == "" {
+		return nil, telegramErrors.ErrNotConfigured
+	}
+	secret := sha256.Sum256([]byte(v.botToken))
+	if !checkHash(data, secret[:]) {
+		return nil, telegramErrors.ErrInvalidSignature
+	}
+
+	authDate, err := checkAuthDate(data, v.loginMaxAge, now)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseInt(data["id"], 10, 64)
+	if err != nil || id <= 0 {
+		return nil, telegramErrors.ErrInvalidSignature
+	}
+	return &User{
+		ID:        id,
+		FirstName: data["first_name"],
+		LastName:  data["last_name"],
+		Username:  data["username"],
+		PhotoURL:  data["photo_url"],
+		AuthDate:  authDate,
+	}, nil
+}
+
+// VerifyInitData проверяет initData Telegram Mini App: строку query с полями user, auth_date, hash и другими.
+// hash = HMAC-SHA256(data_check_string, HMAC-SHA256("WebAppData", bot_token)), data_check_string собирается так же,
+// как для Login Widget, из декодированных значений
+func (v *Verifier) VerifyInitData(initData string, now time.Time) (*User, error) {
+	if v.botToken == "" {
+		return nil, telegramErrors.ErrNotConfigured
+	}
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return nil, telegramErrors.ErrInvalidSignature
+	}
+	data := make(map[string]string, len(values))
+	for key, value := range values {
+		if len(value) != 1 {
+			return nil, telegramErrors.ErrInvalidSignature
+		}
+		data[key] = value[0]
+	}
+
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(v.botToken))
+	if !checkHash(data, secret.Sum(nil)) {
+		return nil, telegramErrors.ErrInvalidSignature
+	}
+
+	authDate, err := checkAuthDate(data, v.initDataMaxAge, now)
+	if err != nil {
+		return nil, err
+	}
+	var webAppUser struct {
+		ID        int64  `json:"id"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+		Username  string `json:"username"`
+		PhotoURL  string `json:"photo_url"`
+	}
+	if err := json.Unmarshal([]byte(data["user"]), &webAppUser); err != nil || webAppUser.ID <= 0 {
+		return nil, telegramErrors.ErrInvalidSignature
+	}
+	return &User{
+		ID:        webAppUser.ID,
+		FirstName: webAppUser.FirstName,
+		LastName:  webAppUser.LastName,
+		Username:  webAppUser.Username,
+		PhotoURL:  webAppUser.PhotoURL,
+		AuthDate:  authDate,
+	}, nil
+}
+
+// checkAuthDate проверяет, что auth_date не из будущего и не старше maxAge
+func checkAuthDate(data map[string]string, maxAge time.Duration, now time.Time) (time.Time, error) {
+	unix, err := strconv.ParseInt(data["auth_date"], 10, 64)
+	if err != nil {
+		return time.Time{}, telegramErrors.ErrInvalidSignature
+	}
+	authDate := time.Unix(unix, 0)
+	if authDate.After(now.Add(clockSkew)) {
+		return time.Time{}, telegramErrors.ErrInvalidSignature
+	}
+	if maxAge > 0 && now.Sub(authDate) > maxAge {
+		return time.Time{}, telegramErrors.ErrExpired
+	}
+	return authDate, nil
+}
+
+// checkHash сравнивает hash из данных с HMAC-SHA256 от data_check_string на ключе secret
+func checkHash(data map[string]string, secret []byte) bool {
+	received, err := hex.DecodeString(data["hash"])
+	if err != nil || len(received) != sha256.Size {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(dataCheckString(data)))
+	return hmac.Equal(mac.Sum(nil), received)
+}
+
+func dataCheckString(data map[string]string) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		if key != "hash" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, key+"="+data[key])
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,235 @@
+package telegram
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	telegramErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/telegram"
+)
+
+const testBotToken = "123456:test-bot-token"
+
+var (
+	testNow        = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	loginMaxAge    = 24 * time.Hour
+	initDataMaxAge = time.Hour
+)
+
+// sign считает hash так, как его считает Telegram: HMAC-SHA256 от отсортированных key=value через \n
+func sign(fields map[string]string, secret []byte) string {
+	lines := make([]string, 0, len(fields))
+	for key, value := range fields {
+		lines = append(lines, key+"="+value)
+	}
+	slices.Sort(lines)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func widgetSecret() []byte {
+	secret := sha256.Sum256([]byte(testBotToken))
+	return secret[:]
+}
+
+func initDataSecret() []byte {
+	mac := hmac.New(sha256.New, []byte("WebAppData"))
+	mac.Write([]byte(testBotToken))
+	return mac.Sum(nil)
+}
+
+func widgetData(authDate time.Time) map[string]string {
+	return map[string]string{
+		"id":         "4242",
+		"first_name": "Иван",
+		"last_name":  "Петров",
+		"username":   "ivan",
+		"photo_url":  "https://t.me/i/userpic/320/ivan.jpg",
+		"auth_date":  strconv.FormatInt(authDate.Unix(), 10),
+	}
+}
+
+func TestVerifyLoginWidget(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare получает подписанные данные и портит их
+		prepare  func(data map[string]string)
+		botToken string
+		wantErr  error
+	}{
+		{name: "valid", prepare: func(map[string]string) {}},
+		{name: "tampered id", prepare: func(data map[string]string) { data["id"] = "4243" }, wantErr: telegramErrors.ErrInvalidSignature},
+		{name: "tampered username", prepare: func(data map[string]string) { data["username"] = "admin" }, wantErr: telegramErrors.ErrInvalidSignature},
+		{name: "added field", prepare: func(data map[string]string) { data["extra"] = "1" }, wantErr: telegramErrors.ErrInvalidSignature},
+		{name: "missing hash", prepare: func(data map[string]string) { delete(data, "hash") }, wantErr: telegramErrors.ErrInvalidSignature},
+		{name: "malformed hash", prepare: func(data map[string]string) { data["hash"] = "not-hex" }, wantErr: telegramErrors.ErrInvalidSignature},
+		{
+			name: "stale auth_date",
+			prepare: func(data map[string]string) {
+				resign(data, widgetSecret(), "auth_date", strconv.FormatInt(testNow.Add(-loginMaxAge-time.Second).Unix(), 10))
+			},
+			wantErr: telegramErrors.ErrExpired,
+		},
+		{
+			name: "auth_date from the future",
+			prepare: func(data map[string]string) {
+				resign(data, widgetSecret(), "auth_date", strconv.FormatInt(testNow.Add(time.Hour).Unix(), 10))
+			},
+			wantErr: telegramErrors.ErrInvalidSignature,
+		},
+		{name: "other bot", prepare: func(map[string]string) {}, botToken: "654321:other-bot-token", wantErr: telegramErrors.ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := widgetData(testNow.Add(-time.Minute))
+			data["hash"] = sign(data, widgetSecret())
+			tt.prepare(data)
+
+			botToken := testBotToken
+			if tt.botToken != "" {
+				botToken = tt.botToken
+			}
+			user, err := NewVerifier(botToken, loginMaxAge, initDataMaxAge).VerifyLoginWidget(data, testNow)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("VerifyLoginWidget error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyLoginWidget: %v", err)
+			}
+			if user.ID != 4242 || user.Username != "ivan" || user.FirstName != "Иван" {
+				t.Errorf("user = %+v, want id 4242, username ivan", user)
+			}
+		})
+	}
+}
+
+func TestVerifyLoginWidgetNotConfigured(t *testing.T) {
+	data := widgetData(testNow)
+	data["hash"] = sign(data, widgetSecret())
+
+	if _, err := NewVerifier("", loginMaxAge, initDataMaxAge).VerifyLoginWidget(data, testNow); !errors.Is(err, telegramErrors.ErrNotConfigured) {
+		t.Fatalf("VerifyLoginWidget error = %v, want %v", err, telegramErrors.ErrNotConfigured)
+	}
+}
+
+// resign меняет поле и подписывает данные заново, как если бы их прислал Telegram
+func resign(data map[string]string, secret []byte, key, value string) {
+	delete(data, "hash")
+	data[key] = value
+	data["hash"] = sign(data, secret)
+}
+
+func initDataFields(authDate time.Time) map[string]string {
+	return map[string]string{
+		"query_id":  "AAHdF6IQAAAAAN0XohDhrOrc",
+		"user":      `{"id":4242,"first_name":"Иван","last_name":"Петров","username":"ivan","language_code":"ru"}`,
+		"auth_date": strconv.FormatInt(authDate.Unix(), 10),
+	}
+}
+
+func encodeInitData(fields map[string]string) string {
+	values := url.Values{}
+	for key, value := range fields {
+		values.Set(key, value)
+	}
+	return values.Encode()
+}
+
+func TestVerifyInitData(t *testing.T) {
+	tests := []struct {
+		name string
+		// initData собирает строку initData из подписанных полей
+		initData func(fields map[string]string) string
+		wantErr  error
+	}{
+		{name: "valid", initData: encodeInitData},
+		{
+			name: "tampered user",
+			initData: func(fields map[string]string) string {
+				fields["user"] = strings.Replace(fields["user"], "4242", "4243", 1)
+				return encodeInitData(fields)
+			},
+			wantErr: telegramErrors.ErrInvalidSignature,
+		},
+		{
+			name: "tampered auth_date",
+			initData: func(fields map[string]string) string {
+				fields["auth_date"] = strconv.FormatInt(testNow.Unix(), 10)
+				return encodeInitData(fields)
+			},
+			wantErr: telegramErrors.ErrInvalidSignature,
+		},
+		{
+			name: "missing hash",
+			initData: func(fields map[string]string) string {
+				delete(fields, "hash")
+				return encodeInitData(fields)
+			},
+			wantErr: telegramErrors.ErrInvalidSignature,
+		},
+		{
+			name: "duplicated field",
+			initData: func(fields map[string]string) string {
+				return encodeInitData(fields) + "&user=" + url.QueryEscape(`{"id":1}`)
+			},
+			wantErr: telegramErrors.ErrInvalidSignature,
+		},
+		{
+			name: "signed with widget secret",
+			initData: func(fields map[string]string) string {
+				resign(fields, widgetSecret(), "auth_date", fields["auth_date"])
+				return encodeInitData(fields)
+			},
+			wantErr: telegramErrors.ErrInvalidSignature,
+		},
+		{
+			name: "stale auth_date",
+			initData: func(fields map[string]string) string {
+				resign(fields, initDataSecret(), "auth_date", strconv.FormatInt(testNow.Add(-initDataMaxAge-time.Second).Unix(), 10))
+				return encodeInitData(fields)
+			},
+			wantErr: telegramErrors.ErrExpired,
+		},
+		{
+			name: "user without id",
+			initData: func(fields map[string]string) string {
+				resign(fields, initDataSecret(), "user", `{"first_name":"Иван"}`)
+				return encodeInitData(fields)
+			},
+			wantErr: telegramErrors.ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := initDataFields(testNow.Add(-time.Minute))
+			fields["hash"] = sign(fields, initDataSecret())
+
+			user, err := NewVerifier(testBotToken, loginMaxAge, initDataMaxAge).VerifyInitData(tt.initData(fields), testNow)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("VerifyInitData error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyInitData: %v", err)
+			}
+			if user.ID != 4242 || user.Username != "ivan" || user.LastName != "Петров" {
+				t.Errorf("user = %+v, want id 4242, username ivan", user)
+			}
+		})
+	}
+}
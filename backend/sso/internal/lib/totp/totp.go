@@ -0,0 +1,85 @@
+// Package totp — одноразовые коды по времени (RFC 6238) в параметрах, которые понимают все приложения-аутентификаторы:
+// HMAC-SHA1, 6 цифр, шаг 30 секунд
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	Digits = 6
+	Period = 30 * time.Second
+	// secretSize — 160 бит, рекомендация RFC 4226 для HMAC-SHA1
+	secretSize = 20
+	// skew — сколько соседних шагов принимается из-за расхождения часов телефона
+	skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret создаёт случайный секрет
+func GenerateSecret() ([]byte, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generate totp secret: %w", err)
+	}
+	return secret, nil
+}
+
+// EncodeSecret — секрет в base32 для ручного ввода в приложение
+func EncodeSecret(secret []byte) string {
+	return encoding.EncodeToString(secret)
+}
+
+// URI — otpauth:// ссылка для QR кода (формат Google Authenticator Key Uri)
+func URI(issuer, account string, secret []byte) string {
+	q := url.Values{}
+	q.Set("secret", EncodeSecret(secret))
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(Digits))
+	q.Set("period", fmt.Sprint(int(Period.Seconds())))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// Step — номер 30-секундного шага для момента времени
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Code вычисляет код для шага (RFC 4226, раздел 5.3)
+func Code(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, value%1_000_000)
+}
+
+// Validate проверяет код для момента now с допуском в один шаг в обе стороны и возвращает шаг,
+// которому код соответствует: повторно использовать этот и более ранние шаги нельзя
+func Validate(secret []byte, code string, now time.Time) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+	current := Step(now)
+	for step := current - skew; step <= current+skew; step++ {
+		if subtle.ConstantTimeCompare([]byte(Code(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
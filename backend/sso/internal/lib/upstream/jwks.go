@@ -0,0 +1,198 @@
+package upstream
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	libjwt "github.com/EtoNeAnanasbI95/sso/internal/lib/jwt"
+)
+
+// jwksRefreshInterval — не чаще этого перечитывать JWKS из-за неизвестного kid,
+// чтобы токены с мусорным kid не превращались в запросы к провайдеру
+const jwksRefreshInterval = time.Minute
+
+// keyCache — публичные ключи провайдера, перечитываются при появлении нового kid
+type keyCache struct {
+	client *http.Client
+	url    string
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+func newKeyCache(client *http.Client, url string) *keyCache {
+	return &keyCache{client: client, url: url}
+}
+
+// key возвращает ключ по kid. Пустой kid допустим, только если у провайдера один ключ
+func (c *keyCache) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.lookup(kid); ok {
+		return key, nil
+	}
+	if !c.fetchedAt.IsZero() && time.Since(c.fetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key: %s", kid)
+	}
+	if err := c.fetch(ctx); err != nil {
+		return nil, err
+	}
+	if key, ok := c.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key: %s", kid)
+}
+
+func (c *keyCache) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" {
+		if len(c.keys) != 1 {
+			return nil, false
+		}
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+func (c *keyCache) fetch(ctx context.Context) error {
+	c.fetchedAt = time.Now()
+
+	var set libjwt.JWKS
+	if err := getJSON(ctx, c.client, c.url, &set); err != nil {
+		return fmt.Errorf("fetch jwks: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(jwk)
+		if err != nil {
+			// ключи неподдерживаемых типов не мешают проверять подписи остальными
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("jwks contains no usable signing keys")
+	}
+	c.keys = keys
+	return nil
+}
+
+// parseJWK восстанавливает публичный ключ из JWK (RFC 7518, раздел 6; RFC 8037)
+func parseJWK(jwk libjwt.JWK) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBase64URL(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64URL(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("rsa exponent is too large")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", jwk.Crv)
+		}
+		x, err := decodeBase64URL(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64URL(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("ec point is not on curve")
+		}
+		return key, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve: %s", jwk.Crv)
+		}
+		x, err := decodeBase64URL(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", jwk.Kty)
+	}
+}
+
+func decodeBase64URL(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("empty key component")
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// getJSON выполняет GET и разбирает JSON ответ; числа сохраняются как json.Number
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeResponse(resp, v)
+}
+
+func decodeResponse(resp *http.Response, v any) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return decodeJSON(resp.Body, v)
+}
+
+func decodeJSON(body io.Reader, v any) error {
+	decoder := json.NewDecoder(io.LimitReader(body, maxResponseSize))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// maxResponseSize — ограничение на размер ответа провайдера
+const maxResponseSize = 1 << 20
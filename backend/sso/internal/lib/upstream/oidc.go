@@ -0,0 +1,351 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	TokenAuthClientSecretBasic = "client_secret_basic"
+	TokenAuthClientSecretPost  = "client_secret_post"
+
+	scopeOpenID = "openid"
+
+	defaultHTTPTimeout = 10 * time.Second
+)
+
+// idTokenAlgorithms — алгоритмы подписи ID токена провайдера. HS256 не принимается:
+// им подписывают секретом клиента, а секрет знает не только провайдер
+var idTokenAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Config — параметры подключения к провайдеру.
+// Для OpenID Connect достаточно Issuer: адреса берутся из discovery. Для OAuth 2.0 без discovery
+// задаются AuthorizationURL, TokenURL и UserInfoURL; явно заданные адреса перекрывают discovery
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL — callback SSO, зарегистрированный у провайдера
+	RedirectURL string
+	Scopes      []string
+
+	AuthorizationURL string
+	TokenURL         string
+	UserInfoURL      string
+	JWKSURL          string
+	// TokenAuthMethod — client_secret_basic (по умолчанию) или client_secret_post
+	TokenAuthMethod string
+
+	Claims ClaimMapping
+	// Leeway — допуск расхождения часов при проверке ID токена
+	Leeway time.Duration
+	// HTTPClient — клиент для запросов к провайдеру; nil — клиент с таймаутом 10 секунд
+	HTTPClient *http.Client
+}
+
+type endpoints struct {
+	authorization string
+	token         string
+	userInfo      string
+	jwks          string
+}
+
+// discoveryDocument — нужная часть OpenID Provider Metadata
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// OIDC — Connector для провайдеров OpenID Connect и OAuth 2.0 с authorization code flow и PKCE.
+// Discovery выполняется при первом входе, а не при старте, чтобы недоступный провайдер не мешал запуску SSO
+type OIDC struct {
+	cfg    Config
+	client *http.Client
+
+	mu        sync.Mutex
+	endpoints *endpoints
+	keys      *keyCache
+}
+
+func NewOIDC(cfg Config) (*OIDC, error) {
+	if cfg.ClientID == "" {
+		return nil, errors.New("client id is required")
+	}
+	if cfg.RedirectURL == "" {
+		return nil, errors.New("redirect url is required")
+	}
+	if cfg.Issuer == "" && (cfg.AuthorizationURL == "" || cfg.TokenURL == "") {
+		return nil, errors.New("issuer or authorization and token urls are required")
+	}
+	if slices.Contains(cfg.Scopes, scopeOpenID) && cfg.Issuer == "" {
+		return nil, errors.New("issuer is required for openid scope")
+	}
+	if cfg.Issuer == "" && cfg.UserInfoURL == "" {
+		return nil, errors.New("userinfo url is required without openid")
+	}
+	switch cfg.TokenAuthMethod {
+	case "":
+		cfg.TokenAuthMethod = TokenAuthClientSecretBasic
+	case TokenAuthClientSecretBasic, TokenAuthClientSecretPost:
+	default:
+		return nil, fmt.Errorf("unsupported token auth method: %s", cfg.TokenAuthMethod)
+	}
+	cfg.Claims = cfg.Claims.withDefaults()
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	return &OIDC{cfg: cfg, client: client}, nil
+}
+
+func (o *OIDC) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	ep, err := o.resolve(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(ep.authorization)
+	if err != nil {
+		return "", fmt.Errorf("parse authorization url: %w", err)
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", o.cfg.ClientID)
+	q.Set("redirect_uri", o.cfg.RedirectURL)
+	if len(o.cfg.Scopes) > 0 {
+		q.Set("scope", strings.Join(o.cfg.Scopes, " "))
+	}
+	q.Set("state", state)
+	if o.openID() {
+		q.Set("nonce", nonce)
+	}
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func (o *OIDC) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	ep, err := o.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := o.exchangeCode(ctx, ep, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	// без scope openid ID токен не запрашивался, и проверить его нечем: claims придут из userinfo
+	var claims map[string]any
+	if o.openID() {
+		if tokens.IDToken == "" {
+			return nil, errors.New("id_token is missing in token response")
+		}
+		claims, err = o.verifyIDToken(ctx, tokens.IDToken, nonce)
+		if err != nil {
+			return nil, fmt.Errorf("verify id token: %w", err)
+		}
+	}
+
+	// недостающие claims (или все, если провайдер не OpenID Connect) берутся из userinfo
+	if ep.userInfo != "" && (claims == nil || o.cfg.Claims.missing(claims)) {
+		userInfo, err := o.userInfo(ctx, ep.userInfo, tokens.AccessToken)
+		if err != nil {
+			return nil, fmt.Errorf("fetch userinfo: %w", err)
+		}
+		if claims == nil {
+			claims = userInfo
+		} else {
+			// OIDC Core 5.3.2: ответ userinfo относится к тому же пользователю, что и ID токен
+			if sub, ok := userInfo["sub"]; ok && claimString(sub) != claimString(claims["sub"]) {
+				return nil, errors.New("userinfo sub does not match id token")
+			}
+			for name, value := range userInfo {
+				if _, ok := claims[name]; !ok {
+					claims[name] = value
+				}
+			}
+		}
+	}
+	if claims == nil {
+		return nil, errNoIdentity
+	}
+	return o.cfg.Claims.identity(claims)
+}
+
+func (o *OIDC) openID() bool {
+	return slices.Contains(o.cfg.Scopes, scopeOpenID)
+}
+
+// resolve возвращает адреса провайдера, при необходимости выполняя discovery.
+// Неудачный discovery не кэшируется и повторится при следующем входе
+func (o *OIDC) resolve(ctx context.Context) (*endpoints, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.endpoints != nil {
+		return o.endpoints, nil
+	}
+
+	ep := &endpoints{
+		authorization: o.cfg.AuthorizationURL,
+		token:         o.cfg.TokenURL,
+		userInfo:      o.cfg.UserInfoURL,
+		jwks:          o.cfg.JWKSURL,
+	}
+	needsJWKS := o.openID() && ep.jwks == ""
+	if o.cfg.Issuer != "" && (ep.authorization == "" || ep.token == "" || needsJWKS) {
+		var doc discoveryDocument
+		discoveryURL := strings.TrimRight(o.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := getJSON(ctx, o.client, discoveryURL, &doc); err != nil {
+			return nil, fmt.Errorf("fetch discovery document: %w", err)
+		}
+		if doc.Issuer != o.cfg.Issuer {
+			return nil, fmt.Errorf("discovery issuer %q does not match %q", doc.Issuer, o.cfg.Issuer)
+		}
+		if ep.authorization == "" {
+			ep.authorization = doc.AuthorizationEndpoint
+		}
+		if ep.token == "" {
+			ep.token = doc.TokenEndpoint
+		}
+		if ep.userInfo == "" {
+			ep.userInfo = doc.UserInfoEndpoint
+		}
+		if ep.jwks == "" {
+			ep.jwks = doc.JWKSURI
+		}
+	}
+	if ep.authorization == "" || ep.token == "" {
+		return nil, errors.New("provider has no authorization or token endpoint")
+	}
+	if o.openID() && ep.jwks == "" {
+		return nil, errors.New("provider has no jwks_uri")
+	}
+
+	if ep.jwks != "" {
+		o.keys = newKeyCache(o.client, ep.jwks)
+	}
+	o.endpoints = ep
+	return ep, nil
+}
+
+func (o *OIDC) exchangeCode(ctx context.Context, ep *endpoints, code, codeVerifier string) (*tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.cfg.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	basic := o.cfg.ClientSecret != "" && o.cfg.TokenAuthMethod == TokenAuthClientSecretBasic
+	if !basic {
+		form.Set("client_id", o.cfg.ClientID)
+		if o.cfg.ClientSecret != "" {
+			form.Set("client_secret", o.cfg.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.token, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basic {
+		// RFC 6749, раздел 2.3.1: id и секрет кодируются как form-urlencoded
+		req.SetBasicAuth(url.QueryEscape(o.cfg.ClientID), url.QueryEscape(o.cfg.ClientSecret))
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// ошибка OAuth приходит JSON телом со статусом 400 или 401, её текст полезнее самого статуса
+	var tokens tokenResponse
+	if err := decodeJSON(resp.Body, &tokens); err != nil {
+		return nil, fmt.Errorf("token request: status %d: %w", resp.StatusCode, err)
+	}
+	if tokens.Error != "" {
+		return nil, fmt.Errorf("token request: %s: %s", tokens.Error, tokens.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request: unexpected status %d", resp.StatusCode)
+	}
+	if tokens.AccessToken == "" {
+		return nil, errors.New("token request: access_token is missing")
+	}
+	return &tokens, nil
+}
+
+func (o *OIDC) verifyIDToken(ctx context.Context, raw, nonce string) (map[string]any, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(idTokenAlgorithms),
+		jwt.WithIssuer(o.cfg.Issuer),
+		jwt.WithAudience(o.cfg.ClientID),
+		jwt.WithLeeway(o.cfg.Leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+		jwt.WithJSONNumber(),
+	)
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return o.keys.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if claimString(claims["nonce"]) != nonce {
+		return nil, errors.New("nonce mismatch")
+	}
+	// OIDC Core 3.1.3.7: при нескольких аудиториях azp обязан совпадать с нашим client_id
+	audience, _ := claims.GetAudience()
+	azp := claimString(claims["azp"])
+	if (len(audience) > 1 || azp != "") && azp != o.cfg.ClientID {
+		return nil, errors.New("azp does not match client id")
+	}
+	return claims, nil
+}
+
+func (o *OIDC) userInfo(ctx context.Context, endpoint, accessToken string) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var claims map[string]any
+	if err := decodeResponse(resp, &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
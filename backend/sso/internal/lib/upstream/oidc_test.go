@@ -0,0 +1,383 @@
+package upstream
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	libjwt "github.com/EtoNeAnanasbI95/sso/internal/lib/jwt"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "sso"
+	testClientSecret = "upstream-secret"
+	testRedirectURL  = "https://sso.example.com/auth/external/corp/callback"
+	testCode         = "auth-code"
+	testVerifier     = "code-verifier"
+	testNonce        = "nonce-value"
+	testKid          = "provider-key"
+)
+
+// mockProvider — провайдер OpenID Connect на httptest.Server: discovery, JWKS, token и userinfo.
+// idToken собирает ID токен для ответа token endpoint по адресу сервера
+type mockProvider struct {
+	server  *httptest.Server
+	key     *ecdsa.PrivateKey
+	idToken func(issuer string) string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	m := &mockProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, discoveryDocument{
+			Issuer:                m.server.URL,
+			AuthorizationEndpoint: m.server.URL + "/authorize",
+			TokenEndpoint:         m.server.URL + "/token",
+			UserInfoEndpoint:      m.server.URL + "/userinfo",
+			JWKSURI:               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, libjwt.JWKS{Keys: []libjwt.JWK{{
+			Kty: "EC",
+			Use: "sig",
+			Alg: "ES256",
+			Kid: testKid,
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(key.PublicKey.X.FillBytes(make([]byte, 32))),
+			Y:   base64.RawURLEncoding.EncodeToString(key.PublicKey.Y.FillBytes(make([]byte, 32))),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, _ := r.BasicAuth()
+		if r.Method != http.MethodPost || r.ParseForm() != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+			return
+		}
+		if clientID != testClientID || clientSecret != testClientSecret {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+			return
+		}
+		if r.PostForm.Get("code") != testCode || r.PostForm.Get("code_verifier") != testVerifier || r.PostForm.Get("redirect_uri") != testRedirectURL {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{
+			"access_token": "upstream-access-token",
+			"token_type":   "Bearer",
+			"id_token":     m.idToken(m.server.URL),
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer upstream-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"sub": "user-1", "name": "Иван Петров"})
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockProvider) connector(t *testing.T) *OIDC {
+	t.Helper()
+	connector, err := NewOIDC(Config{
+		Issuer:       m.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+		Claims:       ClaimMapping{Verified: "email_verified"},
+		HTTPClient:   m.server.Client(),
+	})
+	if err != nil {
+		t.Fatalf("NewOIDC: %v", err)
+	}
+	return connector
+}
+
+// sign подписывает claims ключом провайдера, опубликованным в JWKS
+func (m *mockProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = testKid
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		t.Fatalf("sign id token: %v", err)
+	}
+	return signed
+}
+
+func validClaims(issuer string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            issuer,
+		"sub":            "user-1",
+		"aud":            testClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          testNonce,
+		"email":          "ivan@example.com",
+		"email_verified": true,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestOIDCAuthCodeURL(t *testing.T) {
+	provider := newMockProvider(t)
+	connector := provider.connector(t)
+
+	location, err := connector.AuthCodeURL(context.Background(), "state-value", testNonce, "challenge")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		t.Fatalf("parse auth url: %v", err)
+	}
+	if got, want := u.Scheme+"://"+u.Host+u.Path, provider.server.URL+"/authorize"; got != want {
+		t.Errorf("authorization endpoint = %s, want %s", got, want)
+	}
+	query := u.Query()
+	for name, want := range map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid email profile",
+		"state":                 "state-value",
+		"nonce":                 testNonce,
+		"code_challenge":        "challenge",
+		"code_challenge_method": "S256",
+	} {
+		if got := query.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestOIDCExchange(t *testing.T) {
+	tests := []struct {
+		name string
+		// idToken собирает ID токен; provider и issuer — мок и его адрес
+		idToken func(t *testing.T, provider *mockProvider, issuer string) string
+		// errContains — ожидаемая часть ошибки, пустая — вход успешен
+		errContains string
+	}{
+		{
+			name: "valid",
+			idToken: func(t *testing.T, provider *mockProvider, issuer string) string {
+				return provider.sign(t, validClaims(issuer))
+			},
+		},
+		{
+			name: "nonce mismatch",
+			idToken: func(t *testing.T, provider *mockProvider, issuer string) string {
+				claims := validClaims(issuer)
+				claims["nonce"] = "other-nonce"
+				return provider.sign(t, claims)
+			},
+			errContains: "nonce mismatch",
+		},
+		{
+			name: "missing nonce",
+			idToken: func(t *testing.T, provider *mockProvider, issuer string) string {
+				claims := validClaims(issuer)
+				delete(claims, "nonce")
+				return provider.sign(t, claims)
+			},
+			errContains: "nonce mismatch",
+		},
+		{
+			name: "wrong audience",
+			idToken: func(t *testing.T, provider *mockProvider, issuer string) string {
+				claims := validClaims(issuer)
+				claims["aud"] = "other-client"
+				return provider.sign(t, claims)
+			},
+			errContains: "aud",
+		},
+		{
+			name: "several audiences without azp",
+			idToken: func(t *testing.T, provider *mockProvider, issuer string) string {
+				claims := validClaims(issuer)
+				claims["aud"] = []string{testClientID, "other-client"}
+				return provider.sign(t, claims)
+			},
+			errContains: "azp does not match",
+		},
+		{
+			name: "wrong azp",
+			idToken: func(t *testing.T, provider *mockProvider, issuer string) string {
+				claims := validClaims(issuer)
+				claims["azp"] = "other-client"
+				return provider.sign(t, claims)
+			},
+			errContains: "azp does not match",
+		},
+		{
+			name: "several audiences with our azp",
+			idToken: func(t *testing.T, provider *mockProvider, issuer string) string {
+				claims := validClaims(issuer)
+				claims["aud"] = []string{testClientID, "other-client"}
+				claims["azp"] = testClientID
+				return provider.sign(t, claims)
+			},
+		},
+		{
+			name: "wrong issuer",
+			idToken: func(t *testing.T, provider *mockProvider, issuer string) string {
+				claims := validClaims(issuer)
+				claims["iss"] = "https://evil.example.com"
+				return provider.sign(t, claims)
+			},
+			errContains: "iss",
+		},
+		{
+			name: "expired",
+			idToken: func(t *testing.T, provider *mockProvider, issuer string) string {
+				claims := validClaims(issuer)
+				claims["iat"] = time.Now().Add(-time.Hour).Unix()
+				claims["exp"] = time.Now().Add(-30 * time.Minute).Unix()
+				return provider.sign(t, claims)
+			},
+			errContains: "expired",
+		},
+		{
+			name: "missing exp",
+			idToken: func(t *testing.T, provider *mockProvider, issuer string) string {
+				claims := validClaims(issuer)
+				delete(claims, "exp")
+				return provider.sign(t, claims)
+			},
+			errContains: "exp",
+		},
+		{
+			name: "HS256 signed with client secret",
+			idToken: func(t *testing.T, provider *mockProvider, issuer string) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims(issuer))
+				token.Header["kid"] = testKid
+				signed, err := token.SignedString([]byte(testClientSecret))
+				if err != nil {
+					t.Fatalf("sign id token: %v", err)
+				}
+				return signed
+			},
+			errContains: "signing method",
+		},
+		{
+			name: "unsigned",
+			idToken: func(t *testing.T, provider *mockProvider, issuer string) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims(issuer))
+				signed, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+				if err != nil {
+					t.Fatalf("sign id token: %v", err)
+				}
+				return signed
+			},
+			errContains: "signing method",
+		},
+		{
+			name: "signed by unknown key",
+			idToken: func(t *testing.T, provider *mockProvider, issuer string) string {
+				other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				if err != nil {
+					t.Fatalf("generate key: %v", err)
+				}
+				token := jwt.NewWithClaims(jwt.SigningMethodES256, validClaims(issuer))
+				token.Header["kid"] = testKid
+				signed, err := token.SignedString(other)
+				if err != nil {
+					t.Fatalf("sign id token: %v", err)
+				}
+				return signed
+			},
+			errContains: "signature is invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newMockProvider(t)
+			provider.idToken = func(issuer string) string {
+				return tt.idToken(t, provider, issuer)
+			}
+			connector := provider.connector(t)
+
+			identity, err := connector.Exchange(context.Background(), testCode, testVerifier, testNonce)
+			if tt.errContains != "" {
+				if err == nil {
+					t.Fatalf("Exchange succeeded, want error containing %q", tt.errContains)
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("Exchange error = %q, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if identity.Subject != "user-1" || identity.Login != "ivan@example.com" || !identity.Verified {
+				t.Errorf("identity = %+v, want user-1 / ivan@example.com / verified", identity)
+			}
+			// name нет в ID токене, он добирается из userinfo
+			if identity.FullName != "Иван Петров" {
+				t.Errorf("full name = %q, want it from userinfo", identity.FullName)
+			}
+		})
+	}
+}
+
+func TestOIDCExchangeRejectsDiscoveryIssuerMismatch(t *testing.T) {
+	provider := newMockProvider(t)
+	connector, err := NewOIDC(Config{
+		Issuer:      provider.server.URL + "/other",
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+		Scopes:      []string{"openid"},
+		HTTPClient:  provider.server.Client(),
+	})
+	if err != nil {
+		t.Fatalf("NewOIDC: %v", err)
+	}
+
+	if _, err := connector.AuthCodeURL(context.Background(), "state", testNonce, "challenge"); err == nil {
+		t.Fatal("AuthCodeURL accepted a discovery document of another issuer")
+	}
+}
+
+func TestOIDCExchangeRejectsInvalidGrant(t *testing.T) {
+	provider := newMockProvider(t)
+	provider.idToken = func(issuer string) string {
+		return provider.sign(t, validClaims(issuer))
+	}
+	connector := provider.connector(t)
+
+	_, err := connector.Exchange(context.Background(), "other-code", testVerifier, testNonce)
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Fatalf("Exchange error = %v, want invalid_grant", err)
+	}
+}
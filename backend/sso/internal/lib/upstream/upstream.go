@@ -0,0 +1,114 @@
+// Package upstream — вход через внешние провайдеры удостоверений (OIDC / OAuth 2.0).
+// Connector отвечает только за протокол с провайдером: ссылку на вход, обмен кода и проверку ответа.
+// Связывание с локальными пользователями делает сервис federation.
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Identity — пользователь внешнего провайдера после сопоставления claims
+type Identity struct {
+	// Subject — постоянный идентификатор пользователя у провайдера
+	Subject  string
+	Login    string
+	FullName string
+	// Verified — провайдер подтвердил Login (например, email_verified). Всегда false, если claim не настроен
+	Verified bool
+	// Claims — все claims, из которых собран Identity
+	Claims map[string]any
+}
+
+// Connector — внешний провайдер удостоверений
+type Connector interface {
+	// AuthCodeURL возвращает адрес, куда перенаправить браузер для входа у провайдера.
+	// codeChallenge — PKCE S256, nonce попадёт в ID токен
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	// Exchange обменивает код из callback на проверенное удостоверение пользователя
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error)
+}
+
+// ClaimMapping — из каких claims брать поля пользователя. Пустое поле — значение по умолчанию,
+// кроме Verified: без него подтверждение логина не проверяется и считается отсутствующим
+type ClaimMapping struct {
+	Subject  string
+	Login    string
+	FullName string
+	Verified string
+}
+
+func (m ClaimMapping) withDefaults() ClaimMapping {
+	if m.Subject == "" {
+		m.Subject = "sub"
+	}
+	if m.Login == "" {
+		m.Login = "email"
+	}
+	if m.FullName == "" {
+		m.FullName = "name"
+	}
+	return m
+}
+
+// identity собирает Identity из claims провайдера
+func (m ClaimMapping) identity(claims map[string]any) (*Identity, error) {
+	subject := claimString(claims[m.Subject])
+	if subject == "" {
+		return nil, fmt.Errorf("claim %q is missing", m.Subject)
+	}
+	identity := &Identity{
+		Subject:  subject,
+		Login:    claimString(claims[m.Login]),
+		FullName: claimString(claims[m.FullName]),
+		Claims:   claims,
+	}
+	if m.Verified != "" {
+		identity.Verified = claimBool(claims[m.Verified])
+	}
+	return identity, nil
+}
+
+// missing сообщает, что в claims нет хотя бы одного сопоставленного поля
+func (m ClaimMapping) missing(claims map[string]any) bool {
+	for _, name := range []string{m.Subject, m.Login, m.FullName, m.Verified} {
+		if name == "" {
+			continue
+		}
+		if _, ok := claims[name]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// claimString приводит claim к строке: провайдеры OAuth 2.0 нередко отдают числовой id
+func claimString(v any) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+func claimBool(v any) bool {
+	switch value := v.(type) {
+	case bool:
+		return value
+	case string:
+		parsed, _ := strconv.ParseBool(value)
+		return parsed
+	default:
+		return false
+	}
+}
+
+var errNoIdentity = errors.New("provider returned neither id_token nor userinfo")
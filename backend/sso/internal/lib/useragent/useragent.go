@@ -0,0 +1,61 @@
+package useragent
+
+import "strings"
+
+// Порядок важен: Edge и Opera содержат в User-Agent ещё и Chrome, а Chrome — Safari
+var browsers = []struct {
+	marker string
+	name   string
+}{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"YaBrowser/", "Yandex Browser"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"PostmanRuntime/", "Postman"},
+	{"TelegramBot", "Telegram"},
+}
+
+var systems = []struct {
+	marker string
+	name   string
+}{
+	{"Android", "Android"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// Describe превращает User-Agent в короткое описание устройства, например «Chrome on Windows»
+func Describe(userAgent string) string {
+	browser := match(userAgent, browsers)
+	system := match(userAgent, systems)
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "Unknown device"
+	}
+}
+
+func match(userAgent string, candidates []struct {
+	marker string
+	name   string
+}) string {
+	for _, candidate := range candidates {
+		if strings.Contains(userAgent, candidate.marker) {
+			return candidate.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Флаги authenticator data (WebAuthn, раздел 6.1)
+const (
+	flagUserPresent    byte = 0x01
+	flagUserVerified   byte = 0x04
+	flagBackupEligible byte = 0x08
+	flagBackedUp       byte = 0x10
+	flagAttestedData   byte = 0x40
+	flagExtensions     byte = 0x80
+)
+
+// maxCredentialIDLength — предел длины идентификатора учётных данных по спецификации
+const maxCredentialIDLength = 1023
+
+// authenticatorData — разобранные данные аутентификатора
+type authenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+	// заполнены только при регистрации (флаг AT)
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+func (a *authenticatorData) has(flag byte) bool {
+	return a.flags&flag != 0
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data is too short")
+	}
+	ad := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if ad.has(flagAttestedData) {
+		if len(rest) < 18 {
+			return nil, errors.New("attested credential data is too short")
+		}
+		ad.aaguid = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength == 0 || idLength > maxCredentialIDLength || len(rest) < idLength {
+			return nil, errors.New("invalid credential id length")
+		}
+		ad.credentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		// ключ COSE не имеет поля длины: его граница — конец элемента CBOR
+		_, remaining, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+		ad.publicKey = rest[:len(rest)-len(remaining)]
+		rest = remaining
+	}
+	if ad.has(flagExtensions) {
+		_, remaining, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+		rest = remaining
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("authenticator data has trailing bytes")
+	}
+	return ad, nil
+}
@@ -0,0 +1,170 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// maxCBORDepth — ограничение вложенности, чтобы присланные клиентом данные не раскручивали стек
+const maxCBORDepth = 16
+
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+// decodeCBOR разбирает один элемент CBOR (RFC 8949) и возвращает его вместе с непрочитанным остатком.
+// Поддерживается подмножество, которое встречается в WebAuthn: целые числа, байтовые и текстовые строки,
+// массивы, словари и false/true/null. Неопределённая длина, теги и числа с плавающей точкой не поддерживаются.
+// Целые возвращаются как int64, словари — как map[any]any с ключами int64 или string
+func decodeCBOR(data []byte) (any, []byte, error) {
+	d := cborDecoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, d.data, nil
+}
+
+type cborDecoder struct {
+	data []byte
+}
+
+func (d *cborDecoder) value(depth int) (any, error) {
+	if depth > maxCBORDepth {
+		return nil, errors.New("cbor: nesting is too deep")
+	}
+	if len(d.data) == 0 {
+		return nil, errCBORTruncated
+	}
+	initial := d.data[0]
+	d.data = d.data[1:]
+	major, info := initial>>5, initial&0x1f
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22:
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+		}
+	}
+
+	arg, err := d.argument(info)
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: integer overflow")
+		}
+		return int64(arg), nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: integer overflow")
+		}
+		return -1 - int64(arg), nil
+	case 2:
+		raw, err := d.take(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), raw...), nil
+	case 3:
+		raw, err := d.take(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(raw) {
+			return nil, errors.New("cbor: invalid utf-8 in text string")
+		}
+		return string(raw), nil
+	case 4:
+		// каждый элемент занимает хотя бы байт: длина больше остатка — заведомо испорченные данные
+		if arg > uint64(len(d.data)) {
+			return nil, errCBORTruncated
+		}
+		items := make([]any, 0, arg)
+		for range arg {
+			item, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5:
+		if arg > uint64(len(d.data)/2) {
+			return nil, errCBORTruncated
+		}
+		m := make(map[any]any, arg)
+		for range arg {
+			key, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, errors.New("cbor: unsupported map key type")
+			}
+			if _, ok := m[key]; ok {
+				return nil, errors.New("cbor: duplicate map key")
+			}
+			value, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("cbor: unsupported major type %d", major)
+	}
+}
+
+func (d *cborDecoder) argument(info byte) (uint64, error) {
+	var size int
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, fmt.Errorf("cbor: unsupported additional info %d", info)
+	}
+	raw, err := d.take(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(raw[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(raw)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(raw)), nil
+	default:
+		return binary.BigEndian.Uint64(raw), nil
+	}
+}
+
+func (d *cborDecoder) take(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)) {
+		return nil, errCBORTruncated
+	}
+	raw := d.data[:n]
+	d.data = d.data[n:]
+	return raw, nil
+}
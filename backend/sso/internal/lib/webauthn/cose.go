@@ -0,0 +1,121 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Алгоритмы COSE (RFC 9053), которые принимает сервер
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// supportedAlgorithms — в порядке предпочтения, так они перечисляются в pubKeyCredParams
+var supportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+// Параметры ключа COSE (RFC 9052, раздел 7; RFC 9053, раздел 7)
+const (
+	coseKty    int64 = 1
+	coseAlg    int64 = 3
+	coseCrv    int64 = -1
+	coseX      int64 = -2
+	coseY      int64 = -3
+	coseRSAN   int64 = -1
+	coseRSAE   int64 = -2
+	ktyOKP     int64 = 1
+	ktyEC2     int64 = 2
+	ktyRSA     int64 = 3
+	crvP256    int64 = 1
+	crvEd25519 int64 = 6
+)
+
+const minRSAKeyBits = 2048
+
+// coseKey — публичный ключ учётных данных и алгоритм, которым им подписывают
+type coseKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// parseCOSEKey разбирает публичный ключ в формате COSE_Key, как он хранится и приходит от аутентификатора
+func parseCOSEKey(data []byte) (*coseKey, error) {
+	v, rest, err := decodeCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("cose key: trailing data")
+	}
+	params, ok := v.(map[any]any)
+	if !ok {
+		return nil, errors.New("cose key: not a map")
+	}
+	kty, _ := params[coseKty].(int64)
+	alg, _ := params[coseAlg].(int64)
+
+	switch alg {
+	case AlgES256:
+		crv, _ := params[coseCrv].(int64)
+		x, _ := params[coseX].([]byte)
+		y, _ := params[coseY].([]byte)
+		if kty != ktyEC2 || crv != crvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("cose key: invalid ES256 key")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("cose key: ec point is not on curve")
+		}
+		return &coseKey{alg: alg, key: key}, nil
+	case AlgEdDSA:
+		crv, _ := params[coseCrv].(int64)
+		x, _ := params[coseX].([]byte)
+		if kty != ktyOKP || crv != crvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("cose key: invalid EdDSA key")
+		}
+		return &coseKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+	case AlgRS256:
+		n, _ := params[coseRSAN].([]byte)
+		e, _ := params[coseRSAE].([]byte)
+		if kty != ktyRSA || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("cose key: invalid RS256 key")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < minRSAKeyBits {
+			return nil, errors.New("cose key: rsa key is too short")
+		}
+		return &coseKey{alg: alg, key: key}, nil
+	default:
+		return nil, fmt.Errorf("cose key: unsupported algorithm %d", alg)
+	}
+}
+
+// verify проверяет подпись signature над signed
+func (k *coseKey) verify(signed, signature []byte) error {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, signed, signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	default:
+		return errors.New("unsupported key")
+	}
+}
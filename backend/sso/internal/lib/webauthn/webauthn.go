@@ -0,0 +1,397 @@
+// Package webauthn — серверная часть WebAuthn Level 2 (relying party): параметры церемоний регистрации
+// и входа для navigator.credentials и проверка ответов аутентификатора.
+// Аттестация не запрашивается и не проверяется: ключу доверяют потому, что его зарегистрировал
+// уже вошедший пользователь, а не из-за производителя аутентификатора.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Требование проверки пользователя (PIN, биометрия) аутентификатором
+const (
+	UserVerificationRequired    = "required"
+	UserVerificationPreferred   = "preferred"
+	UserVerificationDiscouraged = "discouraged"
+)
+
+const (
+	credentialType  = "public-key"
+	challengeLength = 32
+)
+
+// ErrSignCountRegression — счётчик подписей не вырос: вероятно, ключ скопирован с аутентификатора
+var ErrSignCountRegression = errors.New("webauthn: signature counter did not increase")
+
+// Bytes — бинарное поле. В JSON кодируется base64url без дополнения, как принято в WebAuthn
+type Bytes []byte
+
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// RelyingPartyEntity — сервис, для которого создаются учётные данные
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity — пользователь, которому принадлежат учётные данные
+type UserEntity struct {
+	ID          Bytes  `json:"id" swaggertype:"string"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// CredentialDescriptor — ссылка на уже зарегистрированные учётные данные
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         Bytes    `json:"id" swaggertype:"string"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions — параметры navigator.credentials.create({publicKey: ...})
+type CreationOptions struct {
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              Bytes                  `json:"challenge" swaggertype:"string"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions — параметры navigator.credentials.get({publicKey: ...}).
+// Пустой AllowCredentials — вход по passkey без логина: аутентификатор сам предлагает учётные данные
+type RequestOptions struct {
+	Challenge        Bytes                  `json:"challenge" swaggertype:"string"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationResponse — PublicKeyCredential из navigator.credentials.create в JSON
+type RegistrationResponse struct {
+	ID       string              `json:"id"`
+	RawID    Bytes               `json:"rawId" swaggertype:"string"`
+	Type     string              `json:"type"`
+	Response AttestationResponse `json:"response"`
+}
+
+type AttestationResponse struct {
+	ClientDataJSON    Bytes    `json:"clientDataJSON" swaggertype:"string"`
+	AttestationObject Bytes    `json:"attestationObject" swaggertype:"string"`
+	Transports        []string `json:"transports,omitempty"`
+}
+
+// AssertionResponse — PublicKeyCredential из navigator.credentials.get в JSON
+type AssertionResponse struct {
+	ID       string        `json:"id"`
+	RawID    Bytes         `json:"rawId" swaggertype:"string"`
+	Type     string        `json:"type"`
+	Response AssertionData `json:"response"`
+}
+
+type AssertionData struct {
+	ClientDataJSON    Bytes `json:"clientDataJSON" swaggertype:"string"`
+	AuthenticatorData Bytes `json:"authenticatorData" swaggertype:"string"`
+	Signature         Bytes `json:"signature" swaggertype:"string"`
+	UserHandle        Bytes `json:"userHandle,omitempty" swaggertype:"string"`
+}
+
+// Credential — проверенные учётные данные после регистрации
+type Credential struct {
+	ID []byte
+	// PublicKey — ключ в формате COSE_Key, в нём же он передаётся в VerifyAssertion
+	PublicKey  []byte
+	SignCount  uint32
+	AAGUID     []byte
+	Transports []string
+	// UserVerified — аутентификатор проверил пользователя при регистрации
+	UserVerified bool
+	// BackupEligible — ключ синхронизируется между устройствами (passkey в облаке)
+	BackupEligible bool
+}
+
+// Config — параметры relying party. RPID — домен, к которому привязываются ключи (без схемы и порта),
+// Origins — адреса страниц, с которых разрешены церемонии
+type Config struct {
+	RPID    string
+	RPName  string
+	Origins []string
+	Timeout time.Duration
+}
+
+type RelyingParty struct {
+	id       string
+	name     string
+	rpIDHash [32]byte
+	origins  map[string]struct{}
+	timeout  time.Duration
+}
+
+func NewRelyingParty(cfg Config) (*RelyingParty, error) {
+	if cfg.RPID == "" {
+		return nil, errors.New("webauthn: rp id is required")
+	}
+	if len(cfg.Origins) == 0 {
+		return nil, errors.New("webauthn: at least one origin is required")
+	}
+	origins := make(map[string]struct{}, len(cfg.Origins))
+	for _, origin := range cfg.Origins {
+		origins[strings.TrimRight(origin, "/")] = struct{}{}
+	}
+	name := cfg.RPName
+	if name == "" {
+		name = cfg.RPID
+	}
+	return &RelyingParty{
+		id:       cfg.RPID,
+		name:     name,
+		rpIDHash: sha256.Sum256([]byte(cfg.RPID)),
+		origins:  origins,
+		timeout:  cfg.Timeout,
+	}, nil
+}
+
+// Timeout — сколько ждать ответа аутентификатора
+func (rp *RelyingParty) Timeout() time.Duration {
+	return rp.timeout
+}
+
+// NewChallenge генерирует одноразовый вызов церемонии
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, challengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, fmt.Errorf("generate webauthn challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// CreationOptions собирает параметры регистрации. exclude — уже зарегистрированные ключи пользователя,
+// чтобы один аутентификатор не добавлялся дважды. Сервер предпочитает passkey (discoverable credential)
+func (rp *RelyingParty) CreationOptions(challenge []byte, user UserEntity, exclude []CredentialDescriptor, userVerification string) *CreationOptions {
+	params := make([]CredentialParameter, 0, len(supportedAlgorithms))
+	for _, alg := range supportedAlgorithms {
+		params = append(params, CredentialParameter{Type: credentialType, Alg: alg})
+	}
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+	return &CreationOptions{
+		RP:                 RelyingPartyEntity{ID: rp.id, Name: rp.name},
+		User:               user,
+		Challenge:          challenge,
+		PubKeyCredParams:   params,
+		Timeout:            rp.timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: userVerification,
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions собирает параметры входа. allow пуст — вход любым passkey этого сервиса
+func (rp *RelyingParty) RequestOptions(challenge []byte, allow []CredentialDescriptor, userVerification string) *RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.timeout.Milliseconds(),
+		RPID:             rp.id,
+		AllowCredentials: allow,
+		UserVerification: userVerification,
+	}
+}
+
+// Descriptor — ссылка на учётные данные для excludeCredentials и allowCredentials
+func Descriptor(id []byte, transports []string) CredentialDescriptor {
+	return CredentialDescriptor{Type: credentialType, ID: id, Transports: transports}
+}
+
+// clientData — CollectedClientData, который браузер подписывает вместе с данными аутентификатора
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// ClientChallenge достаёт вызов из clientDataJSON без проверки, чтобы найти церемонию, к которой относится ответ
+func ClientChallenge(clientDataJSON []byte) ([]byte, error) {
+	var data clientData
+	if err := json.Unmarshal(clientDataJSON, &data); err != nil {
+		return nil, fmt.Errorf("decode client data: %w", err)
+	}
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(data.Challenge, "="))
+}
+
+func (rp *RelyingParty) verifyClientData(raw []byte, expectedType string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("decode client data: %w", err)
+	}
+	if data.Type != expectedType {
+		return fmt.Errorf("unexpected client data type %q", data.Type)
+	}
+	received, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(data.Challenge, "="))
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return errors.New("challenge mismatch")
+	}
+	if _, ok := rp.origins[data.Origin]; !ok {
+		return fmt.Errorf("origin %q is not allowed", data.Origin)
+	}
+	if data.CrossOrigin {
+		return errors.New("cross-origin ceremonies are not allowed")
+	}
+	return nil
+}
+
+// verifyAuthenticatorData проверяет, что данные получены для этого RP и пользователь присутствовал
+func (rp *RelyingParty) verifyAuthenticatorData(ad *authenticatorData, requireUserVerification bool) error {
+	if subtle.ConstantTimeCompare(ad.rpIDHash, rp.rpIDHash[:]) != 1 {
+		return errors.New("rp id hash mismatch")
+	}
+	if !ad.has(flagUserPresent) {
+		return errors.New("user presence is required")
+	}
+	if requireUserVerification && !ad.has(flagUserVerified) {
+		return errors.New("user verification is required")
+	}
+	if !ad.has(flagBackupEligible) && ad.has(flagBackedUp) {
+		return errors.New("invalid backup flags")
+	}
+	return nil
+}
+
+// VerifyRegistration проверяет ответ navigator.credentials.create на вызов challenge
+// и возвращает учётные данные для сохранения
+func (rp *RelyingParty) VerifyRegistration(response *RegistrationResponse, challenge []byte, requireUserVerification bool) (*Credential, error) {
+	if response.Type != credentialType {
+		return nil, fmt.Errorf("unexpected credential type %q", response.Type)
+	}
+	if err := rp.verifyClientData(response.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	decoded, rest, err := decodeCBOR(response.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("decode attestation object: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("attestation object has trailing bytes")
+	}
+	attestation, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, errors.New("attestation object is not a map")
+	}
+	format, _ := attestation["fmt"].(string)
+	rawAuthData, _ := attestation["authData"].([]byte)
+	statement, ok := attestation["attStmt"].(map[any]any)
+	if format == "" || rawAuthData == nil || !ok {
+		return nil, errors.New("malformed attestation object")
+	}
+	// запрошен attestation: "none"; прочие форматы присылают некоторые аутентификаторы, их заявление игнорируется
+	if format == "none" && len(statement) != 0 {
+		return nil, errors.New("none attestation must have an empty statement")
+	}
+
+	ad, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthenticatorData(ad, requireUserVerification); err != nil {
+		return nil, err
+	}
+	if !ad.has(flagAttestedData) {
+		return nil, errors.New("attested credential data is missing")
+	}
+	if !bytes.Equal(ad.credentialID, response.RawID) {
+		return nil, errors.New("credential id mismatch")
+	}
+	if _, err := parseCOSEKey(ad.publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:             bytes.Clone(ad.credentialID),
+		PublicKey:      bytes.Clone(ad.publicKey),
+		SignCount:      ad.signCount,
+		AAGUID:         bytes.Clone(ad.aaguid),
+		Transports:     response.Response.Transports,
+		UserVerified:   ad.has(flagUserVerified),
+		BackupEligible: ad.has(flagBackupEligible),
+	}, nil
+}
+
+// VerifyAssertion проверяет ответ navigator.credentials.get на вызов challenge ключом publicKey (COSE_Key)
+// и возвращает новое значение счётчика подписей. storedSignCount — счётчик с прошлого входа
+func (rp *RelyingParty) VerifyAssertion(response *AssertionResponse, challenge, publicKey []byte, storedSignCount uint32, requireUserVerification bool) (uint32, error) {
+	if response.Type != credentialType {
+		return 0, fmt.Errorf("unexpected credential type %q", response.Type)
+	}
+	if err := rp.verifyClientData(response.Response.ClientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+	ad, err := parseAuthenticatorData(response.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	if err := rp.verifyAuthenticatorData(ad, requireUserVerification); err != nil {
+		return 0, err
+	}
+
+	key, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(response.Response.ClientDataJSON)
+	signed := append(bytes.Clone(response.Response.AuthenticatorData), clientDataHash[:]...)
+	if err := key.verify(signed, response.Response.Signature); err != nil {
+		return 0, err
+	}
+
+	// аутентификаторы без счётчика всегда присылают 0 — тогда сравнивать нечего
+	if (ad.signCount != 0 || storedSignCount != 0) && ad.signCount <= storedSignCount {
+		return 0, ErrSignCountRegression
+	}
+	return ad.signCount, nil
+}
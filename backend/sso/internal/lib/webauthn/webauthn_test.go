@@ -0,0 +1,219 @@
+package webauthn_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/webauthn"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/webauthn/webauthntest"
+)
+
+const (
+	testRPID   = "sso.example.com"
+	testOrigin = "https://sso.example.com"
+)
+
+func newRelyingParty(t *testing.T) *webauthn.RelyingParty {
+	t.Helper()
+	rp, err := webauthn.NewRelyingParty(webauthn.Config{
+		RPID:    testRPID,
+		RPName:  "SSO",
+		Origins: []string{testOrigin + "/"},
+		Timeout: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewRelyingParty: %v", err)
+	}
+	return rp
+}
+
+func newChallenge(t *testing.T) []byte {
+	t.Helper()
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge: %v", err)
+	}
+	return challenge
+}
+
+// register проходит регистрацию ключа аутентификатора и возвращает сохранённые учётные данные
+func register(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+	challenge := newChallenge(t)
+	options := rp.CreationOptions(challenge, webauthn.UserEntity{ID: []byte{1}, Name: "user", DisplayName: "User"}, nil, webauthn.UserVerificationPreferred)
+	response, err := authenticator.Create(options)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	credential, err := rp.VerifyRegistration(response, challenge, false)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	return credential
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	tests := []struct {
+		name         string
+		origin       string
+		userVerified bool
+		// otherChallenge — сервер проверяет ответ на другой вызов
+		otherChallenge bool
+		requireUV      bool
+		wantErr        bool
+	}{
+		{name: "valid", origin: testOrigin, userVerified: true, requireUV: true},
+		{name: "user verification not required", origin: testOrigin, userVerified: false},
+		{name: "bad origin", origin: "https://evil.example.com", userVerified: true, wantErr: true},
+		{name: "bad challenge", origin: testOrigin, userVerified: true, otherChallenge: true, wantErr: true},
+		{name: "user verification required", origin: testOrigin, userVerified: false, requireUV: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := newRelyingParty(t)
+			authenticator := webauthntest.New(tt.origin)
+			authenticator.UserVerified = tt.userVerified
+
+			challenge := newChallenge(t)
+			options := rp.CreationOptions(challenge, webauthn.UserEntity{ID: []byte{1}, Name: "user", DisplayName: "User"}, nil, webauthn.UserVerificationPreferred)
+			response, err := authenticator.Create(options)
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if tt.otherChallenge {
+				challenge = newChallenge(t)
+			}
+
+			credential, err := rp.VerifyRegistration(response, challenge, tt.requireUV)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("VerifyRegistration succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyRegistration: %v", err)
+			}
+			if !bytes.Equal(credential.ID, response.RawID) {
+				t.Errorf("credential id = %x, want %x", credential.ID, response.RawID)
+			}
+			if credential.UserVerified != tt.userVerified {
+				t.Errorf("UserVerified = %v, want %v", credential.UserVerified, tt.userVerified)
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrationRejectsTamperedCredentialID(t *testing.T) {
+	rp := newRelyingParty(t)
+	challenge := newChallenge(t)
+	options := rp.CreationOptions(challenge, webauthn.UserEntity{ID: []byte{1}, Name: "user", DisplayName: "User"}, nil, webauthn.UserVerificationPreferred)
+	response, err := webauthntest.New(testOrigin).Create(options)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	response.RawID = append(bytes.Clone(response.RawID), 0)
+
+	if _, err := rp.VerifyRegistration(response, challenge, false); err == nil {
+		t.Fatal("VerifyRegistration accepted a credential id that differs from authenticator data")
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	tests := []struct {
+		name         string
+		origin       string
+		userVerified bool
+		// otherChallenge — сервер проверяет ответ на другой вызов
+		otherChallenge bool
+		// storedSignCount — счётчик с прошлого входа; ответ аутентификатора приходит со счётчиком 1
+		storedSignCount uint32
+		requireUV       bool
+		wantErr         error
+		wantAnyErr      bool
+	}{
+		{name: "valid", origin: testOrigin, userVerified: true, requireUV: true},
+		{name: "bad origin", origin: "https://evil.example.com", userVerified: true, wantAnyErr: true},
+		{name: "bad challenge", origin: testOrigin, userVerified: true, otherChallenge: true, wantAnyErr: true},
+		{name: "sign counter not increased", origin: testOrigin, userVerified: true, storedSignCount: 1, wantErr: webauthn.ErrSignCountRegression},
+		{name: "sign counter regression", origin: testOrigin, userVerified: true, storedSignCount: 5, wantErr: webauthn.ErrSignCountRegression},
+		{name: "user verification required", origin: testOrigin, userVerified: false, requireUV: true, wantAnyErr: true},
+		{name: "user verification not required", origin: testOrigin, userVerified: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := newRelyingParty(t)
+			authenticator := webauthntest.New(testOrigin)
+			credential := register(t, rp, authenticator)
+			authenticator.Origin = tt.origin
+			authenticator.UserVerified = tt.userVerified
+
+			challenge := newChallenge(t)
+			options := rp.RequestOptions(challenge, []webauthn.CredentialDescriptor{webauthn.Descriptor(credential.ID, nil)}, webauthn.UserVerificationPreferred)
+			response, err := authenticator.Get(options)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if tt.otherChallenge {
+				challenge = newChallenge(t)
+			}
+
+			signCount, err := rp.VerifyAssertion(response, challenge, credential.PublicKey, tt.storedSignCount, tt.requireUV)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("VerifyAssertion error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantAnyErr:
+				if err == nil {
+					t.Fatal("VerifyAssertion succeeded, want error")
+				}
+			default:
+				if err != nil {
+					t.Fatalf("VerifyAssertion: %v", err)
+				}
+				if signCount != 1 {
+					t.Errorf("sign count = %d, want 1", signCount)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionRejectsTamperedSignature(t *testing.T) {
+	rp := newRelyingParty(t)
+	authenticator := webauthntest.New(testOrigin)
+	credential := register(t, rp, authenticator)
+
+	challenge := newChallenge(t)
+	response, err := authenticator.Get(rp.RequestOptions(challenge, nil, webauthn.UserVerificationPreferred))
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	response.Response.AuthenticatorData[len(response.Response.AuthenticatorData)-1] ^= 0xff
+
+	if _, err := rp.VerifyAssertion(response, challenge, credential.PublicKey, 0, false); err == nil {
+		t.Fatal("VerifyAssertion accepted tampered authenticator data")
+	}
+}
+
+func TestVerifyAssertionRejectsOtherKey(t *testing.T) {
+	rp := newRelyingParty(t)
+	authenticator := webauthntest.New(testOrigin)
+	register(t, rp, authenticator)
+	other := register(t, rp, webauthntest.New(testOrigin))
+
+	challenge := newChallenge(t)
+	response, err := authenticator.Get(rp.RequestOptions(challenge, nil, webauthn.UserVerificationPreferred))
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if _, err := rp.VerifyAssertion(response, challenge, other.PublicKey, 0, false); err == nil {
+		t.Fatal("VerifyAssertion accepted a signature made by another key")
+	}
+}
@@ -0,0 +1,205 @@
+// Package webauthntest — программный аутентификатор WebAuthn. Выполняет церемонии так же, как браузер
+// с аппаратным ключом, чтобы регистрацию и вход можно было проверить без устройства
+package webauthntest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/webauthn"
+)
+
+// Authenticator хранит ключи ES256 в памяти. Все созданные учётные данные — passkey:
+// они находятся по rpId без allowCredentials и возвращают userHandle
+type Authenticator struct {
+	// Origin — адрес страницы, от имени которой «браузер» выполняет церемонии
+	Origin string
+	// UserVerified — аутентификатор проверяет пользователя (флаг UV)
+	UserVerified bool
+	// BackupEligible — ключи синхронизируемые (флаги BE и BS)
+	BackupEligible bool
+
+	mu          sync.Mutex
+	credentials []*credential
+}
+
+type credential struct {
+	id         []byte
+	rpID       string
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+	signCount  uint32
+}
+
+func New(origin string) *Authenticator {
+	return &Authenticator{Origin: origin, UserVerified: true}
+}
+
+// Create выполняет navigator.credentials.create
+func (a *Authenticator) Create(options *webauthn.CreationOptions) (*webauthn.RegistrationResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	supported := false
+	for _, param := range options.PubKeyCredParams {
+		if param.Alg == webauthn.AlgES256 {
+			supported = true
+		}
+	}
+	if !supported {
+		return nil, errors.New("webauthntest: ES256 is not offered")
+	}
+	for _, excluded := range options.ExcludeCredentials {
+		if a.find(options.RP.ID, excluded.ID) != nil {
+			return nil, errors.New("webauthntest: credential already registered")
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	cred := &credential{id: id, rpID: options.RP.ID, userHandle: bytes.Clone(options.User.ID), key: key}
+	a.credentials = append(a.credentials, cred)
+
+	clientDataJSON, err := a.clientData("webauthn.create", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	authData := a.authenticatorData(cred, true)
+	attestationObject := encode(cborMap{
+		{"fmt", "none"},
+		{"attStmt", cborMap{}},
+		{"authData", authData},
+	})
+
+	return &webauthn.RegistrationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(id),
+		RawID: id,
+		Type:  "public-key",
+		Response: webauthn.AttestationResponse{
+			ClientDataJSON:    clientDataJSON,
+			AttestationObject: attestationObject,
+			Transports:        []string{"internal"},
+		},
+	}, nil
+}
+
+// Get выполняет navigator.credentials.get. При пустом allowCredentials выбирается последний созданный passkey
+func (a *Authenticator) Get(options *webauthn.RequestOptions) (*webauthn.AssertionResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var cred *credential
+	if len(options.AllowCredentials) == 0 {
+		for i := len(a.credentials) - 1; i >= 0; i-- {
+			if a.credentials[i].rpID == options.RPID {
+				cred = a.credentials[i]
+				break
+			}
+		}
+	} else {
+		for _, allowed := range options.AllowCredentials {
+			if cred = a.find(options.RPID, allowed.ID); cred != nil {
+				break
+			}
+		}
+	}
+	if cred == nil {
+		return nil, errors.New("webauthntest: no matching credential")
+	}
+
+	cred.signCount++
+	clientDataJSON, err := a.clientData("webauthn.get", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	authData := a.authenticatorData(cred, false)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(bytes.Clone(authData), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, cred.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &webauthn.AssertionResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(cred.id),
+		RawID: bytes.Clone(cred.id),
+		Type:  "public-key",
+		Response: webauthn.AssertionData{
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authData,
+			Signature:         signature,
+			UserHandle:        bytes.Clone(cred.userHandle),
+		},
+	}, nil
+}
+
+func (a *Authenticator) find(rpID string, id []byte) *credential {
+	for _, cred := range a.credentials {
+		if cred.rpID == rpID && bytes.Equal(cred.id, id) {
+			return cred
+		}
+	}
+	return nil
+}
+
+func (a *Authenticator) clientData(ceremony string, challenge []byte) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+}
+
+func (a *Authenticator) authenticatorData(cred *credential, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(cred.rpID))
+	flags := byte(0x01)
+	if a.UserVerified {
+		flags |= 0x04
+	}
+	if a.BackupEligible {
+		flags |= 0x08 | 0x10
+	}
+	if attested {
+		flags |= 0x40
+	}
+
+	data := append([]byte(nil), rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, cred.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...) // AAGUID программного аутентификатора — нули
+		data = binary.BigEndian.AppendUint16(data, uint16(len(cred.id)))
+		data = append(data, cred.id...)
+		data = append(data, coseKey(&cred.key.PublicKey)...)
+	}
+	return data
+}
+
+func coseKey(key *ecdsa.PublicKey) []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return encode(cborMap{
+		{int64(1), int64(2)},  // kty: EC2
+		{int64(3), int64(-7)}, // alg: ES256
+		{int64(-1), int64(1)}, // crv: P-256
+		{int64(-2), x},
+		{int64(-3), y},
+	})
+}
@@ -0,0 +1,51 @@
+package webauthntest
+
+import "encoding/binary"
+
+// cborMap — словарь CBOR с заданным порядком ключей
+type cborMap []cborPair
+
+type cborPair struct {
+	key   any
+	value any
+}
+
+// encode кодирует в CBOR значения, из которых состоят ответы аутентификатора:
+// int64, string, []byte и cborMap
+func encode(v any) []byte {
+	switch value := v.(type) {
+	case int64:
+		if value < 0 {
+			return header(1, uint64(-1-value))
+		}
+		return header(0, uint64(value))
+	case string:
+		return append(header(3, uint64(len(value))), value...)
+	case []byte:
+		return append(header(2, uint64(len(value))), value...)
+	case cborMap:
+		out := header(5, uint64(len(value)))
+		for _, pair := range value {
+			out = append(out, encode(pair.key)...)
+			out = append(out, encode(pair.value)...)
+		}
+		return out
+	default:
+		panic("webauthntest: unsupported cbor value")
+	}
+}
+
+func header(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	default:
+		return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, arg)
+	}
+}
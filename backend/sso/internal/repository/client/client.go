@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	libjwt "github.com/EtoNeAnanasbI95/sso/internal/lib/jwt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type ClientRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *ClientRepository {
+	return &ClientRepository{db: db}
+}
+
+type clientRow struct {
+	Id                   string         `db:"id"`
+	Name                 string         `db:"name"`
+	SecretHash           []byte         `db:"secret_hash"`
+	Audience             pq.StringArray `db:"audience"`
+	RedirectURIs         pq.StringArray `db:"redirect_uris"`
+	AllowedOrigins       pq.StringArray `db:"allowed_origins"`
+	GrantTypes           pq.StringArray `db:"grant_types"`
+	Scopes               pq.StringArray `db:"scopes"`
+	BackchannelLogoutURI string         `db:"backchannel_logout_uri"`
+	AccessTTLSeconds     int64          `db:"access_ttl_seconds"`
+	RefreshTTLSeconds    int64          `db:"refresh_ttl_seconds"`
+	MaxSessionTTLSeconds int64          `db:"max_session_ttl_seconds"`
+}
+
+func (r clientRow) toDomain() domain.Client {
+	return domain.Client{
+		Id:                   r.Id,
+		Name:                 r.Name,
+		SecretHash:           r.SecretHash,
+		Audience:             r.Audience,
+		RedirectURIs:         r.RedirectURIs,
+		AllowedOrigins:       r.AllowedOrigins,
+		GrantTypes:           r.GrantTypes,
+		Scopes:               r.Scopes,
+		BackchannelLogoutURI: r.BackchannelLogoutURI,
+		Lifetime: libjwt.Lifetime{
+			Access:  time.Duration(r.AccessTTLSeconds) * time.Second,
+			Refresh: time.Duration(r.RefreshTTLSeconds) * time.Second,
+			Session: time.Duration(r.MaxSessionTTLSeconds) * time.Second,
+		},
+	}
+}
+
+// ListActiveClients возвращает всех не отключённых клиентов
+func (r *ClientRepository) ListActiveClients(ctx context.Context) ([]domain.Client, error) {
+	const query = `
+		SELECT id, name, secret_hash, audience, redirect_uris, allowed_origins, grant_types, scopes, backchannel_logout_uri,
+		       access_ttl_seconds, refresh_ttl_seconds, max_session_ttl_seconds
+		FROM clients
+		WHERE NOT is_disabled
+	`
+	rows := make([]clientRow, 0)
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("list clients: %w", err)
+	}
+
+	clients := make([]domain.Client, 0, len(rows))
+	for _, row := range rows {
+		clients = append(clients, row.toDomain())
+	}
+	return clients, nil
+}
+
+// GetClientSecretHash возвращает хэш секрета клиента, в том числе отключённого. nil — клиента нет или он публичный
+func (r *ClientRepository) GetClientSecretHash(ctx context.Context, clientId string) ([]byte, error) {
+	const query = `SELECT secret_hash FROM clients WHERE id = $1`
+	var hash []byte
+	if err := r.db.GetContext(ctx, &hash, query, clientId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get client secret hash: %w", err)
+	}
+	return hash, nil
+}
+
+// UpsertClient регистрирует клиента или приводит настройки уже зарегистрированного к переданным.
+// is_disabled не трогается. created — клиент добавлен, updated — изменились настройки существующего
+func (r *ClientRepository) UpsertClient(ctx context.Context, client *domain.Client) (created bool, updated bool, err error) {
+	const query = `
+		INSERT INTO clients (id, name, secret_hash, audience, redirect_uris, allowed_origins, grant_types, scopes,
+		                     backchannel_logout_uri, access_ttl_seconds, refresh_ttl_seconds, max_session_ttl_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			secret_hash = EXCLUDED.secret_hash,
+			audience = EXCLUDED.audience,
+			redirect_uris = EXCLUDED.redirect_uris,
+			allowed_origins = EXCLUDED.allowed_origins,
+			grant_types = EXCLUDED.grant_types,
+			scopes = EXCLUDED.scopes,
+			backchannel_logout_uri = EXCLUDED.backchannel_logout_uri,
+			access_ttl_seconds = EXCLUDED.access_ttl_seconds,
+			refresh_ttl_seconds = EXCLUDED.refresh_ttl_seconds,
+			max_session_ttl_seconds = EXCLUDED.max_session_ttl_seconds,
+			updated_at = now()
+		WHERE (clients.name, clients.secret_hash, clients.audience, clients.redirect_uris, clients.allowed_origins,
+		       clients.grant_types, clients.scopes, clients.backchannel_logout_uri, clients.access_ttl_seconds,
+		       clients.refresh_ttl_seconds, clients.max_session_ttl_seconds)
+		      IS DISTINCT FROM
+		      (EXCLUDED.name, EXCLUDED.secret_hash, EXCLUDED.audience, EXCLUDED.redirect_uris, EXCLUDED.allowed_origins,
+		       EXCLUDED.grant_types, EXCLUDED.scopes, EXCLUDED.backchannel_logout_uri, EXCLUDED.access_ttl_seconds,
+		       EXCLUDED.refresh_ttl_seconds, EXCLUDED.max_session_ttl_seconds)
+		RETURNING xmax = 0
+	`
+	var inserted bool
+	err = r.db.QueryRowxContext(ctx, query,
+		client.Id,
+		client.Name,
+		client.SecretHash,
+		textArray(client.Audience),
+		textArray(client.RedirectURIs),
+		textArray(client.AllowedOrigins),
+		textArray(client.GrantTypes),
+		textArray(client.Scopes),
+		client.BackchannelLogoutURI,
+		int64(client.Lifetime.Access/time.Second),
+		int64(client.Lifetime.Refresh/time.Second),
+		int64(client.Lifetime.Session/time.Second),
+	).Scan(&inserted)
+	// строка не возвращается, если клиент уже зарегистрирован с теми же настройками
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("upsert client: %w", err)
+	}
+	return inserted, !inserted, nil
+}
+
+// textArray — nil пишется как NULL, а столбцы массивов NOT NULL, поэтому пустой список передаётся как '{}'
+func textArray(values []string) pq.StringArray {
+	if values == nil {
+		return pq.StringArray{}
+	}
+	return pq.StringArray(values)
+}
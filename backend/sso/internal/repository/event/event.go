@@ -0,0 +1,44 @@
+package event
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type AuthEventRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *AuthEventRepository {
+	return &AuthEventRepository{db: db}
+}
+
+func (r *AuthEventRepository) RecordEvent(ctx context.Context, event *domain.AuthEvent) error {
+	const query = `
+		INSERT INTO auth_events (user_id, event_type, client_id, user_agent, ip_address, created_at)
+		VALUES (:user_id, :event_type, :client_id, :user_agent, :ip_address, :created_at)
+	`
+	if _, err := r.db.NamedExecContext(ctx, query, event); err != nil {
+		return fmt.Errorf("record auth event: %w", err)
+	}
+	return nil
+}
+
+// ListEvents возвращает последние limit событий пользователя, новые — первыми
+func (r *AuthEventRepository) ListEvents(ctx context.Context, userId int64, limit int) ([]domain.AuthEvent, error) {
+	const query = `
+		SELECT id, user_id, event_type, client_id, user_agent, ip_address, created_at
+		FROM auth_events
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+	events := make([]domain.AuthEvent, 0)
+	if err := r.db.SelectContext(ctx, &events, query, userId, limit); err != nil {
+		return nil, fmt.Errorf("list auth events: %w", err)
+	}
+	return events, nil
+}
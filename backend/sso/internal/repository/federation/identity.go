@@ -0,0 +1,126 @@
+package federation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/EtoNeAnanasbI95/sso/pkg/database"
+	"github.com/jmoiron/sqlx"
+)
+
+type IdentityRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+func (r *IdentityRepository) CreateUpstreamLogin(ctx context.Context, login *domain.UpstreamLogin) error {
+	const query = `
+		INSERT INTO upstream_logins (state_hash, provider, client_id, nonce, code_verifier, return_to, created_at, expires_at)
+		VALUES (:state_hash, :provider, :client_id, :nonce, :code_verifier, :return_to, :created_at, :expires_at)
+	`
+	if _, err := r.db.NamedExecContext(ctx, query, login); err != nil {
+		return fmt.Errorf("create upstream login: %w", err)
+	}
+	return nil
+}
+
+// ConsumeUpstreamLogin атомарно помечает вход завершённым и возвращает его.
+// Возвращает nil, если state неизвестен или уже был использован
+func (r *IdentityRepository) ConsumeUpstreamLogin(ctx context.Context, stateHash []byte) (*domain.UpstreamLogin, error) {
+	const query = `
+		UPDATE upstream_logins
+		SET consumed_at = now()
+		WHERE state_hash = $1 AND consumed_at IS NULL
+		RETURNING id, state_hash, provider, client_id, nonce, code_verifier, return_to, created_at, expires_at, consumed_at
+	`
+	var login domain.UpstreamLogin
+	if err := r.db.GetContext(ctx, &login, query, stateHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("consume upstream login: %w", err)
+	}
+	return &login, nil
+}
+
+func (r *IdentityRepository) DeleteExpiredUpstreamLogins(ctx context.Context) error {
+	const query = `DELETE FROM upstream_logins WHERE expires_at <= now()`
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("delete expired upstream logins: %w", err)
+	}
+	return nil
+}
+
+func (r *IdentityRepository) GetIdentity(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	const query = `
+		SELECT id, provider, subject, user_id, login, created_at, last_login_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`
+	var identity domain.UserIdentity
+	if err := r.db.GetContext(ctx, &identity, query, provider, subject); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get user identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// LinkIdentity связывает учётную запись провайдера с пользователем или, если связь уже есть,
+// обновляет логин и время входа. Связь с другим пользователем не перезаписывается
+func (r *IdentityRepository) LinkIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	const query = `
+		INSERT INTO user_identities (provider, subject, user_id, login, created_at, last_login_at)
+		VALUES (:provider, :subject, :user_id, :login, :created_at, :last_login_at)
+		ON CONFLICT (provider, subject) DO UPDATE
+		SET login = EXCLUDED.login, last_login_at = EXCLUDED.last_login_at
+		WHERE user_identities.user_id = EXCLUDED.user_id
+	`
+	if _, err := r.db.NamedExecContext(ctx, query, identity); err != nil {
+		return fmt.Errorf("link user identity: %w", err)
+	}
+	return nil
+}
+
+// CreateUserWithIdentity создаёт пользователя и его связь с провайдером в одной транзакции
+func (r *IdentityRepository) CreateUserWithIdentity(ctx context.Context, user *domain.User, identity *domain.UserIdentity) (int64, error) {
+	const insertUser = `
+		INSERT INTO users (role_id, login, telegram_username, telegram_chat_id, telegram_verified, full_name, password, creation_datetime, update_datetime, is_archived, is_deleted)
+		VALUES (:role_id, :login, :telegram_username, :telegram_chat_id, :telegram_verified, :full_name, :password, :creation_datetime, :update_datetime, :is_archived, :is_deleted)
+		RETURNING id
+	`
+	const insertIdentity = `
+		INSERT INTO user_identities (provider, subject, user_id, login, created_at, last_login_at)
+		VALUES (:provider, :subject, :user_id, :login, :created_at, :last_login_at)
+	`
+
+	id, err := database.WithUserTransaction(r.db, ctx, func(tx *sqlx.Tx) (int64, error) {
+		stmt, err := tx.PrepareNamedContext(ctx, insertUser)
+		if err != nil {
+			return 0, err
+		}
+		defer stmt.Close()
+
+		var id int64
+		if err := stmt.GetContext(ctx, &id, user); err != nil {
+			return 0, err
+		}
+
+		identity.UserId = id
+		if _, err := tx.NamedExecContext(ctx, insertIdentity, identity); err != nil {
+			return 0, err
+		}
+		return id, nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("create user with identity: %w", err)
+	}
+	return id, nil
+}
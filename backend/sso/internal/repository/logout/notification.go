@@ -0,0 +1,103 @@
+package logout
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+// notifyRevokedSessions продолжает запрос, начатый CTE revoked (id, user_id, client_id отозванных сессий):
+// ставит в очередь back-channel уведомления клиентам, у которых задан адрес, и возвращает число отозванных сессий
+const notifyRevokedSessions = `
+	, notified AS (
+		INSERT INTO logout_notifications (client_id, logout_uri, user_id, session_id)
+		SELECT r.client_id, c.backchannel_logout_uri, r.user_id, r.id
+		FROM revoked r
+		JOIN clients c ON c.id = r.client_id
+		WHERE c.backchannel_logout_uri <> ''
+	)
+	SELECT count(*) FROM revoked
+`
+
+// RevokeSessions выполняет в транзакции tx запрос revokeQuery — WITH с CTE revoked, который возвращает
+// id, user_id, client_id отозванных сессий, — ставит в очередь back-channel уведомления их клиентам и возвращает
+// число отозванных сессий. Уведомления попадают в очередь в той же транзакции, что и отзыв, поэтому не теряются
+func RevokeSessions(ctx context.Context, tx *sqlx.Tx, revokeQuery string, args ...any) (int64, error) {
+	var count int64
+	if err := tx.GetContext(ctx, &count, revokeQuery+notifyRevokedSessions, args...); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+type NotificationRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// ClaimDueNotifications забирает до limit уведомлений, которые пора отправить, и откладывает их на lease,
+// чтобы другой инстанс не отправил их параллельно. Если отправка не завершится, они вернутся в очередь после lease
+func (r *NotificationRepository) ClaimDueNotifications(ctx context.Context, limit int, lease time.Duration) ([]domain.LogoutNotification, error) {
+	const query = `
+		UPDATE logout_notifications
+		SET next_attempt_at = now() + $2 * interval '1 second',
+		    attempts        = attempts + 1
+		WHERE id IN (
+			SELECT id FROM logout_notifications
+			WHERE delivered_at IS NULL AND failed_at IS NULL AND next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, client_id, logout_uri, user_id, session_id, attempts, last_error, next_attempt_at, created_at, delivered_at, failed_at
+	`
+	notifications := make([]domain.LogoutNotification, 0)
+	if err := r.db.SelectContext(ctx, &notifications, query, limit, lease.Seconds()); err != nil {
+		return nil, fmt.Errorf("claim logout notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+func (r *NotificationRepository) MarkDelivered(ctx context.Context, id int64) error {
+	const query = `UPDATE logout_notifications SET delivered_at = now(), last_error = '' WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("mark logout notification delivered: %w", err)
+	}
+	return nil
+}
+
+// Reschedule записывает ошибку и время следующей попытки
+func (r *NotificationRepository) Reschedule(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error {
+	const query = `UPDATE logout_notifications SET last_error = $2, next_attempt_at = $3 WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, id, lastError, nextAttemptAt); err != nil {
+		return fmt.Errorf("reschedule logout notification: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed прекращает попытки доставить уведомление
+func (r *NotificationRepository) MarkFailed(ctx context.Context, id int64, lastError string) error {
+	const query = `UPDATE logout_notifications SET last_error = $2, failed_at = now() WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, id, lastError); err != nil {
+		return fmt.Errorf("mark logout notification failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteFinished удаляет доставленные и брошенные уведомления старше before
+func (r *NotificationRepository) DeleteFinished(ctx context.Context, before time.Time) error {
+	const query = `
+		DELETE FROM logout_notifications
+		WHERE (delivered_at IS NOT NULL AND delivered_at < $1) OR (failed_at IS NOT NULL AND failed_at < $1)
+	`
+	if _, err := r.db.ExecContext(ctx, query, before); err != nil {
+		return fmt.Errorf("delete finished logout notifications: %w", err)
+	}
+	return nil
+}
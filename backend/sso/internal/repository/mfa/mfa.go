@@ -0,0 +1,207 @@
+package mfa
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/EtoNeAnanasbI95/sso/pkg/database"
+	"github.com/jmoiron/sqlx"
+)
+
+type MfaRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *MfaRepository {
+	return &MfaRepository{db: db}
+}
+
+func (r *MfaRepository) GetTOTP(ctx context.Context, userId int64) (*domain.UserTOTP, error) {
+	const query = `
+		SELECT user_id, secret_encrypted, confirmed_at, last_used_step, created_at
+		FROM user_totp
+		WHERE user_id = $1
+	`
+	var totp domain.UserTOTP
+	if err := r.db.GetContext(ctx, &totp, query, userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get user totp: %w", err)
+	}
+	return &totp, nil
+}
+
+// SaveTOTP сохраняет новый неподтверждённый секрет, заменяя прежний неподтверждённый.
+// Возвращает false, если у пользователя уже есть подтверждённый фактор
+func (r *MfaRepository) SaveTOTP(ctx context.Context, totp *domain.UserTOTP) (bool, error) {
+	const query = `
+		INSERT INTO user_totp (user_id, secret_encrypted, created_at)
+		VALUES (:user_id, :secret_encrypted, :created_at)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted, created_at = EXCLUDED.created_at, last_used_step = NULL
+		WHERE user_totp.confirmed_at IS NULL
+	`
+	result, err := r.db.NamedExecContext(ctx, query, totp)
+	if err != nil {
+		return false, fmt.Errorf("save user totp: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("save user totp: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// UseTOTPStep принимает шаг TOTP, если он позже последнего принятого, и при необходимости подтверждает фактор.
+// Возвращает false для повторно предъявленного кода
+func (r *MfaRepository) UseTOTPStep(ctx context.Context, userId int64, step int64) (bool, error) {
+	const query = `
+		UPDATE user_totp
+		SET last_used_step = $2, confirmed_at = COALESCE(confirmed_at, now())
+		WHERE user_id = $1 AND (last_used_step IS NULL OR last_used_step < $2)
+	`
+	result, err := r.db.ExecContext(ctx, query, userId, step)
+	if err != nil {
+		return false, fmt.Errorf("use totp step: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("use totp step: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// DeleteTOTP отключает фактор. Коды восстановления не трогает: они нужны, пока у пользователя остаются ключи WebAuthn
+func (r *MfaRepository) DeleteTOTP(ctx context.Context, userId int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userId); err != nil {
+		return fmt.Errorf("delete user totp: %w", err)
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes заменяет все коды восстановления пользователя новым набором
+func (r *MfaRepository) ReplaceRecoveryCodes(ctx context.Context, userId int64, codes []domain.RecoveryCode) error {
+	const insert = `
+		INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at)
+		VALUES (:user_id, :code_hash, :created_at)
+	`
+	_, err := database.WithUserTransaction(r.db, ctx, func(tx *sqlx.Tx) (struct{}, error) {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userId); err != nil {
+			return struct{}{}, err
+		}
+		if len(codes) == 0 {
+			return struct{}{}, nil
+		}
+		if _, err := tx.NamedExecContext(ctx, insert, codes); err != nil {
+			return struct{}{}, err
+		}
+		return struct{}{}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("replace recovery codes: %w", err)
+	}
+	return nil
+}
+
+// ListUnusedRecoveryCodes возвращает ещё не использованные коды восстановления пользователя
+func (r *MfaRepository) ListUnusedRecoveryCodes(ctx context.Context, userId int64) ([]domain.RecoveryCode, error) {
+	const query = `
+		SELECT id, user_id, code_hash, created_at, used_at
+		FROM mfa_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL
+		ORDER BY id
+	`
+	codes := make([]domain.RecoveryCode, 0)
+	if err := r.db.SelectContext(ctx, &codes, query, userId); err != nil {
+		return nil, fmt.Errorf("list recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// UseRecoveryCode помечает код использованным. Возвращает false, если его успели использовать параллельно
+func (r *MfaRepository) UseRecoveryCode(ctx context.Context, id int64) (bool, error) {
+	const query = `UPDATE mfa_recovery_codes SET used_at = now() WHERE id = $1 AND used_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("use recovery code: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("use recovery code: %w", err)
+	}
+	return affected == 1, nil
+}
+
+func (r *MfaRepository) CreateChallenge(ctx context.Context, challenge *domain.MfaChallenge) error {
+	const query = `
+		INSERT INTO mfa_challenges (token_hash, user_id, client_id, amr, created_at, expires_at)
+		VALUES (:token_hash, :user_id, :client_id, :amr, :created_at, :expires_at)
+	`
+	if _, err := r.db.NamedExecContext(ctx, query, challenge); err != nil {
+		return fmt.Errorf("create mfa challenge: %w", err)
+	}
+	return nil
+}
+
+// GetChallenge возвращает действующий вход, не тратя попытку. Возвращает nil, если вход не найден,
+// уже завершён, истёк или попытки исчерпаны
+func (r *MfaRepository) GetChallenge(ctx context.Context, tokenHash []byte, maxAttempts int) (*domain.MfaChallenge, error) {
+	const query = `
+		SELECT id, token_hash, user_id, client_id, amr, attempts, created_at, expires_at, consumed_at
+		FROM mfa_challenges
+		WHERE token_hash = $1 AND consumed_at IS NULL AND expires_at > now() AND attempts < $2
+	`
+	var challenge domain.MfaChallenge
+	if err := r.db.GetContext(ctx, &challenge, query, tokenHash, maxAttempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get mfa challenge: %w", err)
+	}
+	return &challenge, nil
+}
+
+// AttemptChallenge засчитывает попытку ввода кода и возвращает вход. Возвращает nil, если вход не найден,
+// уже завершён, истёк или попытки исчерпаны
+func (r *MfaRepository) AttemptChallenge(ctx context.Context, tokenHash []byte, maxAttempts int) (*domain.MfaChallenge, error) {
+	const query = `
+		UPDATE mfa_challenges
+		SET attempts = attempts + 1
+		WHERE token_hash = $1 AND consumed_at IS NULL AND expires_at > now() AND attempts < $2
+		RETURNING id, token_hash, user_id, client_id, amr, attempts, created_at, expires_at, consumed_at
+	`
+	var challenge domain.MfaChallenge
+	if err := r.db.GetContext(ctx, &challenge, query, tokenHash, maxAttempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("attempt mfa challenge: %w", err)
+	}
+	return &challenge, nil
+}
+
+// ConsumeChallenge завершает вход. Возвращает false, если его успели завершить параллельно
+func (r *MfaRepository) ConsumeChallenge(ctx context.Context, id int64) (bool, error) {
+	const query = `UPDATE mfa_challenges SET consumed_at = now() WHERE id = $1 AND consumed_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("consume mfa challenge: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("consume mfa challenge: %w", err)
+	}
+	return affected == 1, nil
+}
+
+func (r *MfaRepository) DeleteExpiredChallenges(ctx context.Context) error {
+	const query = `DELETE FROM mfa_challenges WHERE expires_at <= now()`
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("delete expired mfa challenges: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package oidc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type AuthorizationCodeRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *AuthorizationCodeRepository {
+	return &AuthorizationCodeRepository{db: db}
+}
+
+func (r *AuthorizationCodeRepository) CreateAuthorizationCode(ctx context.Context, code *domain.AuthorizationCode) error {
+	const query = `
+		INSERT INTO authorization_codes (code_hash, client_id, user_id, session_id, redirect_uri, scope, nonce, code_challenge, auth_time, amr, created_at, expires_at)
+		VALUES (:code_hash, :client_id, :user_id, :session_id, :redirect_uri, :scope, :nonce, :code_challenge, :auth_time, :amr, :created_at, :expires_at)
+	`
+	if _, err := r.db.NamedExecContext(ctx, query, code); err != nil {
+		return fmt.Errorf("create authorization code: %w", err)
+	}
+	return nil
+}
+
+// ConsumeAuthorizationCode атомарно помечает код использованным и возвращает его.
+// Возвращает nil, если кода нет или он уже был использован
+func (r *AuthorizationCodeRepository) ConsumeAuthorizationCode(ctx context.Context, hash []byte) (*domain.AuthorizationCode, error) {
+	const query = `
+		UPDATE authorization_codes
+		SET consumed_at = now()
+		WHERE code_hash = $1 AND consumed_at IS NULL
+		RETURNING id, code_hash, client_id, user_id, session_id, redirect_uri, scope, nonce, code_challenge, auth_time, amr, created_at, expires_at, consumed_at
+	`
+	var code domain.AuthorizationCode
+	if err := r.db.GetContext(ctx, &code, query, hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("consume authorization code: %w", err)
+	}
+	return &code, nil
+}
+
+func (r *AuthorizationCodeRepository) DeleteExpiredAuthorizationCodes(ctx context.Context) error {
+	const query = `DELETE FROM authorization_codes WHERE expires_at <= now()`
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("delete expired authorization codes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package oidc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type DeviceAuthorizationRepository struct {
+	db *sqlx.DB
+}
+
+func NewDevices(db *sqlx.DB) *DeviceAuthorizationRepository {
+	return &DeviceAuthorizationRepository{db: db}
+}
+
+func (r *DeviceAuthorizationRepository) CreateDeviceAuthorization(ctx context.Context, device *domain.DeviceAuthorization) error {
+	const query = `
+		INSERT INTO device_authorizations (device_code_hash, user_code, client_id, scope, status, interval_seconds, created_at, expires_at)
+		VALUES (:device_code_hash, :user_code, :client_id, :scope, :status, :interval_seconds, :created_at, :expires_at)
+	`
+	if _, err := r.db.NamedExecContext(ctx, query, device); err != nil {
+		return fmt.Errorf("create device authorization: %w", err)
+	}
+	return nil
+}
+
+// GetPendingDeviceAuthorization возвращает ожидающий решения и не истёкший запрос по user_code или nil
+func (r *DeviceAuthorizationRepository) GetPendingDeviceAuthorization(ctx context.Context, userCode string) (*domain.DeviceAuthorization, error) {
+	const query = `
+		SELECT id, device_code_hash, user_code, client_id, scope, status, user_id, auth_time, amr, interval_seconds, last_polled_at, created_at, expires_at
+		FROM device_authorizations
+		WHERE user_code = $1 AND status = 'pending' AND expires_at > now()
+	`
+	var device domain.DeviceAuthorization
+	if err := r.db.GetContext(ctx, &device, query, userCode); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get device authorization: %w", err)
+	}
+	return &device, nil
+}
+
+// DecideDeviceAuthorization записывает решение пользователя по ожидающему запросу.
+// Возвращает false, если запроса нет, он истёк или решение уже принято
+func (r *DeviceAuthorizationRepository) DecideDeviceAuthorization(ctx context.Context, userCode, status string, userId int64, authTime time.Time, amr []string) (bool, error) {
+	const query = `
+		UPDATE device_authorizations
+		SET status = $2, user_id = $3, auth_time = $4, amr = $5
+		WHERE user_code = $1 AND status = 'pending' AND expires_at > now()
+	`
+	result, err := r.db.ExecContext(ctx, query, userCode, status, userId, authTime, domain.JoinAmr(amr))
+	if err != nil {
+		return false, fmt.Errorf("decide device authorization: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("decide device authorization: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// PollDeviceAuthorization отмечает опрос клиента и возвращает запрос с временем предыдущего опроса или nil
+func (r *DeviceAuthorizationRepository) PollDeviceAuthorization(ctx context.Context, hash []byte) (*domain.DeviceAuthorization, error) {
+	const query = `
+		UPDATE device_authorizations d
+		SET last_polled_at = now()
+		FROM (
+			SELECT id, last_polled_at FROM device_authorizations WHERE device_code_hash = $1 FOR UPDATE
+		) prev
+		WHERE d.id = prev.id
+		RETURNING d.id, d.device_code_hash, d.user_code, d.client_id, d.scope, d.status, d.user_id, d.auth_time, d.amr,
+		          d.interval_seconds, prev.last_polled_at AS last_polled_at, d.created_at, d.expires_at
+	`
+	var device domain.DeviceAuthorization
+	if err := r.db.GetContext(ctx, &device, query, hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("poll device authorization: %w", err)
+	}
+	return &device, nil
+}
+
+// ConsumeDeviceAuthorization атомарно помечает одобренный запрос использованным.
+// Возвращает false, если токены по нему уже выданы
+func (r *DeviceAuthorizationRepository) ConsumeDeviceAuthorization(ctx context.Context, id int64) (bool, error) {
+	const query = `
+		UPDATE device_authorizations
+		SET status = 'consumed'
+		WHERE id = $1 AND status = 'approved'
+	`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("consume device authorization: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("consume device authorization: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func (r *DeviceAuthorizationRepository) DeleteExpiredDeviceAuthorizations(ctx context.Context) error {
+	const query = `DELETE FROM device_authorizations WHERE expires_at <= now()`
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("delete expired device authorizations: %w", err)
+	}
+	return nil
+}
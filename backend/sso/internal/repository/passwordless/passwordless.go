@@ -0,0 +1,129 @@
+package passwordless
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type LoginCodeRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *LoginCodeRepository {
+	return &LoginCodeRepository{db: db}
+}
+
+func (r *LoginCodeRepository) CreateLoginCode(ctx context.Context, code *domain.LoginCode) error {
+	const query = `
+		INSERT INTO login_codes (token_hash, code_hash, user_id, client_id, created_at, expires_at)
+		VALUES (:token_hash, :code_hash, :user_id, :client_id, :created_at, :expires_at)
+	`
+	if _, err := r.db.NamedExecContext(ctx, query, code); err != nil {
+		return fmt.Errorf("create login code: %w", err)
+	}
+	return nil
+}
+
+// HasRecentLoginCode сообщает, что пользователю уже отправляли код после since
+func (r *LoginCodeRepository) HasRecentLoginCode(ctx context.Context, userId int64, since time.Time) (bool, error) {
+	const query = `SELECT EXISTS (SELECT 1 FROM login_codes WHERE user_id = $1 AND created_at > $2)`
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, query, userId, since); err != nil {
+		return false, fmt.Errorf("check recent login code: %w", err)
+	}
+	return exists, nil
+}
+
+// TouchLoginCodeRequest отмечает запрос кода для логина с хэшем loginHash. Возвращает false и ничего не меняет,
+// если предыдущий запрос для этого логина был после since. Проверка и отметка — один запрос, поэтому
+// параллельные запросы с разных инстансов не проходят оба
+func (r *LoginCodeRepository) TouchLoginCodeRequest(ctx context.Context, loginHash []byte, since time.Time) (bool, error) {
+	const query = `
+		INSERT INTO login_code_requests (login_hash, requested_at)
+		VALUES ($1, now())
+		ON CONFLICT (login_hash) DO UPDATE SET requested_at = now()
+		WHERE login_code_requests.requested_at <= $2
+		RETURNING TRUE
+	`
+	var touched bool
+	if err := r.db.GetContext(ctx, &touched, query, loginHash, since); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("touch login code request: %w", err)
+	}
+	return touched, nil
+}
+
+// AttemptLoginCode засчитывает попытку ввода кода и возвращает запрос входа. Возвращает nil, если запрос
+// не найден, уже использован, истёк или попытки исчерпаны
+func (r *LoginCodeRepository) AttemptLoginCode(ctx context.Context, tokenHash []byte, maxAttempts int) (*domain.LoginCode, error) {
+	const query = `
+		UPDATE login_codes
+		SET attempts = attempts + 1
+		WHERE token_hash = $1 AND consumed_at IS NULL AND expires_at > now() AND attempts < $2
+		RETURNING id, token_hash, code_hash, user_id, client_id, attempts, created_at, expires_at, consumed_at
+	`
+	var code domain.LoginCode
+	if err := r.db.GetContext(ctx, &code, query, tokenHash, maxAttempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("attempt login code: %w", err)
+	}
+	return &code, nil
+}
+
+// ConsumeLoginCode помечает код использованным вместе с остальными неиспользованными кодами пользователя.
+// Возвращает false, если код успели использовать параллельно
+func (r *LoginCodeRepository) ConsumeLoginCode(ctx context.Context, code *domain.LoginCode) (bool, error) {
+	const query = `
+		UPDATE login_codes
+		SET consumed_at = now()
+		WHERE user_id = $1 AND consumed_at IS NULL
+		RETURNING id
+	`
+	rows, err := r.db.QueryContext(ctx, query, code.UserId)
+	if err != nil {
+		return false, fmt.Errorf("consume login code: %w", err)
+	}
+	defer rows.Close()
+
+	consumed := false
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return false, fmt.Errorf("consume login code: %w", err)
+		}
+		if id == code.Id {
+			consumed = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("consume login code: %w", err)
+	}
+	return consumed, nil
+}
+
+func (r *LoginCodeRepository) DeleteExpiredLoginCodes(ctx context.Context) error {
+	const query = `DELETE FROM login_codes WHERE expires_at <= now()`
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("delete expired login codes: %w", err)
+	}
+	return nil
+}
+
+// DeleteLoginCodeRequests удаляет отметки запросов, сделанных до before: они уже не ограничивают повторный запрос
+func (r *LoginCodeRepository) DeleteLoginCodeRequests(ctx context.Context, before time.Time) error {
+	const query = `DELETE FROM login_code_requests WHERE requested_at <= $1`
+	if _, err := r.db.ExecContext(ctx, query, before); err != nil {
+		return fmt.Errorf("delete login code requests: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type RevocationRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *RevocationRepository {
+	return &RevocationRepository{db: db}
+}
+
+func (r *RevocationRepository) RevokeToken(ctx context.Context, token *domain.RevokedToken) error {
+	const query = `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at, revoked_at, revoked_by, reason)
+		VALUES (:jti, :user_id, :expires_at, :revoked_at, :revoked_by, :reason)
+		ON CONFLICT (jti) DO NOTHING
+	`
+	if _, err := r.db.NamedExecContext(ctx, query, token); err != nil {
+		return fmt.Errorf("revoke token: %w", err)
+	}
+	return nil
+}
+
+func (r *RevocationRepository) CreateCutoff(ctx context.Context, cutoff *domain.RevocationCutoff) error {
+	const query = `
+		INSERT INTO token_revocation_cutoffs (user_id, revoked_before, created_at, created_by, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+	err := r.db.GetContext(ctx, &cutoff.Id, query, cutoff.UserId, cutoff.RevokedBefore, cutoff.CreatedAt, cutoff.CreatedBy, cutoff.Reason)
+	if err != nil {
+		return fmt.Errorf("create revocation cutoff: %w", err)
+	}
+	return nil
+}
+
+// ListActiveTokens возвращает отзывы токенов, которые ещё не истекли сами по себе
+func (r *RevocationRepository) ListActiveTokens(ctx context.Context) ([]domain.RevokedToken, error) {
+	const query = `
+		SELECT jti, user_id, expires_at, revoked_at, revoked_by, reason
+		FROM revoked_tokens
+		WHERE expires_at > now()
+	`
+	tokens := make([]domain.RevokedToken, 0)
+	if err := r.db.SelectContext(ctx, &tokens, query); err != nil {
+		return nil, fmt.Errorf("list revoked tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// ListCutoffs возвращает самое позднее отсечение на каждого пользователя и глобальное (user_id IS NULL).
+// Отсечения старше maxTokenLifetime уже не влияют ни на один живой токен.
+func (r *RevocationRepository) ListCutoffs(ctx context.Context, maxTokenLifetime time.Duration) ([]domain.RevocationCutoff, error) {
+	const query = `
+		SELECT DISTINCT ON (user_id) id, user_id, revoked_before, created_at, created_by, reason
+		FROM token_revocation_cutoffs
+		WHERE revoked_before > $1
+		ORDER BY user_id, revoked_before DESC
+	`
+	cutoffs := make([]domain.RevocationCutoff, 0)
+	if err := r.db.SelectContext(ctx, &cutoffs, query, time.Now().Add(-maxTokenLifetime)); err != nil {
+		return nil, fmt.Errorf("list revocation cutoffs: %w", err)
+	}
+	return cutoffs, nil
+}
+
+// ListTokenVersions возвращает версии токенов пользователей, которых хотя бы раз разлогинивали принудительно
+func (r *RevocationRepository) ListTokenVersions(ctx context.Context) ([]domain.UserTokenVersion, error) {
+	const query = `
+		SELECT id, token_version
+		FROM users
+		WHERE token_version > 0
+	`
+	versions := make([]domain.UserTokenVersion, 0)
+	if err := r.db.SelectContext(ctx, &versions, query); err != nil {
+		return nil, fmt.Errorf("list token versions: %w", err)
+	}
+	return versions, nil
+}
+
+func (r *RevocationRepository) DeleteExpiredTokens(ctx context.Context) error {
+	const query = `DELETE FROM revoked_tokens WHERE expires_at <= now()`
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("delete expired revoked tokens: %w", err)
+	}
+	return nil
+}
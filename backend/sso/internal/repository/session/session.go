@@ -0,0 +1,204 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/EtoNeAnanasbI95/sso/internal/repository/logout"
+	"github.com/EtoNeAnanasbI95/sso/pkg/database"
+	"github.com/jmoiron/sqlx"
+)
+
+type SessionRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// SaveSession создаёт сессию при входе и обновляет устройство, адрес и срок действия при обновлении токенов
+func (r *SessionRepository) SaveSession(ctx context.Context, session *domain.Session) error {
+	const query = `
+		INSERT INTO sessions (id, user_id, client_id, sso_session_id, device, user_agent, ip_address, created_at, last_refreshed_at, expires_at)
+		VALUES (:id, :user_id, :client_id, :sso_session_id, :device, :user_agent, :ip_address, :created_at, :last_refreshed_at, :expires_at)
+		ON CONFLICT (id) DO UPDATE
+		SET device            = EXCLUDED.device,
+		    user_agent        = EXCLUDED.user_agent,
+		    ip_address        = EXCLUDED.ip_address,
+		    last_refreshed_at = EXCLUDED.last_refreshed_at,
+		    expires_at        = EXCLUDED.expires_at
+	`
+	if _, err := r.db.NamedExecContext(ctx, query, session); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	return nil
+}
+
+// ListActiveSessions возвращает неотозванные и неистёкшие сессии пользователя, последние обновлённые — первыми
+func (r *SessionRepository) ListActiveSessions(ctx context.Context, userId int64) ([]domain.Session, error) {
+	const query = `
+		SELECT id, user_id, client_id, sso_session_id, device, user_agent, ip_address, created_at, last_refreshed_at, expires_at, revoked_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY last_refreshed_at DESC
+	`
+	sessions := make([]domain.Session, 0)
+	if err := r.db.SelectContext(ctx, &sessions, query, userId); err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession отзывает сессию пользователя вместе с цепочкой её refresh токенов.
+// Возвращает false, если активной сессии с таким id у пользователя нет
+func (r *SessionRepository) RevokeSession(ctx context.Context, userId int64, sessionId string) (bool, error) {
+	const revokeSessionQuery = `
+		WITH revoked AS (
+			UPDATE sessions
+			SET revoked_at = now()
+			WHERE user_id = $1 AND id = $2 AND revoked_at IS NULL
+			RETURNING id, user_id, client_id
+		)
+	`
+	const revokeTokensQuery = `
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL
+	`
+
+	revoked, err := database.WithUserTransaction(r.db, ctx, func(tx *sqlx.Tx) (bool, error) {
+		count, err := logout.RevokeSessions(ctx, tx, revokeSessionQuery, userId, sessionId)
+		if err != nil {
+			return false, err
+		}
+		if count == 0 {
+			return false, nil
+		}
+		if _, err := tx.ExecContext(ctx, revokeTokensQuery, userId, sessionId); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("revoke session: %w", err)
+	}
+	return revoked, nil
+}
+
+// RevokeSingleSignOn завершает сессию SSO, к которой относится сессия sessionId: саму сессию SSO в браузере
+// и все сессии клиентов, полученные из неё через /authorize, вместе с их refresh токенами
+func (r *SessionRepository) RevokeSingleSignOn(ctx context.Context, sessionId string) error {
+	const revokeSessionsQuery = `
+		WITH root AS (
+			SELECT COALESCE(sso_session_id, id) AS id FROM sessions WHERE id = $1
+		), revoked AS (
+			UPDATE sessions s
+			SET revoked_at = now()
+			FROM root
+			WHERE (s.id = root.id OR s.sso_session_id = root.id) AND s.revoked_at IS NULL
+			RETURNING s.id, s.user_id, s.client_id
+		), revoked_families AS (
+			UPDATE refresh_tokens t
+			SET revoked_at = now()
+			FROM revoked
+			WHERE t.family_id = revoked.id AND t.revoked_at IS NULL
+		)
+	`
+	// цепочки, выданные до появления таблицы sessions, отзываются по family_id напрямую
+	const revokeTokensQuery = `
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+
+	_, err := database.WithUserTransaction(r.db, ctx, func(tx *sqlx.Tx) (struct{}, error) {
+		if _, err := logout.RevokeSessions(ctx, tx, revokeSessionsQuery, sessionId); err != nil {
+			return struct{}{}, err
+		}
+		_, err := tx.ExecContext(ctx, revokeTokensQuery, sessionId)
+		return struct{}{}, err
+	})
+	if err != nil {
+		return fmt.Errorf("revoke single sign-on session: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllSessions отзывает все сессии и все refresh токены пользователя,
+// в том числе цепочки, выданные до появления таблицы sessions
+func (r *SessionRepository) RevokeAllSessions(ctx context.Context, userId int64) error {
+	const revokeSessionsQuery = `
+		WITH revoked AS (
+			UPDATE sessions
+			SET revoked_at = now()
+			WHERE user_id = $1 AND revoked_at IS NULL
+			RETURNING id, user_id, client_id
+		)
+	`
+	const revokeTokensQuery = `
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	_, err := database.WithUserTransaction(r.db, ctx, func(tx *sqlx.Tx) (struct{}, error) {
+		if _, err := logout.RevokeSessions(ctx, tx, revokeSessionsQuery, userId); err != nil {
+			return struct{}{}, err
+		}
+		_, err := tx.ExecContext(ctx, revokeTokensQuery, userId)
+		return struct{}{}, err
+	})
+	if err != nil {
+		return fmt.Errorf("revoke all sessions: %w", err)
+	}
+	return nil
+}
+
+// ForceLogout отзывает все сессии и refresh токены пользователя и увеличивает версию его токенов,
+// чтобы перестали приниматься и уже выданные access токены. Возвращает новую версию и false, если пользователя нет
+func (r *SessionRepository) ForceLogout(ctx context.Context, userId int64) (int64, bool, error) {
+	const bumpVersionQuery = `
+		UPDATE users
+		SET token_version = token_version + 1
+		WHERE id = $1
+		RETURNING token_version
+	`
+	const revokeSessionsQuery = `
+		WITH revoked AS (
+			UPDATE sessions
+			SET revoked_at = now()
+			WHERE user_id = $1 AND revoked_at IS NULL
+			RETURNING id, user_id, client_id
+		)
+	`
+	const revokeTokensQuery = `
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	version, err := database.WithUserTransaction(r.db, ctx, func(tx *sqlx.Tx) (int64, error) {
+		var version int64
+		if err := tx.GetContext(ctx, &version, bumpVersionQuery, userId); err != nil {
+			return 0, err
+		}
+		if _, err := logout.RevokeSessions(ctx, tx, revokeSessionsQuery, userId); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, revokeTokensQuery, userId); err != nil {
+			return 0, err
+		}
+		return version, nil
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("force logout: %w", err)
+	}
+	return version, true, nil
+}
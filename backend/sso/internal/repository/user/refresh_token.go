@@ -0,0 +1,128 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/EtoNeAnanasbI95/sso/internal/repository/logout"
+	"github.com/EtoNeAnanasbI95/sso/pkg/database"
+	"github.com/jmoiron/sqlx"
+)
+
+const insertRefreshTokenQuery = `
+	INSERT INTO refresh_tokens (family_id, user_id, token_hash, parent_id, auth_time, amr, issued_at, expires_at)
+	VALUES (:family_id, :user_id, :token_hash, :parent_id, :auth_time, :amr, :issued_at, :expires_at)
+	RETURNING id
+`
+
+func (u *UserRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	id, err := database.WithUserTransaction(u.db, ctx, func(tx *sqlx.Tx) (int64, error) {
+		return insertRefreshToken(tx, token)
+	})
+	if err != nil {
+		return fmt.Errorf("create refresh token: %w", err)
+	}
+	token.Id = id
+	return nil
+}
+
+func (u *UserRepository) GetRefreshTokenByHash(ctx context.Context, hash []byte) (*domain.RefreshToken, error) {
+	const query = `
+		SELECT id, family_id, user_id, token_hash, parent_id, auth_time, amr, issued_at, expires_at, rotated_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+	var token domain.RefreshToken
+	if err := u.db.GetContext(ctx, &token, query, hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get refresh token: %w", err)
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken помечает текущий токен ротированным и сохраняет следующий токен цепочки.
+// Возвращает false, если текущий токен уже был ротирован или отозван (например, параллельным запросом).
+func (u *UserRepository) RotateRefreshToken(ctx context.Context, current *domain.RefreshToken, next *domain.RefreshToken) (bool, error) {
+	const markRotatedQuery = `
+		UPDATE refresh_tokens
+		SET rotated_at = now()
+		WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+	`
+
+	rotated, err := database.WithUserTransaction(u.db, ctx, func(tx *sqlx.Tx) (bool, error) {
+		result, err := tx.ExecContext(ctx, markRotatedQuery, current.Id)
+		if err != nil {
+			return false, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return false, err
+		}
+		if affected == 0 {
+			return false, nil
+		}
+
+		id, err := insertRefreshToken(tx, next)
+		if err != nil {
+			return false, err
+		}
+		next.Id = id
+		return true, nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("rotate refresh token: %w", err)
+	}
+	return rotated, nil
+}
+
+// RevokeRefreshTokenFamily отзывает цепочку refresh токенов и сессию, которой она принадлежит,
+// и ставит в очередь back-channel уведомление клиенту
+func (u *UserRepository) RevokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	const revokeTokensQuery = `
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+	const revokeSessionQuery = `
+		WITH revoked AS (
+			UPDATE sessions
+			SET revoked_at = now()
+			WHERE id = $1 AND revoked_at IS NULL
+			RETURNING id, user_id, client_id
+		)
+	`
+
+	_, err := database.WithUserTransaction(u.db, ctx, func(tx *sqlx.Tx) (struct{}, error) {
+		if _, err := tx.ExecContext(ctx, revokeTokensQuery, familyId); err != nil {
+			return struct{}{}, err
+		}
+		_, err := logout.RevokeSessions(ctx, tx, revokeSessionQuery, familyId)
+		return struct{}{}, err
+	})
+	if err != nil {
+		return fmt.Errorf("revoke refresh token family: %w", err)
+	}
+	return nil
+}
+
+func insertRefreshToken(tx *sqlx.Tx, token *domain.RefreshToken) (int64, error) {
+	rows, err := tx.NamedQuery(insertRefreshTokenQuery, token)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var id int64
+	if !rows.Next() {
+		return 0, fmt.Errorf("no id returned")
+	}
+	if err := rows.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
@@ -21,6 +21,9 @@ func New(db *sqlx.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// errTelegramTaken откатывает транзакцию привязки, если у пользователя уже подтверждён другой Telegram
+var errTelegramTaken = errors.New("telegram account already linked")
+
 const baseSelectQuery = `
 	SELECT u.*, r.name AS role_name
 	FROM users u
@@ -149,3 +152,78 @@ func (u *UserRepository) UpdateUserPassword(ctx context.Context, userId int64, p
 	}
 	return nil
 }
+
+// RehashUserPassword заменяет хеш того же пароля, если он не изменился с момента проверки:
+// смена пароля в это время не перезаписывается. update_datetime не трогается
+func (u *UserRepository) RehashUserPassword(ctx context.Context, userId int64, current, rehashed []byte) (bool, error) {
+	const query = `
+		UPDATE users
+		SET password = $3
+		WHERE id = $1 AND password = $2
+	`
+	result, err := u.db.ExecContext(ctx, query, userId, current, rehashed)
+	if err != nil {
+		return false, fmt.Errorf("rehash user password: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rehash user password: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// GetUserByTelegramChatId ищет пользователя по подтверждённому Telegram: chat id, указанный при регистрации
+// без подтверждения, не учитывается
+func (u *UserRepository) GetUserByTelegramChatId(ctx context.Context, chatId int64) (*domain.User, error) {
+	query := baseSelectQuery + " WHERE u.telegram_chat_id = $1 AND u.telegram_verified = TRUE AND u.is_deleted = FALSE AND u.is_archived = FALSE"
+	var user domain.User
+	if err := u.db.GetContext(ctx, &user, query, chatId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get user by telegram chat id: %w", err)
+	}
+	return &user, nil
+}
+
+// LinkTelegramAccount привязывает к пользователю Telegram, подтверждённый подписью Telegram.
+// Неподтверждённые chat id, указанные при регистрации, снимаются в той же транзакции — и свой, и чужие с этим же id.
+// Возвращает false, если у пользователя уже подтверждён другой Telegram или этот Telegram подтверждён у другого,
+// в том числе архивного, пользователя
+func (u *UserRepository) LinkTelegramAccount(ctx context.Context, userId int64, chatId int64) (bool, error) {
+	const releaseQuery = `
+		UPDATE users
+		SET telegram_chat_id = NULL, update_datetime = now()
+		WHERE telegram_chat_id = $2 AND id <> $1 AND NOT telegram_verified
+	`
+	const linkQuery = `
+		UPDATE users
+		SET telegram_chat_id = $2, telegram_verified = TRUE, update_datetime = now()
+		WHERE id = $1 AND (telegram_chat_id IS NULL OR telegram_chat_id = $2 OR NOT telegram_verified)
+	`
+	_, err := database.WithUserTransaction(u.db, ctx, func(tx *sqlx.Tx) (struct{}, error) {
+		if _, err := tx.ExecContext(ctx, releaseQuery, userId, chatId); err != nil {
+			return struct{}{}, err
+		}
+		result, err := tx.ExecContext(ctx, linkQuery, userId, chatId)
+		if err != nil {
+			return struct{}{}, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return struct{}{}, err
+		}
+		// откатываем и снятие чужих заявок: привязки не будет
+		if affected != 1 {
+			return struct{}{}, errTelegramTaken
+		}
+		return struct{}{}, nil
+	})
+	if errors.Is(err, errTelegramTaken) || database.IsUniqueViolation(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("link telegram account: %w", err)
+	}
+	return true, nil
+}
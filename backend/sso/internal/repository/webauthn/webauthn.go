@@ -0,0 +1,166 @@
+package webauthn
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type WebAuthnRepository struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *WebAuthnRepository {
+	return &WebAuthnRepository{db: db}
+}
+
+type credentialRow struct {
+	Id             int64          `db:"id"`
+	UserId         int64          `db:"user_id"`
+	CredentialId   []byte         `db:"credential_id"`
+	PublicKey      []byte         `db:"public_key"`
+	SignCount      int64          `db:"sign_count"`
+	Transports     pq.StringArray `db:"transports"`
+	Name           string         `db:"name"`
+	BackupEligible bool           `db:"backup_eligible"`
+	CreatedAt      time.Time      `db:"created_at"`
+	LastUsedAt     *time.Time     `db:"last_used_at"`
+}
+
+func (r credentialRow) toDomain() domain.WebAuthnCredential {
+	return domain.WebAuthnCredential{
+		Id:             r.Id,
+		UserId:         r.UserId,
+		CredentialId:   r.CredentialId,
+		PublicKey:      r.PublicKey,
+		SignCount:      r.SignCount,
+		Transports:     r.Transports,
+		Name:           r.Name,
+		BackupEligible: r.BackupEligible,
+		CreatedAt:      r.CreatedAt,
+		LastUsedAt:     r.LastUsedAt,
+	}
+}
+
+const credentialColumns = `id, user_id, credential_id, public_key, sign_count, transports, name, backup_eligible, created_at, last_used_at`
+
+// CreateCredential сохраняет ключ. Возвращает false, если ключ с таким идентификатором уже зарегистрирован
+func (r *WebAuthnRepository) CreateCredential(ctx context.Context, credential *domain.WebAuthnCredential) (bool, error) {
+	const query = `
+		INSERT INTO webauthn_credentials (user_id, credential_id, public_key, sign_count, transports, name, backup_eligible, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (credential_id) DO NOTHING
+		RETURNING id
+	`
+	err := r.db.GetContext(ctx, &credential.Id, query,
+		credential.UserId, credential.CredentialId, credential.PublicKey, credential.SignCount,
+		pq.StringArray(credential.Transports), credential.Name, credential.BackupEligible, credential.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("create webauthn credential: %w", err)
+	}
+	return true, nil
+}
+
+func (r *WebAuthnRepository) GetCredential(ctx context.Context, credentialId []byte) (*domain.WebAuthnCredential, error) {
+	query := `SELECT ` + credentialColumns + ` FROM webauthn_credentials WHERE credential_id = $1`
+	var row credentialRow
+	if err := r.db.GetContext(ctx, &row, query, credentialId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get webauthn credential: %w", err)
+	}
+	credential := row.toDomain()
+	return &credential, nil
+}
+
+func (r *WebAuthnRepository) ListCredentials(ctx context.Context, userId int64) ([]domain.WebAuthnCredential, error) {
+	query := `SELECT ` + credentialColumns + ` FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at`
+	rows := make([]credentialRow, 0)
+	if err := r.db.SelectContext(ctx, &rows, query, userId); err != nil {
+		return nil, fmt.Errorf("list webauthn credentials: %w", err)
+	}
+	credentials := make([]domain.WebAuthnCredential, 0, len(rows))
+	for _, row := range rows {
+		credentials = append(credentials, row.toDomain())
+	}
+	return credentials, nil
+}
+
+// UseCredential запоминает новый счётчик подписей и время входа. Счётчик сдвигается только вперёд:
+// из двух параллельных входов с одним значением пройдёт один
+func (r *WebAuthnRepository) UseCredential(ctx context.Context, id int64, previousCount, signCount int64) (bool, error) {
+	const query = `
+		UPDATE webauthn_credentials
+		SET sign_count = $3, last_used_at = now()
+		WHERE id = $1 AND sign_count = $2
+	`
+	result, err := r.db.ExecContext(ctx, query, id, previousCount, signCount)
+	if err != nil {
+		return false, fmt.Errorf("use webauthn credential: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("use webauthn credential: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// DeleteCredential удаляет ключ пользователя. Возвращает false, если ключа нет или он чужой
+func (r *WebAuthnRepository) DeleteCredential(ctx context.Context, userId, id int64) (bool, error) {
+	const query = `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`
+	result, err := r.db.ExecContext(ctx, query, id, userId)
+	if err != nil {
+		return false, fmt.Errorf("delete webauthn credential: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete webauthn credential: %w", err)
+	}
+	return affected == 1, nil
+}
+
+func (r *WebAuthnRepository) CreateCeremony(ctx context.Context, ceremony *domain.WebAuthnCeremony) error {
+	const query = `
+		INSERT INTO webauthn_ceremonies (challenge_hash, kind, user_id, client_id, created_at, expires_at)
+		VALUES (:challenge_hash, :kind, :user_id, :client_id, :created_at, :expires_at)
+	`
+	if _, err := r.db.NamedExecContext(ctx, query, ceremony); err != nil {
+		return fmt.Errorf("create webauthn ceremony: %w", err)
+	}
+	return nil
+}
+
+// ConsumeCeremony удаляет и возвращает неистёкшую церемонию вида kind. Возвращает nil, если её нет
+func (r *WebAuthnRepository) ConsumeCeremony(ctx context.Context, challengeHash []byte, kind string) (*domain.WebAuthnCeremony, error) {
+	const query = `
+		DELETE FROM webauthn_ceremonies
+		WHERE challenge_hash = $1 AND kind = $2 AND expires_at > now()
+		RETURNING id, challenge_hash, kind, user_id, client_id, created_at, expires_at
+	`
+	var ceremony domain.WebAuthnCeremony
+	if err := r.db.GetContext(ctx, &ceremony, query, challengeHash, kind); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("consume webauthn ceremony: %w", err)
+	}
+	return &ceremony, nil
+}
+
+func (r *WebAuthnRepository) DeleteExpiredCeremonies(ctx context.Context) error {
+	const query = `DELETE FROM webauthn_ceremonies WHERE expires_at <= now()`
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("delete expired webauthn ceremonies: %w", err)
+	}
+	return nil
+}
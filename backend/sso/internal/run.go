@@ -2,22 +2,56 @@ package internal
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/jmoiron/sqlx"
 	"github.com/EtoNeAnanasbI95/sso/internal/application"
 	"github.com/EtoNeAnanasbI95/sso/internal/config"
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
 	"github.com/EtoNeAnanasbI95/sso/internal/lib/jwt"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/notify"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/passhash"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/passwordpolicy"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/secretbox"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/telegram"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/upstream"
+	libwebauthn "github.com/EtoNeAnanasbI95/sso/internal/lib/webauthn"
+	"github.com/EtoNeAnanasbI95/sso/internal/repository/client"
+	eventRepository "github.com/EtoNeAnanasbI95/sso/internal/repository/event"
+	federationRepository "github.com/EtoNeAnanasbI95/sso/internal/repository/federation"
+	logoutRepository "github.com/EtoNeAnanasbI95/sso/internal/repository/logout"
+	mfaRepository "github.com/EtoNeAnanasbI95/sso/internal/repository/mfa"
+	oidcRepository "github.com/EtoNeAnanasbI95/sso/internal/repository/oidc"
+	passwordlessRepository "github.com/EtoNeAnanasbI95/sso/internal/repository/passwordless"
+	"github.com/EtoNeAnanasbI95/sso/internal/repository/revocation"
+	"github.com/EtoNeAnanasbI95/sso/internal/repository/session"
 	"github.com/EtoNeAnanasbI95/sso/internal/repository/user"
+	webauthnRepository "github.com/EtoNeAnanasbI95/sso/internal/repository/webauthn"
 	"github.com/EtoNeAnanasbI95/sso/internal/services/auth"
+	clientService "github.com/EtoNeAnanasbI95/sso/internal/services/client"
+	eventService "github.com/EtoNeAnanasbI95/sso/internal/services/event"
+	federationService "github.com/EtoNeAnanasbI95/sso/internal/services/federation"
+	logoutService "github.com/EtoNeAnanasbI95/sso/internal/services/logout"
+	mfaService "github.com/EtoNeAnanasbI95/sso/internal/services/mfa"
+	"github.com/EtoNeAnanasbI95/sso/internal/services/oauth"
+	"github.com/EtoNeAnanasbI95/sso/internal/services/oidc"
+	passwordlessService "github.com/EtoNeAnanasbI95/sso/internal/services/passwordless"
+	revocationService "github.com/EtoNeAnanasbI95/sso/internal/services/revocation"
+	sessionService "github.com/EtoNeAnanasbI95/sso/internal/services/session"
+	webauthnService "github.com/EtoNeAnanasbI95/sso/internal/services/webauthn"
 	"github.com/EtoNeAnanasbI95/sso/pkg/database"
+	"github.com/EtoNeAnanasbI95/sso/pkg/echomiddleware"
 	"github.com/EtoNeAnanasbI95/sso/pkg/logger"
+	"github.com/jmoiron/sqlx"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -33,7 +67,9 @@ func Run(cfg *config.Config) error {
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
 	defer stop()
 
-	startServers(ctx, g, db, cfg)
+	if err := startServers(ctx, g, db, cfg); err != nil {
+		return err
+	}
 	startPprofServer(ctx, g)
 
 	if err := g.Wait(); err != nil && errors.Is(err, context.Canceled) {
@@ -43,12 +79,155 @@ func Run(cfg *config.Config) error {
 	return nil
 }
 
-func startServers(ctx context.Context, g *errgroup.Group, db *sqlx.DB, cfg *config.Config) {
+func startServers(ctx context.Context, g *errgroup.Group, db *sqlx.DB, cfg *config.Config) error {
+	keySet, err := setupKeySet(cfg)
+	if err != nil {
+		return err
+	}
+	startKeyRotation(ctx, g, keySet, cfg.JWT.Rotation.Interval)
+
 	usersRepository := user.New(db)
-	jwtLib := jwt.NewJwtLib(time.Minute*60, []byte(cfg.Secret))
-	authService := auth.New(usersRepository, jwtLib)
+	clients, err := setupClientRegistry(ctx, db, cfg)
+	if err != nil {
+		return err
+	}
+	g.Go(func() error {
+		return clients.Run(ctx, cfg.ClientRegistry.ReloadInterval)
+	})
+	if cfg.JWT.DefaultClient == "" {
+		return errors.New("jwt.default_client is required")
+	}
+	if defaultClient, _ := clients.GetClient(ctx, cfg.JWT.DefaultClient); defaultClient == nil {
+		return fmt.Errorf("jwt.default_client %q is not registered", cfg.JWT.DefaultClient)
+	}
+	if cfg.Telegram.MiniAppClient != "" {
+		if miniAppClient, _ := clients.GetClient(ctx, cfg.Telegram.MiniAppClient); miniAppClient == nil {
+			return fmt.Errorf("telegram.mini_app_client %q is not registered", cfg.Telegram.MiniAppClient)
+		}
+	}
+	if cfg.JWT.AdminAudience != "" && !clients.IsKnownAudience(cfg.JWT.AdminAudience) {
+		return fmt.Errorf("jwt.admin_audience %q is not an audience of any registered client", cfg.JWT.AdminAudience)
+	}
+	jwtLib := jwt.NewJwtLib(keySet, jwt.Options{
+		Issuer:    cfg.JWT.Issuer,
+		Audiences: clients,
+		Leeway:    cfg.JWT.Leeway,
+		Lifetimes: lifetimePolicy(cfg),
+	})
+
+	revocations := revocationService.New(revocation.New(db), jwtLib.MaxLifetime())
+	if err := revocations.Reload(ctx); err != nil {
+		return fmt.Errorf("failed to load revoked tokens: %w", err)
+	}
+	g.Go(func() error {
+		return revocations.Run(ctx, cfg.Revocation.ReloadInterval)
+	})
+
+	sessionsRepository := session.New(db)
+	telegramVerifier := telegram.NewVerifier(cfg.Telegram.BotToken, cfg.Telegram.AuthMaxAge, cfg.Telegram.InitDataMaxAge)
+	mfaBox, err := setupMFABox(cfg)
+	if err != nil {
+		return err
+	}
+	events := eventService.New(eventRepository.New(db))
+	passkeys := webauthnService.New(webauthnRepository.New(db), usersRepository, events, setupRelyingParty(cfg))
+	g.Go(func() error {
+		return passkeys.Run(ctx, time.Hour)
+	})
+	mfa := mfaService.New(mfaRepository.New(db), usersRepository, events, passkeys, mfaBox, mfaService.Options{
+		Issuer:       cfg.MFA.Issuer,
+		ChallengeTTL: cfg.MFA.ChallengeTTL,
+		MaxAttempts:  cfg.MFA.MaxAttempts,
+	})
+	g.Go(func() error {
+		return mfa.Run(ctx, time.Hour)
+	})
+	notifier, err := setupNotifier(cfg)
+	if err != nil {
+		return err
+	}
+	passwordless := passwordlessService.New(passwordlessRepository.New(db), usersRepository, notifier, passwordlessService.Options{
+		CodeTTL:        cfg.Passwordless.CodeTTL,
+		MaxAttempts:    cfg.Passwordless.MaxAttempts,
+		ResendInterval: cfg.Passwordless.ResendInterval,
+		LinkURL:        cfg.Passwordless.LinkURL,
+	})
+	g.Go(func() error {
+		return passwordless.Run(ctx, time.Hour)
+	})
+	passwords, err := passhash.New(passhash.Options{
+		Algorithm: cfg.Password.Algorithm,
+		Argon2: passhash.Argon2Params{
+			Memory:      cfg.Password.Argon2.Memory,
+			Iterations:  cfg.Password.Argon2.Iterations,
+			Parallelism: cfg.Password.Argon2.Parallelism,
+			SaltLength:  cfg.Password.Argon2.SaltLength,
+			KeyLength:   cfg.Password.Argon2.KeyLength,
+		},
+		BcryptCost: cfg.Password.BcryptCost,
+	})
+	if err != nil {
+		return err
+	}
+	passwordPolicy, err := passwordpolicy.New(passwordpolicy.Options{
+		MinLength:       cfg.Password.Policy.MinLength,
+		MaxLength:       cfg.Password.Policy.MaxLength,
+		RequiredClasses: cfg.Password.Policy.RequiredClasses,
+		BreachedPath:    cfg.Password.Policy.BreachedPath,
+	})
+	if err != nil {
+		return err
+	}
+	authService := auth.New(usersRepository, jwtLib, revocations, sessionsRepository, clients, telegramVerifier, mfa, passkeys, passwordless, passwords, passwordPolicy, cfg.JWT.DefaultClient, cfg.Telegram.MiniAppClient)
+	introspection := oauth.NewIntrospection(usersRepository, jwtLib, revocations, clients)
 
-	httpServer := application.SetupHTTPServer(cfg, authService, &jwtMiddlewareAdapter{jwtLib: jwtLib})
+	oidcProvider := oidc.NewProvider(oidcRepository.New(db), oidcRepository.NewDevices(db), usersRepository, authService, clients, jwtLib, oidc.Options{
+		LoginURL:           cfg.OIDC.LoginURL,
+		CodeTTL:            cfg.OIDC.CodeTTL,
+		VerificationURI:    cfg.OIDC.DeviceVerificationURI,
+		DeviceCodeTTL:      cfg.OIDC.DeviceCodeTTL,
+		DevicePollInterval: cfg.OIDC.DevicePollInterval,
+	})
+	g.Go(func() error {
+		return oidcProvider.Run(ctx, time.Hour)
+	})
+
+	upstreamProviders, err := setupUpstreamProviders(cfg)
+	if err != nil {
+		return err
+	}
+	federation := federationService.New(federationRepository.New(db), usersRepository, authService, clients, upstreamProviders, passwords, federationService.Options{
+		Issuer:        cfg.JWT.Issuer,
+		DefaultClient: cfg.JWT.DefaultClient,
+		LoginTTL:      cfg.Federation.LoginTTL,
+	})
+	g.Go(func() error {
+		return federation.Run(ctx, time.Hour)
+	})
+
+	if cfg.BackchannelLogout.Timeout >= logoutService.Lease {
+		return fmt.Errorf("backchannel_logout.timeout must be less than %s", logoutService.Lease)
+	}
+	logoutNotifier := logoutService.New(logoutRepository.New(db), jwtLib, cfg.BackchannelLogout.Timeout, cfg.BackchannelLogout.MaxAttempts)
+	g.Go(func() error {
+		return logoutNotifier.Run(ctx, cfg.BackchannelLogout.PollInterval)
+	})
+
+	httpServer := application.SetupHTTPServer(cfg, application.Services{
+		Auth:          authService,
+		Federation:    federation,
+		Revocations:   revocations,
+		Introspection: introspection,
+		Sessions:      sessionService.New(sessionsRepository, revocations),
+		MFA:           mfa,
+		Passkeys:      passkeys,
+		Telegram:      authService,
+		Events:        events,
+		OIDC:          oidcProvider,
+		Jwt:           &jwtMiddlewareAdapter{jwtLib: jwtLib},
+		Keys:          jwtLib,
+		Origins:       clients,
+	})
 
 	server := &http.Server{
 		Addr:              fmt.Sprintf(":%d", cfg.HTTP.Port),
@@ -57,6 +236,260 @@ func startServers(ctx context.Context, g *errgroup.Group, db *sqlx.DB, cfg *conf
 	}
 
 	startGroup(ctx, g, "http", fmt.Sprintf("%d", cfg.HTTP.Port), server, time.Second*5)
+	return nil
+}
+
+func lifetimePolicy(cfg *config.Config) jwt.LifetimePolicy {
+	toLifetime := func(c config.LifetimeConfig) jwt.Lifetime {
+		return jwt.Lifetime{Access: c.AccessTTL, Refresh: c.RefreshTTL, Session: c.MaxSessionTTL}
+	}
+
+	policy := jwt.LifetimePolicy{
+		Default: toLifetime(cfg.JWT.Lifetime),
+		Roles:   make(map[string]jwt.Lifetime, len(cfg.JWT.Roles)),
+	}
+	for role, lifetime := range cfg.JWT.Roles {
+		policy.Roles[role] = toLifetime(lifetime)
+	}
+	if retention := cfg.JWT.Rotation.Retention; retention > 0 && retention < policy.Max() {
+		slog.Warn("jwt.rotation.retention is shorter than the longest token lifetime", "retention", retention, "max_lifetime", policy.Max())
+	}
+	return policy
+}
+
+// setupClientRegistry регистрирует клиентов из конфигурации, которых ещё нет в БД, и загружает реестр
+func setupClientRegistry(ctx context.Context, db *sqlx.DB, cfg *config.Config) (*clientService.Registry, error) {
+	seed := make([]clientService.ConfigClient, 0, len(cfg.Clients))
+	for _, c := range cfg.Clients {
+		if c.ID == "" {
+			return nil, errors.New("clients: id is required")
+		}
+		// секрет-заглушка из примера конфига известен всем, с ним клиент стартует только локально
+		if isPlaceholderSecret(c.Secret) && cfg.Env != "local" {
+			return nil, fmt.Errorf("clients: secret of client %q is a placeholder, set %s", c.ID, config.ClientSecretEnv(c.ID))
+		}
+		if c.Secret == "" && slices.Contains(c.GrantTypes, domain.GrantTypeClientCredentials) {
+			slog.Warn("client has client_credentials grant but no secret, set it to enable service tokens",
+				slog.String("client_id", c.ID), slog.String("env", config.ClientSecretEnv(c.ID)))
+		}
+		audience := c.Audience
+		if len(audience) == 0 {
+			audience = []string{c.ID}
+		}
+		seed = append(seed, clientService.ConfigClient{Secret: c.Secret, Client: domain.Client{
+			Id:                   c.ID,
+			Name:                 c.Name,
+			Audience:             audience,
+			RedirectURIs:         c.RedirectURIs,
+			AllowedOrigins:       c.AllowedOrigins,
+			GrantTypes:           c.GrantTypes,
+			Scopes:               c.Scopes,
+			BackchannelLogoutURI: c.BackchannelLogoutURI,
+			Lifetime:             jwt.Lifetime{Access: c.Lifetime.AccessTTL, Refresh: c.Lifetime.RefreshTTL, Session: c.Lifetime.MaxSessionTTL},
+		}})
+	}
+
+	registry := clientService.New(client.New(db))
+	if err := registry.Seed(ctx, seed); err != nil {
+		return nil, fmt.Errorf("failed to register clients: %w", err)
+	}
+	if err := registry.Reload(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load clients: %w", err)
+	}
+	return registry, nil
+}
+
+func isPlaceholderSecret(secret string) bool {
+	normalized := strings.ToLower(strings.ReplaceAll(secret, "-", ""))
+	return strings.HasPrefix(normalized, "changeme")
+}
+
+// setupUpstreamProviders создаёт коннекторы внешних провайдеров. Discovery выполняется при первом входе
+func setupUpstreamProviders(cfg *config.Config) ([]federationService.Provider, error) {
+	providers := make([]federationService.Provider, 0, len(cfg.Federation.Providers))
+	seen := make(map[string]struct{}, len(cfg.Federation.Providers))
+	for _, p := range cfg.Federation.Providers {
+		if p.ID == "" {
+			return nil, errors.New("federation.providers: id is required")
+		}
+		if _, ok := seen[p.ID]; ok {
+			return nil, fmt.Errorf("federation.providers: duplicate id %q", p.ID)
+		}
+		seen[p.ID] = struct{}{}
+
+		connector, err := upstream.NewOIDC(upstream.Config{
+			Issuer:           p.Issuer,
+			ClientID:         p.ClientID,
+			ClientSecret:     p.ClientSecret,
+			RedirectURL:      p.RedirectURL,
+			Scopes:           p.Scopes,
+			AuthorizationURL: p.AuthorizationURL,
+			TokenURL:         p.TokenURL,
+			UserInfoURL:      p.UserInfoURL,
+			JWKSURL:          p.JWKSURL,
+			TokenAuthMethod:  p.TokenAuthMethod,
+			Claims: upstream.ClaimMapping{
+				Subject:  p.Claims.Subject,
+				Login:    p.Claims.Login,
+				FullName: p.Claims.FullName,
+				Verified: p.Claims.Verified,
+			},
+			Leeway: cfg.JWT.Leeway,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("federation provider %q: %w", p.ID, err)
+		}
+		providers = append(providers, federationService.Provider{
+			ID:          p.ID,
+			Name:        p.Name,
+			Connector:   connector,
+			AutoCreate:  p.AutoCreate,
+			LinkByLogin: p.LinkByLogin,
+			RoleID:      p.RoleID,
+		})
+	}
+	return providers, nil
+}
+
+// setupMFABox создаёт шифр секретов TOTP. Без ключа возвращает nil: второй фактор нельзя подключить,
+// но вход пользователей, у которых он уже есть, не обходится — проверка кода вернёт ошибку
+func setupMFABox(cfg *config.Config) (*secretbox.Box, error) {
+	if cfg.MFA.EncryptionKey == "" {
+		slog.Warn("mfa.encryption_key is not set, totp is disabled")
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.MFA.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("mfa.encryption_key: %w", err)
+	}
+	box, err := secretbox.New(key)
+	if err != nil {
+		return nil, fmt.Errorf("mfa.encryption_key: %w", err)
+	}
+	return box, nil
+}
+
+// setupRelyingParty описывает сервер для WebAuthn. Без rp_id и origins ключи выключены:
+// регистрация и вход по ним возвращают ошибку, пароль и TOTP работают как прежде
+func setupRelyingParty(cfg *config.Config) *libwebauthn.RelyingParty {
+	rp, err := libwebauthn.NewRelyingParty(libwebauthn.Config{
+		RPID:    cfg.WebAuthn.RPID,
+		RPName:  cfg.WebAuthn.RPName,
+		Origins: cfg.WebAuthn.Origins,
+		Timeout: cfg.WebAuthn.Timeout,
+	})
+	if err != nil {
+		slog.Warn("webauthn is disabled", slog.String("reason", err.Error()))
+		return nil
+	}
+	return rp
+}
+
+// setupNotifier собирает каналы доставки кодов входа в порядке passwordless.notifiers.
+// Без каналов возвращает nil: вход по коду выключен
+func setupNotifier(cfg *config.Config) (notify.Notifier, error) {
+	var chain notify.Chain
+	for _, name := range cfg.Passwordless.Notifiers {
+		switch name {
+		case "telegram":
+			if cfg.Telegram.BotToken == "" {
+				return nil, fmt.Errorf("passwordless: telegram notifier requires telegram.bot_token")
+			}
+			chain = append(chain, notify.NewTelegram(cfg.Telegram.BotToken, 10*time.Second))
+		case "email":
+			email, err := notify.NewEmail(notify.EmailConfig{
+				Host:     cfg.Passwordless.SMTP.Host,
+				Port:     cfg.Passwordless.SMTP.Port,
+				Username: cfg.Passwordless.SMTP.Username,
+				Password: cfg.Passwordless.SMTP.Password,
+				From:     cfg.Passwordless.SMTP.From,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("passwordless: %w", err)
+			}
+			chain = append(chain, email)
+		case "log":
+			slog.Warn("passwordless: login codes are written to the log, use only for development")
+			chain = append(chain, notify.Log{})
+		case "file":
+			if cfg.Passwordless.FilePath == "" {
+				return nil, fmt.Errorf("passwordless: file notifier requires passwordless.file_path")
+			}
+			slog.Warn("passwordless: login codes are written to a file, use only for development", slog.String("path", cfg.Passwordless.FilePath))
+			chain = append(chain, notify.NewFile(cfg.Passwordless.FilePath))
+		default:
+			return nil, fmt.Errorf("passwordless: unknown notifier %q", name)
+		}
+	}
+	if len(chain) == 0 {
+		slog.Warn("passwordless.notifiers is empty, login by one-time code is disabled")
+		return nil, nil
+	}
+	return chain, nil
+}
+
+func setupKeySet(cfg *config.Config) (*jwt.KeySet, error) {
+	var store jwt.KeyStore
+	if cfg.JWT.KeysDir != "" {
+		dirStore, err := jwt.NewDirKeyStore(cfg.JWT.KeysDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open key store: %w", err)
+		}
+		store = dirStore
+	} else if cfg.JWT.Rotation.Interval > 0 {
+		return nil, errors.New("jwt.rotation.interval requires jwt.keys_dir")
+	}
+
+	var configured *jwt.SigningKey
+	if cfg.JWT.PrivateKeyPath != "" || cfg.Secret != "" {
+		key, err := jwt.LoadSigningKey(cfg.JWT.Algorithm, cfg.JWT.PrivateKeyPath, []byte(cfg.Secret))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load signing key: %w", err)
+		}
+		configured = key
+	}
+
+	keySet, err := jwt.NewKeySet(cfg.JWT.Algorithm, cfg.JWT.Rotation.Retention, store, configured)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup signing keys: %w", err)
+	}
+	slog.Info("signing keys loaded", "active_kid", keySet.Active().ID(), "keys", len(keySet.Keys()))
+	return keySet, nil
+}
+
+// startKeyRotation ротирует ключи по расписанию и по SIGHUP
+func startKeyRotation(ctx context.Context, g *errgroup.Group, keySet *jwt.KeySet, interval time.Duration) {
+	if !keySet.CanRotate() {
+		return
+	}
+
+	logRotation := func(key *jwt.SigningKey, err error) {
+		if err != nil {
+			slog.Error("signing key rotation failed", "err", err)
+			return
+		}
+		if key != nil {
+			slog.Info("signing key rotated", "kid", key.ID(), "algorithm", key.Algorithm())
+		}
+	}
+
+	g.Go(func() error {
+		return keySet.RunRotation(ctx, interval, time.Minute, logRotation)
+	})
+
+	g.Go(func() error {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-hup:
+				logRotation(keySet.Rotate())
+			}
+		}
+	})
 }
 
 func startPprofServer(ctx context.Context, g *errgroup.Group) {
@@ -92,10 +525,24 @@ type jwtMiddlewareAdapter struct {
 	jwtLib *jwt.JwtLib
 }
 
-func (j *jwtMiddlewareAdapter) ParseToken(tokenString string) (int64, error) {
+func (j *jwtMiddlewareAdapter) ParseToken(tokenString string) (*echomiddleware.TokenClaims, error) {
 	claims, err := j.jwtLib.ParseToken(tokenString, jwt.TokenTypeAccess)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return claims.UserID, nil
+	return &echomiddleware.TokenClaims{
+		ID:            claims.ID,
+		PrincipalType: string(claims.PrincipalType),
+		UserID:        claims.UserID,
+		Role:          claims.Role,
+		ClientID:      claims.ClientID,
+		Audience:      claims.Audience,
+		Scopes:        claims.Scopes,
+		SessionID:     claims.SessionID,
+		TokenVersion:  claims.TokenVersion,
+		IssuedAt:      claims.IssuedAt,
+		AuthTime:      claims.AuthTime,
+		Amr:           claims.Amr,
+		Acr:           claims.Acr,
+	}, nil
 }
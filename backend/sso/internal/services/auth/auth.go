@@ -5,19 +5,24 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/EtoNeAnanasbI95/sso/internal/domain"
 	"github.com/EtoNeAnanasbI95/sso/internal/dto/auth"
 	authErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/auth"
+	clientErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/client"
 	jwtErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/jwt"
+	telegramErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/telegram"
 	libjwt "github.com/EtoNeAnanasbI95/sso/internal/lib/jwt"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/telegram"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/webauthn"
 )
 
 type Jwt interface {
-	NewTokens(userId int64, role string) (accessToken string, refreshToken string, err error)
+	NewTokens(params libjwt.TokenParams) (*libjwt.TokenPair, error)
 	ParseToken(tokenString string, expectedType libjwt.TokenType) (*libjwt.TokenClaims, error)
+	Lifetime(role string, client libjwt.Lifetime) libjwt.Lifetime
 }
 
 type Repository interface {
@@ -28,25 +33,107 @@ type Repository interface {
 	GetPasswordResetToken(ctx context.Context, token string) (*domain.PasswordResetToken, error)
 	MarkResetTokenConsumed(ctx context.Context, tokenId int64) error
 	UpdateUserPassword(ctx context.Context, userId int64, password []byte) error
+	RehashUserPassword(ctx context.Context, userId int64, current, rehashed []byte) (bool, error)
+	CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, hash []byte) (*domain.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, current *domain.RefreshToken, next *domain.RefreshToken) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyId string) error
+	GetUserByTelegramChatId(ctx context.Context, chatId int64) (*domain.User, error)
+	LinkTelegramAccount(ctx context.Context, userId int64, chatId int64) (bool, error)
+}
+
+type Revocations interface {
+	IsRevoked(jti string, userId int64, issuedAt time.Time) bool
+}
+
+type Sessions interface {
+	SaveSession(ctx context.Context, session *domain.Session) error
+	RevokeSingleSignOn(ctx context.Context, sessionId string) error
+}
+
+type Clients interface {
+	GetClient(ctx context.Context, clientId string) (*domain.Client, error)
+}
+
+// Telegram проверяет данные, подписанные Telegram для бота SSO
+type Telegram interface {
+	VerifyLoginWidget(data map[string]string, now time.Time) (*telegram.User, error)
+	VerifyInitData(initData string, now time.Time) (*telegram.User, error)
+}
+
+// MFA — второй фактор входа по паролю
+type MFA interface {
+	Methods(ctx context.Context, userId int64) ([]string, error)
+	NewChallenge(ctx context.Context, userId int64, clientId string, amr []string) (string, error)
+	CompleteChallenge(ctx context.Context, token, code string) (*domain.MfaChallenge, error)
+	CompleteChallengeWithRecoveryCode(ctx context.Context, token, recoveryCode string, meta domain.SessionMeta) (*domain.MfaChallenge, error)
+	WebAuthnOptions(ctx context.Context, token string) (*webauthn.RequestOptions, error)
+	CompleteChallengeWithWebAuthn(ctx context.Context, token string, response webauthn.AssertionResponse) (*domain.MfaChallenge, error)
+}
+
+// Passkeys — вход по ключу WebAuthn без пароля
+type Passkeys interface {
+	BeginLogin(ctx context.Context, clientId string) (*webauthn.RequestOptions, error)
+	VerifyLogin(ctx context.Context, response webauthn.AssertionResponse) (int64, string, error)
+}
+
+// PasswordPolicy проверяет новый пароль. personal — данные пользователя, которых не должно быть в пароле
+type PasswordPolicy interface {
+	Check(password string, personal ...string) error
+}
+
+// Passwordless — вход по одноразовому коду, отправленному пользователю
+type Passwordless interface {
+	RequestCode(ctx context.Context, login, clientId string) (string, error)
+	VerifyCode(ctx context.Context, token, code string) (*domain.LoginCode, error)
+	CodeTTL() time.Duration
 }
 
 type Auth struct {
-	repo Repository
-	jwt  Jwt
+	repo          Repository
+	jwt           Jwt
+	revocations   Revocations
+	sessions      Sessions
+	clients       Clients
+	telegram      Telegram
+	mfa           MFA
+	passkeys      Passkeys
+	passwordless  Passwordless
+	passwords     domain.PasswordHasher
+	policy        PasswordPolicy
+	defaultClient string
+	// miniAppClient — клиент, для которого выпускаются токены Telegram Mini App
+	miniAppClient string
 }
 
 const resetTokenTTLMinutes = 30
 
-func New(repo Repository, jwt Jwt) *Auth {
+func New(repo Repository, jwt Jwt, revocations Revocations, sessions Sessions, clients Clients, telegram Telegram, mfa MFA, passkeys Passkeys, passwordless Passwordless, passwords domain.PasswordHasher, policy PasswordPolicy, defaultClient, miniAppClient string) *Auth {
 	return &Auth{
-		repo: repo,
-		jwt:  jwt,
+		repo:          repo,
+		jwt:           jwt,
+		revocations:   revocations,
+		sessions:      sessions,
+		clients:       clients,
+		telegram:      telegram,
+		mfa:           mfa,
+		passkeys:      passkeys,
+		passwordless:  passwordless,
+		passwords:     passwords,
+		policy:        policy,
+		defaultClient: defaultClient,
+		miniAppClient: miniAppClient,
 	}
 }
 
-func (a *Auth) Auth(ctx context.Context, request auth.AuthRequest, isNew bool) (*auth.AuthResponse, error) {
+func (a *Auth) Auth(ctx context.Context, request auth.AuthRequest, isNew bool, meta domain.SessionMeta) (*auth.AuthResponse, error) {
 	const op string = "Auth.Login"
 
+	client, err := a.resolveClient(ctx, request.ClientID, domain.GrantTypePassword)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := a.repo.GetUserByLogin(ctx, request.Login)
 	if err != nil {
 		slog.Error("failed to get user", "err", err)
@@ -58,16 +145,24 @@ func (a *Auth) Auth(ctx context.Context, request auth.AuthRequest, isNew bool) (
 		if user != nil {
 			return nil, authErrors.ErrUserAlreadyExists
 		}
+		if err := a.policy.Check(request.Password, request.Login, request.TelegramUsername, request.FullName); err != nil {
+			return nil, err
+		}
 
-		user = domain.NewUser(
+		user, err = domain.NewUser(
+			a.passwords,
 			request.Login,
 			request.TelegramUsername,
 			request.Password,
 			request.FullName,
-			request.TelegramChatID,
+			// chat id берётся только из подписанных Telegram данных при привязке
+			nil,
 			nil,
 			nil,
 		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
 		newUserID, err := a.repo.CreateUser(ctx, user)
 		if err != nil {
 			errText := fmt.Errorf("ошибка в ходе создания пользователя: %w", err)
@@ -83,17 +178,354 @@ func (a *Auth) Auth(ctx context.Context, request auth.AuthRequest, isNew bool) (
 		if user == nil {
 			return nil, authErrors.ErrInvalidUserCredentials
 		}
-		valid := user.CheckPassword(request.Password)
+		valid, outdated := user.CheckPassword(a.passwords, request.Password)
 		// если пароль не верен
 		if !valid {
 			return nil, authErrors.ErrInvalidUserCredentials
 		}
+		if outdated {
+			a.rehashPassword(ctx, user, request.Password)
+		}
+
+		return a.CompleteFirstFactor(ctx, user, client, []string{domain.AmrPassword}, meta)
+	}
+
+	return a.StartSession(ctx, user, client, time.Now(), []string{domain.AmrPassword}, "", meta)
+}
+
+// rehashPassword пересчитывает устаревший хеш только что проверенного пароля. Ошибка не мешает входу:
+// хеш пересчитается при следующем
+func (a *Auth) rehashPassword(ctx context.Context, user *domain.User, password string) {
+	current := user.PasswordHash
+	if err := user.RehashPassword(a.passwords, password); err != nil {
+		slog.Warn("failed to rehash password", "user_id", user.Id, "err", err)
+		return
+	}
+	rehashed, err := a.repo.RehashUserPassword(ctx, user.Id, current, user.PasswordHash)
+	if err != nil {
+		slog.Warn("failed to rehash password", "user_id", user.Id, "err", err)
+		return
+	}
+	if rehashed {
+		slog.Info("password rehashed", "user_id", user.Id)
+	}
+}
+
+// CompleteFirstFactor завершает вход после первого шага, пройденного способами amr. При включённом втором
+// факторе токены выдаются только после его проверки в VerifyMFA. Через него проходят и входы, первый шаг
+// которых проверяют другие сервисы, например вход через внешнего провайдера
+func (a *Auth) CompleteFirstFactor(ctx context.Context, user *domain.User, client *domain.Client, amr []string, meta domain.SessionMeta) (*auth.AuthResponse, error) {
+	const op string = "Auth.CompleteFirstFactor"
+
+	methods, err := a.mfa.Methods(ctx, user.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(methods) > 0 {
+		token, err := a.mfa.NewChallenge(ctx, user.Id, client.Id, amr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return &auth.AuthResponse{MfaRequired: true, MfaToken: token, MfaMethods: methods}, nil
 	}
+	return a.StartSession(ctx, user, client, time.Now(), amr, "", meta)
+}
 
-	return a.getAuthResponse(ctx, user)
+// VerifyMFA завершает вход по паролю или одноразовому коду кодом второго фактора, ключом WebAuthn или кодом восстановления
+// и выпускает токены для клиента, указанного на первом шаге
+func (a *Auth) VerifyMFA(ctx context.Context, request auth.MfaVerifyRequest, meta domain.SessionMeta) (*auth.AuthResponse, error) {
+	var (
+		challenge *domain.MfaChallenge
+		factor    string
+		err       error
+	)
+	switch {
+	case request.WebAuthn != nil:
+		challenge, err = a.mfa.CompleteChallengeWithWebAuthn(ctx, request.MfaToken, *request.WebAuthn)
+		factor = domain.AmrWebAuthn
+	case request.RecoveryCode != "":
+		challenge, err = a.mfa.CompleteChallengeWithRecoveryCode(ctx, request.MfaToken, request.RecoveryCode, meta)
+		factor = domain.AmrOTP
+	default:
+		challenge, err = a.mfa.CompleteChallenge(ctx, request.MfaToken, request.Code)
+		factor = domain.AmrOTP
+	}
+	if err != nil {
+		return nil, err
+	}
+	amr := domain.MultiFactorAmr(append(challenge.FirstFactorAmr(), factor)...)
+	client, err := a.resolveClient(ctx, challenge.ClientId, domain.GrantTypePassword)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.repo.GetUserWithId(ctx, challenge.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, authErrors.ErrUserNotFound
+	}
+	return a.StartSession(ctx, user, client, time.Now(), amr, "", meta)
+}
+
+// MfaWebAuthnOptions — параметры navigator.credentials.get для второго шага входа ключом WebAuthn
+func (a *Auth) MfaWebAuthnOptions(ctx context.Context, mfaToken string) (*webauthn.RequestOptions, error) {
+	return a.mfa.WebAuthnOptions(ctx, mfaToken)
 }
 
-func (a *Auth) Refresh(ctx context.Context, refreshToken string) (*auth.AuthResponse, error) {
+// WebAuthnLoginOptions начинает вход по passkey для клиента clientId
+func (a *Auth) WebAuthnLoginOptions(ctx context.Context, clientId string) (*webauthn.RequestOptions, error) {
+	client, err := a.resolveClient(ctx, clientId, domain.GrantTypePassword)
+	if err != nil {
+		return nil, err
+	}
+	return a.passkeys.BeginLogin(ctx, client.Id)
+}
+
+// WebAuthnLogin входит по passkey без пароля и второго шага. Passkey требует проверки пользователя
+// на устройстве, поэтому вход считается многофакторным: владение ключом и PIN или биометрия
+func (a *Auth) WebAuthnLogin(ctx context.Context, response webauthn.AssertionResponse, meta domain.SessionMeta) (*auth.AuthResponse, error) {
+	userId, clientId, err := a.passkeys.VerifyLogin(ctx, response)
+	if err != nil {
+		return nil, err
+	}
+	client, err := a.resolveClient(ctx, clientId, domain.GrantTypePassword)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.repo.GetUserWithId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.IsArchived {
+		return nil, authErrors.ErrUserNotFound
+	}
+	return a.StartSession(ctx, user, client, time.Now(), domain.MultiFactorAmr(domain.AmrWebAuthn), "", meta)
+}
+
+// RequestLoginCode отправляет пользователю одноразовый код входа в клиент из запроса
+func (a *Auth) RequestLoginCode(ctx context.Context, request auth.LoginCodeRequest) (*auth.LoginCodeResponse, error) {
+	client, err := a.resolveClient(ctx, request.ClientID, domain.GrantTypePassword)
+	if err != nil {
+		return nil, err
+	}
+	token, err := a.passwordless.RequestCode(ctx, request.Login, client.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.LoginCodeResponse{
+		LoginToken: token,
+		ExpiresIn:  int64(a.passwordless.CodeTTL().Seconds()),
+	}, nil
+}
+
+// LoginWithCode входит по одноразовому коду вместо пароля. Второй фактор, если он включён, всё равно нужен:
+// код подтверждает только доступ к почте или Telegram
+func (a *Auth) LoginWithCode(ctx context.Context, request auth.LoginCodeVerifyRequest, meta domain.SessionMeta) (*auth.AuthResponse, error) {
+	code, err := a.passwordless.VerifyCode(ctx, request.LoginToken, request.Code)
+	if err != nil {
+		return nil, err
+	}
+	client, err := a.resolveClient(ctx, code.ClientId, domain.GrantTypePassword)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.repo.GetUserWithId(ctx, code.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.IsArchived {
+		return nil, authErrors.ErrUserNotFound
+	}
+	return a.CompleteFirstFactor(ctx, user, client, []string{domain.AmrOTP}, meta)
+}
+
+// TelegramAuth входит по данным Telegram Login Widget, подпись которых проверена токеном бота
+func (a *Auth) TelegramAuth(ctx context.Context, request auth.TelegramAuthRequest, meta domain.SessionMeta) (*auth.AuthResponse, error) {
+	client, err := a.resolveClient(ctx, request.ClientID, domain.GrantTypePassword)
+	if err != nil {
+		return nil, err
+	}
+
+	tgUser, err := a.telegram.VerifyLoginWidget(telegramWidgetData(request), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.telegramUser(ctx, tgUser)
+	if err != nil {
+		return nil, err
+	}
+	return a.CompleteFirstFactor(ctx, user, client, []string{domain.AmrTelegram}, meta)
+}
+
+// TelegramWebAppAuth входит по initData Telegram Mini App. Клиент не выбирается запросом: токены всегда
+// выпускаются для клиента Mini App, поэтому их audience отличает Mini App от остальных приложений
+func (a *Auth) TelegramWebAppAuth(ctx context.Context, initData string, meta domain.SessionMeta) (*auth.AuthResponse, error) {
+	if a.miniAppClient == "" {
+		return nil, telegramErrors.ErrNotConfigured
+	}
+	client, err := a.resolveClient(ctx, a.miniAppClient, domain.GrantTypePassword)
+	if err != nil {
+		return nil, err
+	}
+
+	tgUser, err := a.telegram.VerifyInitData(initData, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.telegramUser(ctx, tgUser)
+	if err != nil {
+		return nil, err
+	}
+	return a.CompleteFirstFactor(ctx, user, client, []string{domain.AmrTelegram}, meta)
+}
+
+// telegramUser находит пользователя по подтверждённому id Telegram (он же telegram_chat_id). Тег Telegram
+// пользователь указывает сам, поэтому по нему аккаунт не ищется: Telegram привязывают из своей сессии через LinkTelegram
+func (a *Auth) telegramUser(ctx context.Context, tgUser *telegram.User) (*domain.User, error) {
+	user, err := a.repo.GetUserByTelegramChatId(ctx, tgUser.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, telegramErrors.ErrNotLinked
+	}
+	return user, nil
+}
+
+// LinkTelegram привязывает к вошедшему пользователю userId аккаунт Telegram из данных Login Widget
+func (a *Auth) LinkTelegram(ctx context.Context, userId int64, request auth.TelegramAuthRequest) error {
+	tgUser, err := a.telegram.VerifyLoginWidget(telegramWidgetData(request), time.Now())
+	if err != nil {
+		return err
+	}
+	return a.linkTelegram(ctx, userId, tgUser)
+}
+
+// LinkTelegramWebApp привязывает к вошедшему пользователю userId аккаунт Telegram из initData Mini App
+func (a *Auth) LinkTelegramWebApp(ctx context.Context, userId int64, initData string) error {
+	tgUser, err := a.telegram.VerifyInitData(initData, time.Now())
+	if err != nil {
+		return err
+	}
+	return a.linkTelegram(ctx, userId, tgUser)
+}
+
+func (a *Auth) linkTelegram(ctx context.Context, userId int64, tgUser *telegram.User) error {
+	owner, err := a.repo.GetUserByTelegramChatId(ctx, tgUser.ID)
+	if err != nil {
+		return err
+	}
+	if owner != nil && owner.Id != userId {
+		return telegramErrors.ErrAlreadyLinked
+	}
+	linked, err := a.repo.LinkTelegramAccount(ctx, userId, tgUser.ID)
+	if err != nil {
+		return err
+	}
+	// у пользователя уже подтверждён другой аккаунт Telegram или этот подтверждён у архивного пользователя
+	if !linked {
+		return telegramErrors.ErrAlreadyLinked
+	}
+	slog.Info("telegram account linked", "user_id", userId)
+	return nil
+}
+
+// telegramWidgetData восстанавливает поля, подписанные Login Widget. Пустые поля виджет не присылает
+func telegramWidgetData(request auth.TelegramAuthRequest) map[string]string {
+	data := map[string]string{
+		"id":        strconv.FormatInt(request.ID, 10),
+		"auth_date": strconv.FormatInt(request.AuthDate, 10),
+		"hash":      request.Hash,
+	}
+	optional := map[string]string{
+		"first_name": request.FirstName,
+		"last_name":  request.LastName,
+		"username":   request.Username,
+		"photo_url":  request.PhotoURL,
+	}
+	for key, value := range optional {
+		if value != "" {
+			data[key] = value
+		}
+	}
+	return data
+}
+
+// StartSession начинает новую сессию уже аутентифицированного пользователя для клиента:
+// выпускает пару токенов новой цепочки и записывает сессию. authTime и amr — когда и как пользователь
+// подтвердил личность, ssoSessionId — сессия SSO в браузере, из которой получена новая сессия; пустой — сессия самостоятельная
+func (a *Auth) StartSession(ctx context.Context, user *domain.User, client *domain.Client, authTime time.Time, amr []string, ssoSessionId string, meta domain.SessionMeta) (*auth.AuthResponse, error) {
+	const op string = "Auth.StartSession"
+
+	familyId, err := domain.NewTokenFamilyId()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	response, tokens, err := a.getAuthResponse(ctx, user, client, familyId, authTime, amr)
+	if err != nil {
+		return nil, err
+	}
+	stored := domain.NewRefreshToken(user.Id, familyId, tokens.RefreshToken, authTime, amr, tokens.RefreshExpiresAt, nil)
+	if err := a.repo.CreateRefreshToken(ctx, stored); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	session := domain.NewSession(familyId, user.Id, client.Id, meta, authTime, tokens.RefreshExpiresAt)
+	if ssoSessionId != "" {
+		session.SsoSessionId = &ssoSessionId
+	}
+	if err := a.sessions.SaveSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return response, nil
+}
+
+// CurrentSession проверяет refresh токен из cookie SSO без ротации и возвращает пользователя и его сессию.
+// Используется, чтобы не спрашивать пароль повторно, когда пользователь уже вошёл в SSO
+func (a *Auth) CurrentSession(ctx context.Context, refreshToken string) (*domain.User, *domain.RefreshToken, error) {
+	claims, err := a.jwt.ParseToken(refreshToken, libjwt.TokenTypeRefresh)
+	if err != nil {
+		return nil, nil, authErrors.ErrInvalidRefreshToken
+	}
+
+	current, err := a.repo.GetRefreshTokenByHash(ctx, domain.HashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, nil, err
+	}
+	if current == nil || current.UserId != claims.UserID || current.IsRevoked() || current.IsRotated() || current.IsExpired(time.Now()) {
+		return nil, nil, authErrors.ErrInvalidRefreshToken
+	}
+	if a.revocations.IsRevoked(claims.ID, claims.UserID, claims.IssuedAt) {
+		return nil, nil, authErrors.ErrInvalidRefreshToken
+	}
+
+	user, err := a.repo.GetUserWithId(ctx, claims.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil || user.IsArchived {
+		return nil, nil, authErrors.ErrUserNotFound
+	}
+	if claims.TokenVersion < user.TokenVersion {
+		return nil, nil, authErrors.ErrInvalidRefreshToken
+	}
+
+	var clientLifetime libjwt.Lifetime
+	client, err := a.clients.GetClient(ctx, claims.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if client != nil {
+		clientLifetime = client.Lifetime
+	}
+	lifetime := a.jwt.Lifetime(userRole(user), clientLifetime)
+	if lifetime.Session > 0 && !current.AuthTime.Add(lifetime.Session).After(time.Now()) {
+		return nil, nil, authErrors.ErrSessionExpired
+	}
+	return user, current, nil
+}
+
+func (a *Auth) Refresh(ctx context.Context, refreshToken string, meta domain.SessionMeta) (*auth.AuthResponse, error) {
 
 	// проверка токена
 	claims, err := a.jwt.ParseToken(refreshToken, libjwt.TokenTypeRefresh)
@@ -105,6 +537,24 @@ func (a *Auth) Refresh(ctx context.Context, refreshToken string) (*auth.AuthResp
 		return nil, err
 	}
 
+	// проверка токена в хранилище
+	current, err := a.repo.GetRefreshTokenByHash(ctx, domain.HashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || current.UserId != claims.UserID || current.IsRevoked() || current.IsExpired(time.Now()) {
+		return nil, authErrors.ErrInvalidRefreshToken
+	}
+	if a.revocations.IsRevoked(claims.ID, claims.UserID, claims.IssuedAt) {
+		if err := a.repo.RevokeRefreshTokenFamily(ctx, current.FamilyId); err != nil {
+			return nil, err
+		}
+		return nil, authErrors.ErrInvalidRefreshToken
+	}
+	if current.IsRotated() {
+		return nil, a.revokeReusedFamily(ctx, current)
+	}
+
 	// проверка пользователя
 	user, err := a.repo.GetUserWithId(ctx, claims.UserID)
 	if err != nil {
@@ -116,8 +566,94 @@ func (a *Auth) Refresh(ctx context.Context, refreshToken string) (*auth.AuthResp
 	if user == nil || user.IsArchived {
 		return nil, authErrors.ErrUserNotFound
 	}
+	// администратор принудительно завершил все сессии пользователя после выпуска токена
+	if claims.TokenVersion < user.TokenVersion {
+		if err := a.repo.RevokeRefreshTokenFamily(ctx, current.FamilyId); err != nil {
+			return nil, err
+		}
+		return nil, authErrors.ErrInvalidRefreshToken
+	}
+
+	// токены продлеваются для того же клиента, для которого была начата сессия
+	client, err := a.resolveClient(ctx, claims.ClientID, domain.GrantTypeRefreshToken)
+	if err != nil {
+		return nil, err
+	}
 
-	return a.getAuthResponse(ctx, user)
+	// простой ограничен сроком жизни refresh токена, а вся сессия — абсолютным пределом от auth_time
+	lifetime := a.jwt.Lifetime(userRole(user), client.Lifetime)
+	if lifetime.Session > 0 && !current.AuthTime.Add(lifetime.Session).After(time.Now()) {
+		if err := a.repo.RevokeRefreshTokenFamily(ctx, current.FamilyId); err != nil {
+			return nil, err
+		}
+		return nil, authErrors.ErrSessionExpired
+	}
+
+	amr := domain.SplitAmr(current.Amr)
+	response, tokens, err := a.getAuthResponse(ctx, user, client, current.FamilyId, current.AuthTime, amr)
+	if err != nil {
+		return nil, err
+	}
+	next := domain.NewRefreshToken(user.Id, current.FamilyId, tokens.RefreshToken, current.AuthTime, amr, tokens.RefreshExpiresAt, &current.Id)
+	rotated, err := a.repo.RotateRefreshToken(ctx, current, next)
+	if err != nil {
+		return nil, err
+	}
+	// токен успели ротировать параллельно — это тоже повторное использование
+	if !rotated {
+		return nil, a.revokeReusedFamily(ctx, current)
+	}
+
+	session := domain.NewSession(current.FamilyId, user.Id, client.Id, meta, current.AuthTime, tokens.RefreshExpiresAt)
+	if err := a.sessions.SaveSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Logout завершает сессию SSO, к которой принадлежит переданный токен: её цепочку refresh токенов и сессии
+// всех клиентов, вошедших через неё. Клиенты с back-channel logout получат уведомления
+func (a *Auth) Logout(ctx context.Context, refreshToken string) error {
+	current, err := a.repo.GetRefreshTokenByHash(ctx, domain.HashRefreshToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return nil
+	}
+	return a.sessions.RevokeSingleSignOn(ctx, current.FamilyId)
+}
+
+// resolveClient находит клиента по client_id и проверяет, что ему разрешён grantType.
+// Пустой client_id означает клиента по умолчанию
+func (a *Auth) resolveClient(ctx context.Context, clientId, grantType string) (*domain.Client, error) {
+	if clientId == "" {
+		clientId = a.defaultClient
+	}
+	client, err := a.clients.GetClient(ctx, clientId)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, clientErrors.ErrUnknownClient
+	}
+	if !client.AllowsGrant(grantType) {
+		return nil, clientErrors.ErrGrantNotAllowed
+	}
+	return client, nil
+}
+
+func (a *Auth) revokeReusedFamily(ctx context.Context, token *domain.RefreshToken) error {
+	slog.Warn("refresh token reuse detected, revoking family",
+		"user_id", token.UserId,
+		"family_id", token.FamilyId,
+		"token_id", token.Id,
+	)
+	if err := a.repo.RevokeRefreshTokenFamily(ctx, token.FamilyId); err != nil {
+		return err
+	}
+	return authErrors.ErrRefreshTokenReused
 }
 
 func (a *Auth) RequestPasswordReset(ctx context.Context, login string) (string, error) {
@@ -148,9 +684,20 @@ func (a *Auth) CompletePasswordReset(ctx context.Context, tokenStr, newPassword
 		return authErrors.ErrInvalidResetToken
 	}
 
-	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	user, err := a.repo.GetUserWithId(ctx, resetToken.UserId)
 	if err != nil {
-		return fmt.Errorf("hash password: %w", err)
+		return err
+	}
+	if user == nil {
+		return authErrors.ErrInvalidResetToken
+	}
+	if err := a.policy.Check(newPassword, user.Login, user.TelegramUsername, user.FullName); err != nil {
+		return err
+	}
+
+	hashed, err := a.passwords.Hash(newPassword)
+	if err != nil {
+		return err
 	}
 
 	if err := a.repo.UpdateUserPassword(ctx, resetToken.UserId, hashed); err != nil {
@@ -164,27 +711,45 @@ func (a *Auth) CompletePasswordReset(ctx context.Context, tokenStr, newPassword
 	return nil
 }
 
-func (a *Auth) getAuthResponse(ctx context.Context, user *domain.User) (*auth.AuthResponse, error) {
+func (a *Auth) getAuthResponse(ctx context.Context, user *domain.User, client *domain.Client, sessionId string, authTime time.Time, amr []string) (*auth.AuthResponse, *libjwt.TokenPair, error) {
 	if user == nil {
-		return nil, fmt.Errorf("user not found for token response")
+		return nil, nil, fmt.Errorf("user not found for token response")
 	}
 
-	roleName := user.RoleName
-	if roleName == "" {
-		roleName = "customer"
-	}
+	roleName := userRole(user)
 
-	accessToken, refreshToken, err := a.jwt.NewTokens(user.Id, roleName)
+	tokens, err := a.jwt.NewTokens(libjwt.TokenParams{
+		UserID:         user.Id,
+		Role:           roleName,
+		ClientID:       client.Id,
+		Audience:       client.Audience,
+		AuthTime:       authTime,
+		Amr:            amr,
+		Acr:            domain.Acr(amr),
+		ClientLifetime: client.Lifetime,
+		SessionID:      sessionId,
+		TokenVersion:   user.TokenVersion,
+	})
 	if err != nil {
 		errorText := fmt.Errorf("ошибка генерации токенов доступа: %w", err)
 		slog.Error(errorText.Error())
-		return nil, errorText
+		return nil, nil, errorText
 	}
 
 	return &auth.AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		UserID:       user.Id,
-		Role:         roleName,
-	}, nil
+		AccessToken:      tokens.AccessToken,
+		RefreshToken:     tokens.RefreshToken,
+		UserID:           user.Id,
+		Role:             roleName,
+		SessionID:        sessionId,
+		ExpiresIn:        int64(time.Until(tokens.AccessExpiresAt).Round(time.Second).Seconds()),
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+	}, tokens, nil
+}
+
+func userRole(user *domain.User) string {
+	if user.RoleName == "" {
+		return "customer"
+	}
+	return user.RoleName
 }
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	clientErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/client"
+)
+
+type Repository interface {
+	ListActiveClients(ctx context.Context) ([]domain.Client, error)
+	GetClientSecretHash(ctx context.Context, clientId string) ([]byte, error)
+	UpsertClient(ctx context.Context, client *domain.Client) (created bool, updated bool, err error)
+}
+
+// ConfigClient — клиент из конфигурации. Secret хранится открытым только в конфиге, в БД попадает его хэш
+type ConfigClient struct {
+	Client domain.Client
+	Secret string
+}
+
+// Registry — реестр OAuth клиентов. Источник правды — таблица clients,
+// проверки идут по копии в памяти, которая перечитывается раз в reloadInterval.
+type Registry struct {
+	repo Repository
+
+	mu        sync.RWMutex
+	clients   map[string]*domain.Client
+	audiences map[string]struct{}
+	origins   map[string]struct{}
+}
+
+func New(repo Repository) *Registry {
+	return &Registry{
+		repo:      repo,
+		clients:   make(map[string]*domain.Client),
+		audiences: make(map[string]struct{}),
+		origins:   make(map[string]struct{}),
+	}
+}
+
+// Seed регистрирует клиентов из конфигурации и приводит к ней настройки уже зарегистрированных.
+// Хэш секрета пересчитывается, только если секрет в конфиге сменился: bcrypt каждый раз даёт новый хэш
+func (r *Registry) Seed(ctx context.Context, clients []ConfigClient) error {
+	for _, configClient := range clients {
+		client := configClient.Client
+		hash, err := r.secretHash(ctx, client.Id, configClient.Secret)
+		if err != nil {
+			return err
+		}
+		client.SecretHash = hash
+
+		created, updated, err := r.repo.UpsertClient(ctx, &client)
+		if err != nil {
+			return err
+		}
+		switch {
+		case created:
+			slog.Info("client registered from config", "client_id", client.Id)
+		case updated:
+			slog.Info("client updated from config", "client_id", client.Id)
+		}
+	}
+	return nil
+}
+
+// secretHash возвращает сохранённый хэш, если он подходит к секрету из конфига, иначе новый. Пустой секрет — публичный клиент
+func (r *Registry) secretHash(ctx context.Context, clientId, secret string) ([]byte, error) {
+	if secret == "" {
+		return nil, nil
+	}
+	stored, err := r.repo.GetClientSecretHash(ctx, clientId)
+	if err != nil {
+		return nil, err
+	}
+	current := domain.Client{SecretHash: stored}
+	if current.CheckSecret(secret) {
+		return stored, nil
+	}
+	hash, err := domain.HashClientSecret(secret)
+	if err != nil {
+		return nil, fmt.Errorf("hash secret of client %q: %w", clientId, err)
+	}
+	return hash, nil
+}
+
+// Reload перечитывает реестр из БД
+func (r *Registry) Reload(ctx context.Context) error {
+	clients, err := r.repo.ListActiveClients(ctx)
+	if err != nil {
+		return err
+	}
+
+	byId := make(map[string]*domain.Client, len(clients))
+	audiences := make(map[string]struct{})
+	origins := make(map[string]struct{})
+	for i := range clients {
+		client := &clients[i]
+		byId[client.Id] = client
+		for _, aud := range client.Audience {
+			audiences[aud] = struct{}{}
+		}
+		for _, origin := range client.AllowedOrigins {
+			origins[origin] = struct{}{}
+		}
+	}
+
+	r.mu.Lock()
+	r.clients = byId
+	r.audiences = audiences
+	r.origins = origins
+	r.mu.Unlock()
+	return nil
+}
+
+// Run перечитывает реестр раз в reloadInterval. Блокируется до отмены ctx
+func (r *Registry) Run(ctx context.Context, reloadInterval time.Duration) error {
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.Reload(ctx); err != nil {
+				slog.Error("failed to reload clients", "err", err)
+			}
+		}
+	}
+}
+
+// GetClient возвращает клиента или nil, если он не зарегистрирован или отключён
+func (r *Registry) GetClient(ctx context.Context, clientId string) (*domain.Client, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.clients[clientId], nil
+}
+
+// Authenticate проверяет учётные данные конфиденциального клиента
+func (r *Registry) Authenticate(ctx context.Context, clientId, clientSecret string) (*domain.Client, error) {
+	client, _ := r.GetClient(ctx, clientId)
+	if client == nil {
+		return nil, clientErrors.ErrUnknownClient
+	}
+	if !client.CheckSecret(clientSecret) {
+		return nil, clientErrors.ErrInvalidClientSecret
+	}
+	return client, nil
+}
+
+// IsKnownAudience — выпускаются ли токены для aud хотя бы одному клиенту
+func (r *Registry) IsKnownAudience(aud string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.audiences[aud]
+	return ok
+}
+
+// IsAllowedOrigin — зарегистрирован ли Origin хотя бы у одного клиента, используется для CORS
+func (r *Registry) IsAllowedOrigin(origin string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.origins[origin]
+	return ok
+}
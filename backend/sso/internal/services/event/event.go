@@ -0,0 +1,55 @@
+package event
+
+import (
+	"context"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	eventModels "github.com/EtoNeAnanasbI95/sso/internal/dto/event"
+)
+
+type Repository interface {
+	RecordEvent(ctx context.Context, event *domain.AuthEvent) error
+	ListEvents(ctx context.Context, userId int64, limit int) ([]domain.AuthEvent, error)
+}
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 500
+)
+
+// Events — журнал событий аутентификации пользователей
+type Events struct {
+	repo Repository
+}
+
+func New(repo Repository) *Events {
+	return &Events{repo: repo}
+}
+
+func (e *Events) Record(ctx context.Context, event *domain.AuthEvent) error {
+	return e.repo.RecordEvent(ctx, event)
+}
+
+// List возвращает последние события пользователя. limit вне 1..500 заменяется на 100
+func (e *Events) List(ctx context.Context, userId int64, limit int) ([]eventModels.AuthEventResponse, error) {
+	if limit <= 0 || limit > maxListLimit {
+		limit = defaultListLimit
+	}
+	events, err := e.repo.ListEvents(ctx, userId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]eventModels.AuthEventResponse, 0, len(events))
+	for _, event := range events {
+		result = append(result, eventModels.AuthEventResponse{
+			ID:        event.Id,
+			Type:      event.Type,
+			ClientID:  event.ClientId,
+			UserAgent: event.UserAgent,
+			IpAddress: event.IpAddress,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+	return result, nil
+}
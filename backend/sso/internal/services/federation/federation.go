@@ -0,0 +1,313 @@
+package federation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/auth"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/federation"
+	authErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/auth"
+	clientErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/client"
+	federationErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/federation"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/upstream"
+)
+
+type Repository interface {
+	CreateUpstreamLogin(ctx context.Context, login *domain.UpstreamLogin) error
+	ConsumeUpstreamLogin(ctx context.Context, stateHash []byte) (*domain.UpstreamLogin, error)
+	DeleteExpiredUpstreamLogins(ctx context.Context) error
+	GetIdentity(ctx context.Context, provider, subject string) (*domain.UserIdentity, error)
+	LinkIdentity(ctx context.Context, identity *domain.UserIdentity) error
+	CreateUserWithIdentity(ctx context.Context, user *domain.User, identity *domain.UserIdentity) (int64, error)
+}
+
+type Users interface {
+	GetUserByLogin(ctx context.Context, login string) (*domain.User, error)
+	GetUserWithId(ctx context.Context, uid int64) (*domain.User, error)
+}
+
+// Sessions — завершение входа после первого фактора, реализуется сервисом auth. Второй фактор
+// запрашивается так же, как после пароля
+type Sessions interface {
+	CompleteFirstFactor(ctx context.Context, user *domain.User, client *domain.Client, amr []string, meta domain.SessionMeta) (*auth.AuthResponse, error)
+}
+
+type Clients interface {
+	GetClient(ctx context.Context, clientId string) (*domain.Client, error)
+}
+
+// Provider — внешний провайдер и правила, по которым его пользователи становятся локальными
+type Provider struct {
+	ID        string
+	Name      string
+	Connector upstream.Connector
+	// AutoCreate — создать локального пользователя при первом входе
+	AutoCreate bool
+	// LinkByLogin — связать с существующим пользователем с тем же логином, если провайдер подтвердил логин
+	LinkByLogin bool
+	// RoleID — роль создаваемых пользователей; 0 — роль по умолчанию
+	RoleID int64
+}
+
+// Options — параметры сервиса
+type Options struct {
+	// Issuer — адрес SSO; return_to на него разрешён любому клиенту
+	Issuer        string
+	DefaultClient string
+	// LoginTTL — сколько ждать возвращения пользователя от провайдера
+	LoginTTL time.Duration
+}
+
+// Service — вход через внешних провайдеров: редирект к провайдеру, callback, связывание
+// учётной записи провайдера с локальным пользователем и выпуск обычных токенов SSO
+type Service struct {
+	repo      Repository
+	users     Users
+	sessions  Sessions
+	clients   Clients
+	providers []Provider
+	passwords domain.PasswordHasher
+	opts      Options
+}
+
+func New(repo Repository, users Users, sessions Sessions, clients Clients, providers []Provider, passwords domain.PasswordHasher, opts Options) *Service {
+	return &Service{
+		repo:      repo,
+		users:     users,
+		sessions:  sessions,
+		clients:   clients,
+		providers: providers,
+		passwords: passwords,
+		opts:      opts,
+	}
+}
+
+// Providers возвращает провайдеров в порядке конфигурации
+func (s *Service) Providers() []federation.ProviderResponse {
+	result := make([]federation.ProviderResponse, 0, len(s.providers))
+	for _, p := range s.providers {
+		result = append(result, federation.ProviderResponse{
+			ID:       p.ID,
+			Name:     p.Name,
+			LoginURL: "/auth/external/" + url.PathEscape(p.ID) + "/login",
+		})
+	}
+	return result
+}
+
+// Login начинает вход через провайдера и возвращает адрес провайдера и state,
+// который нужно привязать к браузеру до callback
+func (s *Service) Login(ctx context.Context, providerId, clientId, returnTo string) (string, string, error) {
+	provider, ok := s.provider(providerId)
+	if !ok {
+		return "", "", federationErrors.ErrUnknownProvider
+	}
+	client, err := s.resolveClient(ctx, clientId)
+	if err != nil {
+		return "", "", err
+	}
+	if !s.allowsReturnTo(client, returnTo) {
+		return "", "", federationErrors.ErrInvalidReturnTo
+	}
+
+	state, login, err := domain.NewUpstreamLogin(provider.ID, client.Id, returnTo, s.opts.LoginTTL)
+	if err != nil {
+		return "", "", err
+	}
+	location, err := provider.Connector.AuthCodeURL(ctx, state, login.Nonce, login.CodeChallenge())
+	if err != nil {
+		slog.Error("failed to build upstream authorization url", "provider", provider.ID, "err", err)
+		return "", "", fmt.Errorf("%w: %v", federationErrors.ErrUpstreamFailed, err)
+	}
+	if err := s.repo.CreateUpstreamLogin(ctx, login); err != nil {
+		return "", "", err
+	}
+	return location, state, nil
+}
+
+// Callback завершает вход: обменивает код у провайдера, находит или создаёт локального пользователя
+// и начинает сессию для клиента, с которым был начат вход. Возвращает токены (или запрос второго фактора) и return_to
+func (s *Service) Callback(ctx context.Context, providerId, state, code string, meta domain.SessionMeta) (*auth.AuthResponse, string, error) {
+	provider, ok := s.provider(providerId)
+	if !ok {
+		return nil, "", federationErrors.ErrUnknownProvider
+	}
+
+	// state одноразовый: он считается использованным даже если обмен кода не удастся
+	login, err := s.repo.ConsumeUpstreamLogin(ctx, domain.HashUpstreamState(state))
+	if err != nil {
+		return nil, "", err
+	}
+	if login == nil || login.Provider != provider.ID || login.IsExpired(time.Now()) {
+		return nil, "", federationErrors.ErrInvalidState
+	}
+
+	identity, err := provider.Connector.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		slog.Warn("upstream login failed", "provider", provider.ID, "err", err)
+		return nil, "", fmt.Errorf("%w: %v", federationErrors.ErrUpstreamFailed, err)
+	}
+
+	user, err := s.resolveUser(ctx, provider, identity)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client, err := s.clients.GetClient(ctx, login.ClientId)
+	if err != nil {
+		return nil, "", err
+	}
+	if client == nil {
+		return nil, "", clientErrors.ErrUnknownClient
+	}
+	result, err := s.sessions.CompleteFirstFactor(ctx, user, client, []string{domain.AmrFederated}, meta)
+	if err != nil {
+		return nil, "", err
+	}
+	return result, login.ReturnTo, nil
+}
+
+// Run раз в interval удаляет просроченные незавершённые входы. Блокируется до отмены ctx
+func (s *Service) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.repo.DeleteExpiredUpstreamLogins(ctx); err != nil {
+				slog.Error("failed to delete expired upstream logins", "err", err)
+			}
+		}
+	}
+}
+
+// resolveUser находит пользователя, связанного с учётной записью провайдера. Без связи —
+// связывает с пользователем с тем же логином или создаёт нового, если это разрешено провайдеру
+func (s *Service) resolveUser(ctx context.Context, provider Provider, identity *upstream.Identity) (*domain.User, error) {
+	linked, err := s.repo.GetIdentity(ctx, provider.ID, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		user, err := s.users.GetUserWithId(ctx, linked.UserId)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil || user.IsArchived {
+			return nil, authErrors.ErrUserNotFound
+		}
+		if err := s.repo.LinkIdentity(ctx, domain.NewUserIdentity(provider.ID, identity.Subject, user.Id, identity.Login)); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	if identity.Login == "" {
+		return nil, federationErrors.ErrMissingLogin
+	}
+	existing, err := s.users.GetUserByLogin(ctx, identity.Login)
+	if err != nil {
+		return nil, err
+	}
+
+	// логину без подтверждения провайдера верить нельзя: иначе любой, кто заведёт у провайдера
+	// учётную запись с чужим логином, войдёт под локальным пользователем
+	if existing != nil && existing.Login == identity.Login && provider.LinkByLogin && identity.Verified {
+		if err := s.repo.LinkIdentity(ctx, domain.NewUserIdentity(provider.ID, identity.Subject, existing.Id, identity.Login)); err != nil {
+			return nil, err
+		}
+		slog.Info("upstream identity linked by login", "provider", provider.ID, "user_id", existing.Id)
+		return existing, nil
+	}
+	if !provider.AutoCreate {
+		return nil, federationErrors.ErrIdentityNotLinked
+	}
+	if existing != nil {
+		return nil, federationErrors.ErrLoginTaken
+	}
+
+	// локальный пароль не нужен, но столбец обязателен: пароль случайный и никому не известен
+	password, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+	var roleId *int64
+	if provider.RoleID != 0 {
+		roleId = &provider.RoleID
+	}
+	user, err := domain.NewUser(s.passwords, identity.Login, "", password, identity.FullName, nil, roleId, nil)
+	if err != nil {
+		return nil, err
+	}
+	userId, err := s.repo.CreateUserWithIdentity(ctx, user, domain.NewUserIdentity(provider.ID, identity.Subject, 0, identity.Login))
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("user created from upstream identity", "provider", provider.ID, "user_id", userId)
+	return s.users.GetUserWithId(ctx, userId)
+}
+
+// resolveClient находит клиента входа. Внешний провайдер заменяет пароль,
+// поэтому клиенту нужен тот же grant, что и для /auth/logIn
+func (s *Service) resolveClient(ctx context.Context, clientId string) (*domain.Client, error) {
+	if clientId == "" {
+		clientId = s.opts.DefaultClient
+	}
+	client, err := s.clients.GetClient(ctx, clientId)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, clientErrors.ErrUnknownClient
+	}
+	if !client.AllowsGrant(domain.GrantTypePassword) {
+		return nil, clientErrors.ErrGrantNotAllowed
+	}
+	return client, nil
+}
+
+// allowsReturnTo разрешает путь на самом SSO, адрес SSO и Origin, зарегистрированные у клиента
+func (s *Service) allowsReturnTo(client *domain.Client, returnTo string) bool {
+	if returnTo == "" {
+		return true
+	}
+	if strings.HasPrefix(returnTo, "/") && !strings.HasPrefix(returnTo, "//") && !strings.HasPrefix(returnTo, "/\\") {
+		return true
+	}
+	u, err := url.Parse(returnTo)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	origin := u.Scheme + "://" + u.Host
+	if issuer, err := url.Parse(s.opts.Issuer); err == nil && issuer.Host != "" && origin == issuer.Scheme+"://"+issuer.Host {
+		return true
+	}
+	return client.AllowsOrigin(origin)
+}
+
+func (s *Service) provider(id string) (Provider, bool) {
+	for _, p := range s.providers {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Provider{}, false
+}
+
+func randomPassword() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate password: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
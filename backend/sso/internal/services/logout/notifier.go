@@ -0,0 +1,170 @@
+package logout
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	libjwt "github.com/EtoNeAnanasbI95/sso/internal/lib/jwt"
+)
+
+// Lease — сколько уведомление считается занятым одним инстансом. Уведомления пачки отправляются параллельно,
+// поэтому аренда должна покрывать один запрос к клиенту: таймаут запроса должен быть меньше
+const Lease = time.Minute
+
+const (
+	batchSize = 50
+	// retention — сколько хранить доставленные и брошенные уведомления
+	retention = 7 * 24 * time.Hour
+
+	retryBase = 30 * time.Second
+	retryMax  = time.Hour
+)
+
+type Repository interface {
+	ClaimDueNotifications(ctx context.Context, limit int, lease time.Duration) ([]domain.LogoutNotification, error)
+	MarkDelivered(ctx context.Context, id int64) error
+	Reschedule(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error
+	MarkFailed(ctx context.Context, id int64, lastError string) error
+	DeleteFinished(ctx context.Context, before time.Time) error
+}
+
+type Jwt interface {
+	NewLogoutToken(params libjwt.LogoutTokenParams) (string, error)
+}
+
+// Notifier доставляет клиентам logout токены из очереди logout_notifications (OIDC Back-Channel Logout).
+// Неудачная отправка повторяется с экспоненциальной задержкой, после maxAttempts попыток уведомление бросается
+type Notifier struct {
+	repo        Repository
+	jwt         Jwt
+	client      *http.Client
+	maxAttempts int
+}
+
+func New(repo Repository, jwt Jwt, timeout time.Duration, maxAttempts int) *Notifier {
+	return &Notifier{
+		repo:        repo,
+		jwt:         jwt,
+		client:      &http.Client{Timeout: timeout},
+		maxAttempts: maxAttempts,
+	}
+}
+
+// Run раз в interval отправляет накопившиеся уведомления. Блокируется до отмены ctx
+func (n *Notifier) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := n.deliverDue(ctx); err != nil {
+				slog.Error("failed to deliver logout notifications", "err", err)
+			}
+			if time.Since(lastCleanup) >= time.Hour {
+				lastCleanup = time.Now()
+				if err := n.repo.DeleteFinished(ctx, time.Now().Add(-retention)); err != nil {
+					slog.Error("failed to delete finished logout notifications", "err", err)
+				}
+			}
+		}
+	}
+}
+
+func (n *Notifier) deliverDue(ctx context.Context) error {
+	for {
+		notifications, err := n.repo.ClaimDueNotifications(ctx, batchSize, Lease)
+		if err != nil {
+			return err
+		}
+		// по очереди последние уведомления пачки отправлялись бы уже после конца аренды,
+		// и другой инстанс забрал бы их повторно
+		var wg sync.WaitGroup
+		for i := range notifications {
+			wg.Add(1)
+			go func(notification *domain.LogoutNotification) {
+				defer wg.Done()
+				n.deliver(ctx, notification)
+			}(&notifications[i])
+		}
+		wg.Wait()
+		if len(notifications) < batchSize {
+			return nil
+		}
+	}
+}
+
+func (n *Notifier) deliver(ctx context.Context, notification *domain.LogoutNotification) {
+	err := n.send(ctx, notification)
+	if err == nil {
+		if err := n.repo.MarkDelivered(ctx, notification.Id); err != nil {
+			slog.Error("failed to mark logout notification delivered", "id", notification.Id, "err", err)
+		}
+		return
+	}
+
+	if notification.Attempts >= n.maxAttempts {
+		slog.Warn("giving up on logout notification", "id", notification.Id, "client_id", notification.ClientId, "attempts", notification.Attempts, "err", err)
+		if err := n.repo.MarkFailed(ctx, notification.Id, err.Error()); err != nil {
+			slog.Error("failed to mark logout notification failed", "id", notification.Id, "err", err)
+		}
+		return
+	}
+
+	slog.Info("logout notification failed, will retry", "id", notification.Id, "client_id", notification.ClientId, "attempts", notification.Attempts, "err", err)
+	if err := n.repo.Reschedule(ctx, notification.Id, err.Error(), time.Now().Add(retryDelay(notification.Attempts))); err != nil {
+		slog.Error("failed to reschedule logout notification", "id", notification.Id, "err", err)
+	}
+}
+
+// send отправляет logout токен методом POST в форме, как требует раздел 2.5 спецификации
+func (n *Notifier) send(ctx context.Context, notification *domain.LogoutNotification) error {
+	token, err := n.jwt.NewLogoutToken(libjwt.LogoutTokenParams{
+		UserID:    notification.UserId,
+		ClientID:  notification.ClientId,
+		SessionID: notification.SessionId,
+	})
+	if err != nil {
+		return fmt.Errorf("sign logout token: %w", err)
+	}
+
+	body := url.Values{"logout_token": {token}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, notification.LogoutUri, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Cache-Control", "no-store")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("client responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// retryDelay — 30s, 1m, 2m, ... но не больше часа
+func retryDelay(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts && delay < retryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMax)
+}
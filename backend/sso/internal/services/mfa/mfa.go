@@ -0,0 +1,523 @@
+package mfa
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/mfa"
+	webauthnModels "github.com/EtoNeAnanasbI95/sso/internal/dto/webauthn"
+	authErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/auth"
+	mfaErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/mfa"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/secretbox"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/totp"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/webauthn"
+)
+
+type Repository interface {
+	GetTOTP(ctx context.Context, userId int64) (*domain.UserTOTP, error)
+	SaveTOTP(ctx context.Context, totp *domain.UserTOTP) (bool, error)
+	UseTOTPStep(ctx context.Context, userId int64, step int64) (bool, error)
+	DeleteTOTP(ctx context.Context, userId int64) error
+	CreateChallenge(ctx context.Context, challenge *domain.MfaChallenge) error
+	GetChallenge(ctx context.Context, tokenHash []byte, maxAttempts int) (*domain.MfaChallenge, error)
+	AttemptChallenge(ctx context.Context, tokenHash []byte, maxAttempts int) (*domain.MfaChallenge, error)
+	ConsumeChallenge(ctx context.Context, id int64) (bool, error)
+	DeleteExpiredChallenges(ctx context.Context) error
+	ReplaceRecoveryCodes(ctx context.Context, userId int64, codes []domain.RecoveryCode) error
+	ListUnusedRecoveryCodes(ctx context.Context, userId int64) ([]domain.RecoveryCode, error)
+	UseRecoveryCode(ctx context.Context, id int64) (bool, error)
+}
+
+type Users interface {
+	GetUserWithId(ctx context.Context, uid int64) (*domain.User, error)
+}
+
+// Events — журнал событий аутентификации
+type Events interface {
+	Record(ctx context.Context, event *domain.AuthEvent) error
+}
+
+// Passkeys — ключи WebAuthn как второй фактор
+type Passkeys interface {
+	HasCredentials(ctx context.Context, userId int64) (bool, error)
+	BeginSecondFactor(ctx context.Context, userId int64) (*webauthn.RequestOptions, error)
+	VerifySecondFactor(ctx context.Context, userId int64, response webauthn.AssertionResponse) error
+	FinishRegistration(ctx context.Context, userId int64, request webauthnModels.RegisterRequest, meta domain.SessionMeta) (*webauthnModels.CredentialResponse, error)
+}
+
+// Способы пройти второй шаг входа
+const (
+	MethodTOTP         = "totp"
+	MethodWebAuthn     = "webauthn"
+	MethodRecoveryCode = "recovery_code"
+)
+
+// Options — параметры второго фактора
+type Options struct {
+	// Issuer — подпись учётной записи в приложении-аутентификаторе
+	Issuer       string
+	ChallengeTTL time.Duration
+	// MaxAttempts — сколько кодов можно ввести в одном входе, дальше нужно снова ввести пароль
+	MaxAttempts int
+}
+
+// Service — второй фактор TOTP (RFC 6238), ключи WebAuthn и коды восстановления: подключение TOTP
+// и второй шаг входа.
+// box == nil означает, что ключ шифрования не задан и подключить фактор нельзя
+type Service struct {
+	repo     Repository
+	users    Users
+	events   Events
+	passkeys Passkeys
+	box      *secretbox.Box
+	opts     Options
+}
+
+func New(repo Repository, users Users, events Events, passkeys Passkeys, box *secretbox.Box, opts Options) *Service {
+	return &Service{
+		repo:     repo,
+		users:    users,
+		events:   events,
+		passkeys: passkeys,
+		box:      box,
+		opts:     opts,
+	}
+}
+
+// EnrollTOTP создаёт новый секрет. Фактор начнёт действовать после подтверждения первым кодом;
+// повторный вызов до подтверждения заменяет секрет
+func (s *Service) EnrollTOTP(ctx context.Context, userId int64) (*mfa.TOTPEnrollmentResponse, error) {
+	if s.box == nil {
+		return nil, mfaErrors.ErrNotConfigured
+	}
+	user, err := s.users.GetUserWithId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, authErrors.ErrUserNotFound
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.box.Seal(secret, secretAAD(userId))
+	if err != nil {
+		return nil, err
+	}
+	saved, err := s.repo.SaveTOTP(ctx, &domain.UserTOTP{
+		UserId:          userId,
+		SecretEncrypted: sealed,
+		CreatedAt:       time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, mfaErrors.ErrAlreadyEnrolled
+	}
+
+	return &mfa.TOTPEnrollmentResponse{
+		Secret: totp.EncodeSecret(secret),
+		URI:    totp.URI(s.opts.Issuer, user.Login, secret),
+	}, nil
+}
+
+// ConfirmTOTP включает фактор, если код из приложения верен, и выдаёт первый набор кодов восстановления
+func (s *Service) ConfirmTOTP(ctx context.Context, userId int64, code string, meta domain.SessionMeta) (*mfa.RecoveryCodesResponse, error) {
+	factor, err := s.repo.GetTOTP(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if factor == nil {
+		return nil, mfaErrors.ErrNotEnrolled
+	}
+	if factor.IsConfirmed() {
+		return nil, mfaErrors.ErrAlreadyEnrolled
+	}
+	if err := s.verifyTOTP(ctx, factor, code); err != nil {
+		return nil, err
+	}
+	if err := s.events.Record(ctx, domain.NewAuthEvent(userId, domain.AuthEventTOTPEnabled, "", meta)); err != nil {
+		return nil, err
+	}
+	slog.Info("totp enrolled", "user_id", userId)
+	return s.issueRecoveryCodes(ctx, userId)
+}
+
+// RegisterPasskey сохраняет новый ключ WebAuthn. Если это первый второй фактор пользователя, выдаёт и первый набор
+// кодов восстановления, как подтверждение TOTP: без них потеря единственного ключа закрыла бы вход
+func (s *Service) RegisterPasskey(ctx context.Context, userId int64, request webauthnModels.RegisterRequest, meta domain.SessionMeta) (*webauthnModels.RegisterResponse, error) {
+	factor, err := s.confirmedTOTP(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	hasPasskeys, err := s.passkeys.HasCredentials(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	credential, err := s.passkeys.FinishRegistration(ctx, userId, request, meta)
+	if err != nil {
+		return nil, err
+	}
+	response := &webauthnModels.RegisterResponse{CredentialResponse: *credential}
+	if factor != nil || hasPasskeys {
+		return response, nil
+	}
+	codes, err := s.issueRecoveryCodes(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	response.RecoveryCodes = codes.RecoveryCodes
+	return response, nil
+}
+
+// ProofOptions начинает проверку ключа WebAuthn вошедшего пользователя перед выпуском новых кодов восстановления
+// или отключением TOTP
+func (s *Service) ProofOptions(ctx context.Context, userId int64) (*webauthn.RequestOptions, error) {
+	return s.passkeys.BeginSecondFactor(ctx, userId)
+}
+
+// RegenerateRecoveryCodes выдаёт новый набор кодов восстановления взамен прежнего. Нужно подтверждение вторым
+// фактором — кодом из приложения, ключом WebAuthn или кодом восстановления, иначе украденный access токен позволил
+// бы его обойти
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userId int64, proof mfa.FactorProofRequest, meta domain.SessionMeta) (*mfa.RecoveryCodesResponse, error) {
+	factor, err := s.confirmedTOTP(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	hasPasskeys, err := s.passkeys.HasCredentials(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if factor == nil && !hasPasskeys {
+		return nil, mfaErrors.ErrNotEnrolled
+	}
+	if err := s.verifyProof(ctx, userId, factor, proof, meta); err != nil {
+		return nil, err
+	}
+	codes, err := s.issueRecoveryCodes(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.events.Record(ctx, domain.NewAuthEvent(userId, domain.AuthEventRecoveryCodesRegenerated, "", meta)); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP отключает фактор. Нужно подтверждение вторым фактором, чтобы украденный access токен не снял защиту.
+// Коды восстановления удаляются, если у пользователя не осталось ключей WebAuthn
+func (s *Service) DisableTOTP(ctx context.Context, userId int64, proof mfa.FactorProofRequest, meta domain.SessionMeta) error {
+	factor, err := s.confirmedTOTP(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if factor == nil {
+		return mfaErrors.ErrNotEnrolled
+	}
+	if err := s.verifyProof(ctx, userId, factor, proof, meta); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteTOTP(ctx, userId); err != nil {
+		return err
+	}
+	hasPasskeys, err := s.passkeys.HasCredentials(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !hasPasskeys {
+		if err := s.repo.ReplaceRecoveryCodes(ctx, userId, nil); err != nil {
+			return err
+		}
+	}
+	if err := s.events.Record(ctx, domain.NewAuthEvent(userId, domain.AuthEventTOTPDisabled, "", meta)); err != nil {
+		return err
+	}
+	slog.Info("totp disabled", "user_id", userId)
+	return nil
+}
+
+// ResetMFA по запросу администратора adminId отключает TOTP пользователя вместе с кодами восстановления —
+// для тех, кто потерял и телефон, и коды. Ключи WebAuthn не затрагиваются
+func (s *Service) ResetMFA(ctx context.Context, userId int64, adminId int64, meta domain.SessionMeta) error {
+	user, err := s.users.GetUserWithId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return authErrors.ErrUserNotFound
+	}
+	factor, err := s.repo.GetTOTP(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if factor == nil {
+		return mfaErrors.ErrNotEnrolled
+	}
+	if err := s.repo.DeleteTOTP(ctx, userId); err != nil {
+		return err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userId, nil); err != nil {
+		return err
+	}
+	if err := s.events.Record(ctx, domain.NewAuthEvent(userId, domain.AuthEventMFAReset, "", meta)); err != nil {
+		return err
+	}
+	slog.Info("mfa reset by admin", "admin_id", adminId, "user_id", userId)
+	return nil
+}
+
+// Methods возвращает способы второго шага входа, доступные пользователю. Пустой список — второй фактор не включён
+func (s *Service) Methods(ctx context.Context, userId int64) ([]string, error) {
+	methods := make([]string, 0, 3)
+	factor, err := s.repo.GetTOTP(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if factor != nil && factor.IsConfirmed() {
+		methods = append(methods, MethodTOTP)
+	}
+	hasPasskeys, err := s.passkeys.HasCredentials(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if hasPasskeys {
+		methods = append(methods, MethodWebAuthn)
+	}
+	if len(methods) == 0 {
+		return methods, nil
+	}
+	codes, err := s.repo.ListUnusedRecoveryCodes(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if len(codes) > 0 {
+		methods = append(methods, MethodRecoveryCode)
+	}
+	return methods, nil
+}
+
+// NewChallenge начинает второй шаг входа пользователя, уже прошедшего первый способами amr, и возвращает его токен
+func (s *Service) NewChallenge(ctx context.Context, userId int64, clientId string, amr []string) (string, error) {
+	token, challenge, err := domain.NewMfaChallenge(userId, clientId, amr, s.opts.ChallengeTTL)
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.CreateChallenge(ctx, challenge); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CompleteChallenge проверяет код второго шага входа и завершает вход. Каждый вызов тратит попытку
+func (s *Service) CompleteChallenge(ctx context.Context, token, code string) (*domain.MfaChallenge, error) {
+	challenge, err := s.repo.AttemptChallenge(ctx, domain.HashMfaToken(token), s.opts.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil {
+		return nil, mfaErrors.ErrInvalidChallenge
+	}
+
+	factor, err := s.repo.GetTOTP(ctx, challenge.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if factor == nil || !factor.IsConfirmed() {
+		return nil, mfaErrors.ErrNotEnrolled
+	}
+	if err := s.verifyTOTP(ctx, factor, code); err != nil {
+		return nil, err
+	}
+
+	consumed, err := s.repo.ConsumeChallenge(ctx, challenge.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, mfaErrors.ErrInvalidChallenge
+	}
+	return challenge, nil
+}
+
+// WebAuthnOptions начинает проверку ключа WebAuthn на втором шаге входа. Попытка не тратится:
+// она засчитывается, когда приходит ответ ключа
+func (s *Service) WebAuthnOptions(ctx context.Context, token string) (*webauthn.RequestOptions, error) {
+	challenge, err := s.repo.GetChallenge(ctx, domain.HashMfaToken(token), s.opts.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil {
+		return nil, mfaErrors.ErrInvalidChallenge
+	}
+	return s.passkeys.BeginSecondFactor(ctx, challenge.UserId)
+}
+
+// CompleteChallengeWithWebAuthn завершает вход ответом ключа WebAuthn вместо кода из приложения
+func (s *Service) CompleteChallengeWithWebAuthn(ctx context.Context, token string, response webauthn.AssertionResponse) (*domain.MfaChallenge, error) {
+	challenge, err := s.repo.AttemptChallenge(ctx, domain.HashMfaToken(token), s.opts.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil {
+		return nil, mfaErrors.ErrInvalidChallenge
+	}
+	if err := s.passkeys.VerifySecondFactor(ctx, challenge.UserId, response); err != nil {
+		return nil, err
+	}
+
+	consumed, err := s.repo.ConsumeChallenge(ctx, challenge.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, mfaErrors.ErrInvalidChallenge
+	}
+	return challenge, nil
+}
+
+// CompleteChallengeWithRecoveryCode завершает вход кодом восстановления вместо кода из приложения.
+// Код сгорает, использование записывается в журнал событий
+func (s *Service) CompleteChallengeWithRecoveryCode(ctx context.Context, token, recoveryCode string, meta domain.SessionMeta) (*domain.MfaChallenge, error) {
+	challenge, err := s.repo.AttemptChallenge(ctx, domain.HashMfaToken(token), s.opts.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil {
+		return nil, mfaErrors.ErrInvalidChallenge
+	}
+
+	if err := s.useRecoveryCode(ctx, challenge.UserId, recoveryCode, challenge.ClientId, meta); err != nil {
+		return nil, err
+	}
+
+	consumed, err := s.repo.ConsumeChallenge(ctx, challenge.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, mfaErrors.ErrInvalidChallenge
+	}
+	return challenge, nil
+}
+
+// Run раз в interval удаляет просроченные входы. Блокируется до отмены ctx
+func (s *Service) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.repo.DeleteExpiredChallenges(ctx); err != nil {
+				slog.Error("failed to delete expired mfa challenges", "err", err)
+			}
+		}
+	}
+}
+
+// issueRecoveryCodes заменяет коды восстановления пользователя новым набором
+func (s *Service) issueRecoveryCodes(ctx context.Context, userId int64) (*mfa.RecoveryCodesResponse, error) {
+	codes, records, err := domain.NewRecoveryCodes(userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userId, records); err != nil {
+		return nil, err
+	}
+	return &mfa.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// useRecoveryCode сжигает неиспользованный код восстановления пользователя и записывает это в журнал событий
+func (s *Service) useRecoveryCode(ctx context.Context, userId int64, recoveryCode, clientId string, meta domain.SessionMeta) error {
+	codes, err := s.repo.ListUnusedRecoveryCodes(ctx, userId)
+	if err != nil {
+		return err
+	}
+	var matched *domain.RecoveryCode
+	for i := range codes {
+		if codes[i].Matches(recoveryCode) {
+			matched = &codes[i]
+			break
+		}
+	}
+	if matched == nil {
+		return mfaErrors.ErrInvalidRecoveryCode
+	}
+	used, err := s.repo.UseRecoveryCode(ctx, matched.Id)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return mfaErrors.ErrInvalidRecoveryCode
+	}
+	if err := s.events.Record(ctx, domain.NewAuthEvent(userId, domain.AuthEventRecoveryCodeUsed, clientId, meta)); err != nil {
+		return err
+	}
+	slog.Info("recovery code used", "user_id", userId, "remaining", len(codes)-1)
+	return nil
+}
+
+// confirmedTOTP возвращает подтверждённый TOTP пользователя или nil
+func (s *Service) confirmedTOTP(ctx context.Context, userId int64) (*domain.UserTOTP, error) {
+	factor, err := s.repo.GetTOTP(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if factor == nil || !factor.IsConfirmed() {
+		return nil, nil
+	}
+	return factor, nil
+}
+
+// verifyProof проверяет подтверждение вторым фактором: код из приложения (factor — подтверждённый TOTP или nil),
+// ответ ключа WebAuthn или код восстановления
+func (s *Service) verifyProof(ctx context.Context, userId int64, factor *domain.UserTOTP, proof mfa.FactorProofRequest, meta domain.SessionMeta) error {
+	switch {
+	case proof.Code != "":
+		if factor == nil {
+			return mfaErrors.ErrInvalidCode
+		}
+		return s.verifyTOTP(ctx, factor, proof.Code)
+	case proof.WebAuthn != nil:
+		return s.passkeys.VerifySecondFactor(ctx, userId, *proof.WebAuthn)
+	case proof.RecoveryCode != "":
+		return s.useRecoveryCode(ctx, userId, proof.RecoveryCode, "", meta)
+	}
+	return mfaErrors.ErrInvalidCode
+}
+
+// verifyTOTP проверяет код и запоминает его шаг, чтобы тот же код нельзя было предъявить повторно
+func (s *Service) verifyTOTP(ctx context.Context, factor *domain.UserTOTP, code string) error {
+	if s.box == nil {
+		return mfaErrors.ErrNotConfigured
+	}
+	secret, err := s.box.Open(factor.SecretEncrypted, secretAAD(factor.UserId))
+	if err != nil {
+		return err
+	}
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return mfaErrors.ErrInvalidCode
+	}
+	used, err := s.repo.UseTOTPStep(ctx, factor.UserId, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return mfaErrors.ErrInvalidCode
+	}
+	return nil
+}
+
+// secretAAD привязывает зашифрованный секрет к пользователю
+func secretAAD(userId int64) []byte {
+	return []byte("totp:" + strconv.FormatInt(userId, 10))
+}
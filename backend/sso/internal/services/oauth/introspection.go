@@ -0,0 +1,175 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/oauth"
+	oauthErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/oauth"
+	libjwt "github.com/EtoNeAnanasbI95/sso/internal/lib/jwt"
+)
+
+const (
+	TokenTypeHintAccess  = "access_token"
+	TokenTypeHintRefresh = "refresh_token"
+)
+
+type Jwt interface {
+	ParseToken(tokenString string, expectedType libjwt.TokenType) (*libjwt.TokenClaims, error)
+}
+
+type Repository interface {
+	GetUserWithId(ctx context.Context, uid int64) (*domain.User, error)
+	GetRefreshTokenByHash(ctx context.Context, hash []byte) (*domain.RefreshToken, error)
+}
+
+type Revocations interface {
+	IsRevoked(jti string, userId int64, issuedAt time.Time) bool
+	IsOutdated(userId int64, tokenVersion int64) bool
+}
+
+type Clients interface {
+	GetClient(ctx context.Context, clientId string) (*domain.Client, error)
+	Authenticate(ctx context.Context, clientId, clientSecret string) (*domain.Client, error)
+}
+
+// Introspection отвечает ресурсным серверам, действителен ли токен и кому он принадлежит (RFC 7662)
+type Introspection struct {
+	repo        Repository
+	jwt         Jwt
+	revocations Revocations
+	clients     Clients
+}
+
+func NewIntrospection(repo Repository, jwt Jwt, revocations Revocations, clients Clients) *Introspection {
+	return &Introspection{
+		repo:        repo,
+		jwt:         jwt,
+		revocations: revocations,
+		clients:     clients,
+	}
+}
+
+// AuthenticateClient проверяет учётные данные сервиса, который спрашивает про токен.
+// Спрашивать может любой конфиденциальный клиент из реестра
+func (i *Introspection) AuthenticateClient(ctx context.Context, clientId, clientSecret string) error {
+	if clientId == "" || clientSecret == "" {
+		return oauthErrors.ErrInvalidClient
+	}
+	if _, err := i.clients.Authenticate(ctx, clientId, clientSecret); err != nil {
+		return oauthErrors.ErrInvalidClient
+	}
+	return nil
+}
+
+// Introspect возвращает {active: false} для любого недействительного токена, не объясняя причину
+func (i *Introspection) Introspect(ctx context.Context, token, tokenTypeHint string) (*oauth.IntrospectionResponse, error) {
+	const op = "Introspection.Introspect"
+	inactive := &oauth.IntrospectionResponse{Active: false}
+
+	if token == "" {
+		return nil, oauthErrors.ErrInvalidRequest
+	}
+
+	// по RFC подсказка необязательна, поэтому при промахе пробуем второй тип
+	types := []libjwt.TokenType{libjwt.TokenTypeAccess, libjwt.TokenTypeRefresh}
+	if tokenTypeHint == TokenTypeHintRefresh {
+		types = []libjwt.TokenType{libjwt.TokenTypeRefresh, libjwt.TokenTypeAccess}
+	}
+
+	var claims *libjwt.TokenClaims
+	for _, tokenType := range types {
+		if parsed, err := i.jwt.ParseToken(token, tokenType); err == nil {
+			claims = parsed
+			break
+		}
+	}
+	if claims == nil {
+		return inactive, nil
+	}
+
+	if claims.PrincipalType == libjwt.PrincipalService {
+		return i.introspectService(ctx, claims)
+	}
+
+	if i.revocations.IsRevoked(claims.ID, claims.UserID, claims.IssuedAt) || i.revocations.IsOutdated(claims.UserID, claims.TokenVersion) {
+		return inactive, nil
+	}
+
+	if claims.Type == libjwt.TokenTypeRefresh {
+		stored, err := i.repo.GetRefreshTokenByHash(ctx, domain.HashRefreshToken(token))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if stored == nil || stored.IsRotated() || stored.IsRevoked() {
+			return inactive, nil
+		}
+	}
+
+	user, err := i.repo.GetUserWithId(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if user == nil || user.IsArchived {
+		return inactive, nil
+	}
+
+	result := &oauth.IntrospectionResponse{
+		Active:    true,
+		Ptype:     string(libjwt.PrincipalUser),
+		Sub:       strconv.FormatInt(claims.UserID, 10),
+		Username:  user.Login,
+		Role:      claims.Role,
+		TokenType: TokenTypeHintAccess,
+		ClientID:  claims.ClientID,
+		Iss:       claims.Issuer,
+		Aud:       claims.Audience,
+		Exp:       claims.ExpiresAt.Unix(),
+		Jti:       claims.ID,
+	}
+	if claims.Type == libjwt.TokenTypeRefresh {
+		result.TokenType = TokenTypeHintRefresh
+	}
+	if !claims.AuthTime.IsZero() {
+		result.AuthTime = claims.AuthTime.Unix()
+	}
+	result.Amr = claims.Amr
+	result.Acr = claims.Acr
+	if !claims.IssuedAt.IsZero() {
+		result.Iat = claims.IssuedAt.Unix()
+	}
+	return result, nil
+}
+
+// introspectService отвечает про токен client_credentials: он действителен, пока клиент зарегистрирован и не отключён
+func (i *Introspection) introspectService(ctx context.Context, claims *libjwt.TokenClaims) (*oauth.IntrospectionResponse, error) {
+	inactive := &oauth.IntrospectionResponse{Active: false}
+	if i.revocations.IsRevoked(claims.ID, 0, claims.IssuedAt) {
+		return inactive, nil
+	}
+	client, err := i.clients.GetClient(ctx, claims.ClientID)
+	if err != nil {
+		return nil, fmt.Errorf("Introspection.introspectService: %w", err)
+	}
+	if client == nil {
+		return inactive, nil
+	}
+
+	return &oauth.IntrospectionResponse{
+		Active:    true,
+		Ptype:     string(libjwt.PrincipalService),
+		Sub:       claims.ClientID,
+		Scope:     strings.Join(claims.Scopes, " "),
+		TokenType: TokenTypeHintAccess,
+		ClientID:  claims.ClientID,
+		Iss:       claims.Issuer,
+		Aud:       claims.Audience,
+		Exp:       claims.ExpiresAt.Unix(),
+		Iat:       claims.IssuedAt.Unix(),
+		Jti:       claims.ID,
+	}, nil
+}
@@ -0,0 +1,219 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/oidc"
+	deviceErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/device"
+	oauthErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/oauth"
+	libjwt "github.com/EtoNeAnanasbI95/sso/internal/lib/jwt"
+)
+
+type DeviceRepository interface {
+	CreateDeviceAuthorization(ctx context.Context, device *domain.DeviceAuthorization) error
+	GetPendingDeviceAuthorization(ctx context.Context, userCode string) (*domain.DeviceAuthorization, error)
+	DecideDeviceAuthorization(ctx context.Context, userCode, status string, userId int64, authTime time.Time, amr []string) (bool, error)
+	PollDeviceAuthorization(ctx context.Context, hash []byte) (*domain.DeviceAuthorization, error)
+	ConsumeDeviceAuthorization(ctx context.Context, id int64) (bool, error)
+	DeleteExpiredDeviceAuthorizations(ctx context.Context) error
+}
+
+// DeviceAuthorization начинает авторизацию устройства без браузера (RFC 8628, 3.1-3.2):
+// выдаёт device_code для опроса /token и user_code, который пользователь подтверждает в браузере
+func (p *Provider) DeviceAuthorization(ctx context.Context, request oidc.DeviceAuthorizationRequest) (*oidc.DeviceAuthorizationResponse, error) {
+	client, err := p.clients.GetClient(ctx, request.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, oauthErrors.ErrInvalidClient
+	}
+	if client.IsConfidential() && !client.CheckSecret(request.ClientSecret) {
+		return nil, oauthErrors.ErrInvalidClient
+	}
+	if !client.AllowsGrant(GrantTypeDeviceCode) {
+		return nil, oauthErrors.ErrUnauthorizedClient
+	}
+	if !client.AllowsScopes(strings.Fields(request.Scope)) {
+		return nil, oauthErrors.ErrInvalidScope
+	}
+
+	deviceCode, device, err := domain.NewDeviceAuthorization(client.Id, request.Scope, p.opts.DevicePollInterval, p.opts.DeviceCodeTTL)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.devices.CreateDeviceAuthorization(ctx, device); err != nil {
+		return nil, err
+	}
+
+	userCode := domain.FormatUserCode(device.UserCode)
+	return &oidc.DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         p.opts.VerificationURI,
+		VerificationURIComplete: redirectURL(p.opts.VerificationURI, map[string]string{"user_code": userCode}),
+		ExpiresIn:               int64(p.opts.DeviceCodeTTL.Seconds()),
+		Interval:                device.Interval,
+	}, nil
+}
+
+// DeviceInfo показывает пользователю, какой клиент просит доступ по user_code
+func (p *Provider) DeviceInfo(ctx context.Context, userCode string) (*oidc.DeviceInfoResponse, error) {
+	device, err := p.devices.GetPendingDeviceAuthorization(ctx, domain.NormalizeUserCode(userCode))
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		return nil, deviceErrors.ErrUserCodeNotFound
+	}
+	client, err := p.clients.GetClient(ctx, device.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, deviceErrors.ErrUserCodeNotFound
+	}
+
+	return &oidc.DeviceInfoResponse{
+		UserCode:   domain.FormatUserCode(device.UserCode),
+		ClientID:   client.Id,
+		ClientName: client.Name,
+		Scope:      device.Scope,
+	}, nil
+}
+
+// DeviceSession возвращает сессию SSO из cookie refresh_token, в которой пользователь подтверждает код
+// в браузере без access токена. nil — сессии нет или она недействительна
+func (p *Provider) DeviceSession(ctx context.Context, refreshToken string) *domain.RefreshToken {
+	if refreshToken == "" {
+		return nil
+	}
+	user, session, err := p.sessions.CurrentSession(ctx, refreshToken)
+	if err != nil {
+		slog.Debug("no valid sso session for device verification", "err", err)
+		return nil
+	}
+	if user == nil || user.IsArchived {
+		return nil
+	}
+	return session
+}
+
+// DeviceLoginURL — куда отправить браузер без сессии SSO: на oidc.login_url с return_to на страницу
+// подтверждения с тем же user_code. Пустая строка — страница входа не настроена
+func (p *Provider) DeviceLoginURL(userCode string) string {
+	if p.opts.LoginURL == "" {
+		return ""
+	}
+	returnTo := redirectURL(p.opts.VerificationURI, map[string]string{"user_code": userCode})
+	return redirectURL(p.opts.LoginURL, map[string]string{"return_to": returnTo})
+}
+
+// DecideDevice записывает решение вошедшего пользователя. Устройство получит токены от его имени,
+// а сессия устройства унаследует authTime и amr сессии, в которой пользователь подтвердил код
+func (p *Provider) DecideDevice(ctx context.Context, userCode string, userId int64, authTime time.Time, amr []string, approve bool) error {
+	// у токенов, выпущенных до появления auth_time, его нет: считаем, что пользователь вошёл сейчас
+	if authTime.IsZero() {
+		authTime = time.Now()
+	}
+	status := domain.DeviceStatusDenied
+	if approve {
+		status = domain.DeviceStatusApproved
+	}
+	decided, err := p.devices.DecideDeviceAuthorization(ctx, domain.NormalizeUserCode(userCode), status, userId, authTime, amr)
+	if err != nil {
+		return err
+	}
+	if !decided {
+		return deviceErrors.ErrUserCodeNotFound
+	}
+	return nil
+}
+
+// exchangeDeviceCode отвечает на опрос /token устройством (RFC 8628, 3.4-3.5)
+func (p *Provider) exchangeDeviceCode(ctx context.Context, client *domain.Client, request oidc.TokenRequest, meta domain.SessionMeta) (*oidc.TokenResponse, error) {
+	if request.DeviceCode == "" {
+		return nil, oauthErrors.ErrInvalidRequest
+	}
+
+	now := time.Now()
+	device, err := p.devices.PollDeviceAuthorization(ctx, domain.HashDeviceCode(request.DeviceCode))
+	if err != nil {
+		return nil, err
+	}
+	if device == nil || device.ClientId != client.Id {
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+	if device.IsExpired(now) {
+		return nil, oauthErrors.ErrExpiredToken
+	}
+	if device.PolledTooOften(now) {
+		return nil, oauthErrors.ErrSlowDown
+	}
+
+	switch device.Status {
+	case domain.DeviceStatusPending:
+		return nil, oauthErrors.ErrAuthorizationPending
+	case domain.DeviceStatusDenied:
+		return nil, oauthErrors.ErrAccessDenied
+	case domain.DeviceStatusApproved:
+	default:
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+
+	// токены по одному device_code выдаются один раз, даже если опросы пришли одновременно
+	consumed, err := p.devices.ConsumeDeviceAuthorization(ctx, device.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed || device.UserId == nil || device.AuthTime == nil {
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+
+	user, err := p.users.GetUserWithId(ctx, *device.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.IsArchived {
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+
+	// устройство живёт своей сессией: выход из браузера, где подтвердили код, его не разлогинивает
+	tokens, err := p.sessions.StartSession(ctx, user, client, *device.AuthTime, domain.SplitAmr(device.Amr), "", meta)
+	if err != nil {
+		return nil, err
+	}
+	result := &oidc.TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokens.ExpiresIn,
+		RefreshToken: tokens.RefreshToken,
+		Scope:        device.Scope,
+	}
+
+	// scope применяется как на /authorize: openid добавляет ID токен, profile — claims профиля в нём.
+	// nonce в device flow нет
+	if hasScope(device.Scope, ScopeOpenID) {
+		amr := domain.SplitAmr(device.Amr)
+		result.IDToken, err = p.jwt.NewIDToken(libjwt.IDTokenParams{
+			UserID:      user.Id,
+			ClientID:    client.Id,
+			SessionID:   tokens.SessionID,
+			AuthTime:    *device.AuthTime,
+			Amr:         amr,
+			Acr:         domain.Acr(amr),
+			ExpiresAt:   time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second),
+			AccessToken: tokens.AccessToken,
+			Claims:      profileClaims(user, hasScope(device.Scope, ScopeProfile)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("sign id token: %w", err)
+		}
+	}
+	return result, nil
+}
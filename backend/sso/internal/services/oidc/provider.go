@@ -0,0 +1,450 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/auth"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/oidc"
+	oauthErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/oauth"
+	libjwt "github.com/EtoNeAnanasbI95/sso/internal/lib/jwt"
+)
+
+const (
+	GrantTypeAuthorizationCode = domain.GrantTypeAuthorizationCode
+	GrantTypeRefreshToken      = domain.GrantTypeRefreshToken
+	GrantTypeClientCredentials = domain.GrantTypeClientCredentials
+	GrantTypeDeviceCode        = domain.GrantTypeDeviceCode
+
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+
+	responseTypeCode = "code"
+	promptNone       = "none"
+)
+
+type Jwt interface {
+	ParseToken(tokenString string, expectedType libjwt.TokenType) (*libjwt.TokenClaims, error)
+	NewIDToken(params libjwt.IDTokenParams) (string, error)
+	NewServiceToken(params libjwt.ServiceTokenParams) (string, time.Time, error)
+	Issuer() string
+	Algorithms() []string
+}
+
+// Sessions — вход пользователя и выпуск токенов, реализуется сервисом auth
+type Sessions interface {
+	CurrentSession(ctx context.Context, refreshToken string) (*domain.User, *domain.RefreshToken, error)
+	StartSession(ctx context.Context, user *domain.User, client *domain.Client, authTime time.Time, amr []string, ssoSessionId string, meta domain.SessionMeta) (*auth.AuthResponse, error)
+	Refresh(ctx context.Context, refreshToken string, meta domain.SessionMeta) (*auth.AuthResponse, error)
+}
+
+type Clients interface {
+	GetClient(ctx context.Context, clientId string) (*domain.Client, error)
+}
+
+type Users interface {
+	GetUserWithId(ctx context.Context, uid int64) (*domain.User, error)
+}
+
+type CodeRepository interface {
+	CreateAuthorizationCode(ctx context.Context, code *domain.AuthorizationCode) error
+	ConsumeAuthorizationCode(ctx context.Context, hash []byte) (*domain.AuthorizationCode, error)
+	DeleteExpiredAuthorizationCodes(ctx context.Context) error
+}
+
+// Options — параметры провайдера
+type Options struct {
+	// LoginURL — страница входа, куда /authorize отправляет пользователя без сессии SSO
+	LoginURL string
+	CodeTTL  time.Duration
+	// VerificationURI — страница, где пользователь вводит user_code устройства
+	VerificationURI string
+	DeviceCodeTTL   time.Duration
+	// DevicePollInterval — как часто устройству разрешено опрашивать /token
+	DevicePollInterval time.Duration
+}
+
+// Provider — OpenID Connect провайдер: authorization code flow с PKCE и авторизация устройств.
+// Конфиденциальные клиенты дополнительно аутентифицируются секретом на /token
+type Provider struct {
+	codes    CodeRepository
+	devices  DeviceRepository
+	users    Users
+	sessions Sessions
+	clients  Clients
+	jwt      Jwt
+	opts     Options
+}
+
+func NewProvider(codes CodeRepository, devices DeviceRepository, users Users, sessions Sessions, clients Clients, jwt Jwt, opts Options) *Provider {
+	return &Provider{
+		codes:    codes,
+		devices:  devices,
+		users:    users,
+		sessions: sessions,
+		clients:  clients,
+		jwt:      jwt,
+		opts:     opts,
+	}
+}
+
+// Authorize проверяет запрос /authorize и возвращает, куда перенаправить браузер: на redirect_uri клиента
+// с кодом или ошибкой, либо на страницу входа. Ошибка возвращается только тогда, когда redirect_uri
+// доверять нельзя и ответ нужно показать самому пользователю.
+// refreshToken — cookie сессии SSO, requestURI — путь и query текущего запроса /authorize
+func (p *Provider) Authorize(ctx context.Context, request oidc.AuthorizeRequest, refreshToken, requestURI string) (string, error) {
+	client, err := p.clients.GetClient(ctx, request.ClientID)
+	if err != nil {
+		return "", err
+	}
+	if client == nil {
+		return "", oauthErrors.ErrInvalidClient
+	}
+	if request.RedirectURI == "" || !client.AllowsRedirectURI(request.RedirectURI) {
+		return "", oauthErrors.ErrInvalidRequest
+	}
+
+	redirectError := func(err error, description string) (string, error) {
+		return redirectURL(request.RedirectURI, map[string]string{
+			"error":             err.Error(),
+			"error_description": description,
+			"state":             request.State,
+			"iss":               p.jwt.Issuer(),
+		}), nil
+	}
+
+	if request.ResponseType != responseTypeCode {
+		return redirectError(oauthErrors.ErrUnsupportedResponseType, "only response_type=code is supported")
+	}
+	if !client.AllowsGrant(GrantTypeAuthorizationCode) {
+		return redirectError(oauthErrors.ErrUnauthorizedClient, "client is not allowed to use authorization_code")
+	}
+	if !hasScope(request.Scope, ScopeOpenID) {
+		return redirectError(oauthErrors.ErrInvalidScope, "scope must include openid")
+	}
+	if !client.AllowsScopes(strings.Fields(request.Scope)) {
+		return redirectError(oauthErrors.ErrInvalidScope, "requested scope is not allowed for this client")
+	}
+	if request.CodeChallenge == "" || request.CodeChallengeMethod != domain.CodeChallengeMethodS256 {
+		return redirectError(oauthErrors.ErrInvalidRequest, "PKCE with code_challenge_method=S256 is required")
+	}
+	maxAge := -1
+	if request.MaxAge != "" {
+		maxAge, err = strconv.Atoi(request.MaxAge)
+		if err != nil || maxAge < 0 {
+			return redirectError(oauthErrors.ErrInvalidRequest, "max_age must be a non-negative integer")
+		}
+	}
+
+	var user *domain.User
+	var session *domain.RefreshToken
+	if refreshToken != "" {
+		user, session, err = p.sessions.CurrentSession(ctx, refreshToken)
+		if err != nil {
+			slog.Debug("no valid sso session for authorize", "err", err)
+			user, session = nil, nil
+		}
+	}
+	// вход старше max_age не подходит: клиент просит, чтобы пользователь подтвердил личность заново
+	if user != nil && maxAge >= 0 && time.Since(session.AuthTime) > time.Duration(maxAge)*time.Second {
+		user, session = nil, nil
+	}
+	if user == nil {
+		if request.Prompt == promptNone || p.opts.LoginURL == "" {
+			return redirectError(oauthErrors.ErrLoginRequired, "user is not logged in")
+		}
+		returnTo := strings.TrimRight(p.jwt.Issuer(), "/") + requestURI
+		return redirectURL(p.opts.LoginURL, map[string]string{"return_to": returnTo}), nil
+	}
+
+	code, stored, err := domain.NewAuthorizationCode(
+		client.Id,
+		user.Id,
+		session.FamilyId,
+		request.RedirectURI,
+		request.Scope,
+		request.Nonce,
+		request.CodeChallenge,
+		session.AuthTime,
+		domain.SplitAmr(session.Amr),
+		p.opts.CodeTTL,
+	)
+	if err != nil {
+		return "", err
+	}
+	if err := p.codes.CreateAuthorizationCode(ctx, stored); err != nil {
+		return "", err
+	}
+
+	return redirectURL(request.RedirectURI, map[string]string{
+		"code":  code,
+		"state": request.State,
+		"iss":   p.jwt.Issuer(),
+	}), nil
+}
+
+// Token обменивает код или refresh токен на токены (RFC 6749, раздел 4.1.3 и 6)
+func (p *Provider) Token(ctx context.Context, request oidc.TokenRequest, meta domain.SessionMeta) (*oidc.TokenResponse, error) {
+	client, err := p.clients.GetClient(ctx, request.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, oauthErrors.ErrInvalidClient
+	}
+	// публичные клиенты защищены PKCE, конфиденциальные обязаны предъявить секрет
+	if client.IsConfidential() && !client.CheckSecret(request.ClientSecret) {
+		return nil, oauthErrors.ErrInvalidClient
+	}
+	if request.GrantType != "" && !client.AllowsGrant(request.GrantType) {
+		return nil, oauthErrors.ErrUnauthorizedClient
+	}
+
+	switch request.GrantType {
+	case GrantTypeAuthorizationCode:
+		return p.exchangeCode(ctx, client, request, meta)
+	case GrantTypeRefreshToken:
+		return p.refresh(ctx, client, request, meta)
+	case GrantTypeClientCredentials:
+		return p.clientCredentials(client, request)
+	case GrantTypeDeviceCode:
+		return p.exchangeDeviceCode(ctx, client, request, meta)
+	default:
+		return nil, oauthErrors.ErrUnsupportedGrantType
+	}
+}
+
+func (p *Provider) exchangeCode(ctx context.Context, client *domain.Client, request oidc.TokenRequest, meta domain.SessionMeta) (*oidc.TokenResponse, error) {
+	if request.Code == "" || request.CodeVerifier == "" {
+		return nil, oauthErrors.ErrInvalidRequest
+	}
+
+	// код одноразовый: он считается использованным даже если дальнейшие проверки не пройдут
+	code, err := p.codes.ConsumeAuthorizationCode(ctx, domain.HashAuthorizationCode(request.Code))
+	if err != nil {
+		return nil, err
+	}
+	if code == nil || code.IsExpired(time.Now()) {
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+	if code.ClientId != client.Id || code.RedirectUri != request.RedirectURI || !code.VerifyCodeVerifier(request.CodeVerifier) {
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+
+	user, err := p.users.GetUserWithId(ctx, code.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.IsArchived {
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+
+	// сессия клиента завершится вместе с сессией SSO, в которой пользователь разрешил доступ
+	var ssoSessionId string
+	if code.SessionId != nil {
+		ssoSessionId = *code.SessionId
+	}
+	amr := domain.SplitAmr(code.Amr)
+	tokens, err := p.sessions.StartSession(ctx, user, client, code.AuthTime, amr, ssoSessionId, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := p.jwt.NewIDToken(libjwt.IDTokenParams{
+		UserID:      user.Id,
+		ClientID:    client.Id,
+		SessionID:   tokens.SessionID,
+		AuthTime:    code.AuthTime,
+		Amr:         amr,
+		Acr:         domain.Acr(amr),
+		ExpiresAt:   time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second),
+		Nonce:       code.Nonce,
+		AccessToken: tokens.AccessToken,
+		Claims:      profileClaims(user, code.HasScope(ScopeProfile)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sign id token: %w", err)
+	}
+
+	return &oidc.TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokens.ExpiresIn,
+		RefreshToken: tokens.RefreshToken,
+		IDToken:      idToken,
+		Scope:        code.Scope,
+	}, nil
+}
+
+func (p *Provider) refresh(ctx context.Context, client *domain.Client, request oidc.TokenRequest, meta domain.SessionMeta) (*oidc.TokenResponse, error) {
+	if request.RefreshToken == "" {
+		return nil, oauthErrors.ErrInvalidRequest
+	}
+
+	// refresh токен можно обменять только тому клиенту, которому он выдан
+	claims, err := p.jwt.ParseToken(request.RefreshToken, libjwt.TokenTypeRefresh)
+	if err != nil || claims.ClientID != client.Id {
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+
+	tokens, err := p.sessions.Refresh(ctx, request.RefreshToken, meta)
+	if err != nil {
+		slog.Debug("refresh token grant rejected", "err", err)
+		return nil, oauthErrors.ErrInvalidGrant
+	}
+
+	return &oidc.TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokens.ExpiresIn,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+// clientCredentials выпускает сервису токен от его собственного имени (RFC 6749, раздел 4.4).
+// Без scope в запросе выдаются все scope клиента
+func (p *Provider) clientCredentials(client *domain.Client, request oidc.TokenRequest) (*oidc.TokenResponse, error) {
+	// сервис должен доказать, что он — это он, поэтому публичным клиентам грант недоступен
+	if !client.IsConfidential() {
+		return nil, oauthErrors.ErrUnauthorizedClient
+	}
+
+	scopes := strings.Fields(request.Scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	if !client.AllowsScopes(scopes) {
+		return nil, oauthErrors.ErrInvalidScope
+	}
+
+	accessToken, expiresAt, err := p.jwt.NewServiceToken(libjwt.ServiceTokenParams{
+		ClientID:       client.Id,
+		Audience:       client.Audience,
+		Scopes:         scopes,
+		ClientLifetime: client.Lifetime,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sign service token: %w", err)
+	}
+
+	return &oidc.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(time.Until(expiresAt).Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// UserInfo возвращает claims профиля владельца access токена
+func (p *Provider) UserInfo(ctx context.Context, userId int64) (*oidc.UserInfoResponse, error) {
+	user, err := p.users.GetUserWithId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.IsArchived {
+		return nil, errors.New("user not found")
+	}
+
+	role := user.RoleName
+	if role == "" {
+		role = "customer"
+	}
+	return &oidc.UserInfoResponse{
+		Sub:               strconv.FormatInt(user.Id, 10),
+		PreferredUsername: user.Login,
+		Name:              user.FullName,
+		Role:              role,
+	}, nil
+}
+
+// Discovery собирает /.well-known/openid-configuration. Адреса эндпоинтов строятся от issuer
+func (p *Provider) Discovery() oidc.DiscoveryDocument {
+	base := strings.TrimRight(p.jwt.Issuer(), "/")
+	return oidc.DiscoveryDocument{
+		Issuer:                            p.jwt.Issuer(),
+		AuthorizationEndpoint:             base + "/authorize",
+		TokenEndpoint:                     base + "/token",
+		UserinfoEndpoint:                  base + "/userinfo",
+		JwksURI:                           base + "/.well-known/jwks.json",
+		IntrospectionEndpoint:             base + "/oauth/introspect",
+		DeviceAuthorizationEndpoint:       base + "/oauth/device/code",
+		ScopesSupported:                   []string{ScopeOpenID, ScopeProfile},
+		ResponseTypesSupported:            []string{responseTypeCode},
+		GrantTypesSupported:               []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken, GrantTypeClientCredentials, GrantTypeDeviceCode},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  p.jwt.Algorithms(),
+		TokenEndpointAuthMethodsSupported: []string{"none", "client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{domain.CodeChallengeMethodS256},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "amr", "acr", "nonce", "sid", "at_hash",
+			"name", "preferred_username", "role",
+		},
+		BackchannelLogoutSupported:        true,
+		BackchannelLogoutSessionSupported: true,
+	}
+}
+
+// Run раз в interval удаляет истёкшие коды и запросы устройств. Блокируется до отмены ctx
+func (p *Provider) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := p.codes.DeleteExpiredAuthorizationCodes(ctx); err != nil {
+				slog.Error("failed to delete expired authorization codes", "err", err)
+			}
+			if err := p.devices.DeleteExpiredDeviceAuthorizations(ctx); err != nil {
+				slog.Error("failed to delete expired device authorizations", "err", err)
+			}
+		}
+	}
+}
+
+func profileClaims(user *domain.User, withProfile bool) map[string]any {
+	if !withProfile {
+		return nil
+	}
+	claims := map[string]any{
+		"preferred_username": user.Login,
+	}
+	if user.FullName != "" {
+		claims["name"] = user.FullName
+	}
+	return claims
+}
+
+func hasScope(scope, expected string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectURL добавляет непустые параметры к адресу, сохраняя его собственные
+func redirectURL(base string, params map[string]string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base
+	}
+	query := u.Query()
+	for name, value := range params {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,195 @@
+package passwordless
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	passwordlessErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/passwordless"
+	"github.com/EtoNeAnanasbI95/sso/internal/lib/notify"
+)
+
+type Repository interface {
+	CreateLoginCode(ctx context.Context, code *domain.LoginCode) error
+	TouchLoginCodeRequest(ctx context.Context, loginHash []byte, since time.Time) (bool, error)
+	HasRecentLoginCode(ctx context.Context, userId int64, since time.Time) (bool, error)
+	AttemptLoginCode(ctx context.Context, tokenHash []byte, maxAttempts int) (*domain.LoginCode, error)
+	ConsumeLoginCode(ctx context.Context, code *domain.LoginCode) (bool, error)
+	DeleteExpiredLoginCodes(ctx context.Context) error
+	DeleteLoginCodeRequests(ctx context.Context, before time.Time) error
+}
+
+type Users interface {
+	GetUserByLogin(ctx context.Context, login string) (*domain.User, error)
+}
+
+// Options — параметры входа по одноразовому коду
+type Options struct {
+	CodeTTL     time.Duration
+	MaxAttempts int
+	// ResendInterval — как часто можно запрашивать код для одного логина и отправлять его одному пользователю
+	ResendInterval time.Duration
+	// LinkURL — страница фронтенда, принимающая login_token и code из ссылки; пустой — ссылка не отправляется
+	LinkURL string
+}
+
+// Service — вход без пароля: код отправляется пользователю через notifier, клиент получает токен запроса.
+// Токены сервис не выпускает, вход завершает сервис auth. notifier == nil означает, что вход выключен
+type Service struct {
+	repo     Repository
+	users    Users
+	notifier notify.Notifier
+	opts     Options
+}
+
+func New(repo Repository, users Users, notifier notify.Notifier, opts Options) *Service {
+	return &Service{
+		repo:     repo,
+		users:    users,
+		notifier: notifier,
+		opts:     opts,
+	}
+}
+
+// RequestCode отправляет пользователю код входа в клиент clientId и возвращает токен запроса.
+// Для неизвестного логина и пользователя, которому некуда написать, возвращается ничего не значащий токен,
+// чтобы по ответу нельзя было узнать, есть ли такой пользователь. Частота запросов ограничивается по логину
+// до поиска пользователя, поэтому ErrTooManyRequests тоже приходит одинаково для любых логинов
+func (s *Service) RequestCode(ctx context.Context, login, clientId string) (string, error) {
+	if s.notifier == nil {
+		return "", passwordlessErrors.ErrNotConfigured
+	}
+	since := time.Now().Add(-s.opts.ResendInterval)
+	allowed, err := s.repo.TouchLoginCodeRequest(ctx, domain.HashLoginRequestKey(login), since)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", passwordlessErrors.ErrTooManyRequests
+	}
+
+	user, err := s.users.GetUserByLogin(ctx, login)
+	if err != nil {
+		return "", err
+	}
+	if user == nil || user.IsArchived {
+		return domain.NewDecoyLoginToken()
+	}
+
+	// код уже запрошен по другому имени того же пользователя (логину или тегу Telegram). Ошибка выдала бы,
+	// что оба имени существуют, поэтому новый код просто не отправляется
+	recent, err := s.repo.HasRecentLoginCode(ctx, user.Id, since)
+	if err != nil {
+		return "", err
+	}
+	if recent {
+		return domain.NewDecoyLoginToken()
+	}
+
+	token, code, stored, err := domain.NewLoginCode(user.Id, clientId, s.opts.CodeTTL)
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.CreateLoginCode(ctx, stored); err != nil {
+		return "", err
+	}
+
+	err = s.notifier.Notify(ctx, recipient(user), s.message(token, code))
+	if errors.Is(err, notify.ErrNoAddress) {
+		slog.Warn("login code not delivered: user has no address for configured channels", "user_id", user.Id)
+		return token, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("deliver login code: %w", err)
+	}
+	slog.Info("login code sent", "user_id", user.Id, "client_id", clientId)
+	return token, nil
+}
+
+// VerifyCode проверяет код и завершает запрос входа. Каждый вызов тратит попытку, после успешного
+// входа остальные отправленные пользователю коды перестают действовать
+func (s *Service) VerifyCode(ctx context.Context, token, code string) (*domain.LoginCode, error) {
+	if s.notifier == nil {
+		return nil, passwordlessErrors.ErrNotConfigured
+	}
+	stored, err := s.repo.AttemptLoginCode(ctx, domain.HashLoginToken(token), s.opts.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || !stored.Matches(token, strings.TrimSpace(code)) {
+		return nil, passwordlessErrors.ErrInvalidCode
+	}
+	consumed, err := s.repo.ConsumeLoginCode(ctx, stored)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, passwordlessErrors.ErrInvalidCode
+	}
+	return stored, nil
+}
+
+// CodeTTL — сколько живёт отправленный код
+func (s *Service) CodeTTL() time.Duration {
+	return s.opts.CodeTTL
+}
+
+// Run раз в interval удаляет истёкшие коды и отметки запросов. Блокируется до отмены ctx
+func (s *Service) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.repo.DeleteExpiredLoginCodes(ctx); err != nil {
+				slog.Error("failed to delete expired login codes", "err", err)
+			}
+			if err := s.repo.DeleteLoginCodeRequests(ctx, time.Now().Add(-s.opts.ResendInterval)); err != nil {
+				slog.Error("failed to delete login code requests", "err", err)
+			}
+		}
+	}
+}
+
+func (s *Service) message(token, code string) notify.Message {
+	minutes := int(s.opts.CodeTTL.Round(time.Minute) / time.Minute)
+	text := fmt.Sprintf("Код для входа: %s\nОн действует %d мин.", code, minutes)
+	if s.opts.LinkURL != "" {
+		text += "\nИли откройте ссылку: " + loginLink(s.opts.LinkURL, token, code)
+	}
+	text += "\nЕсли вы не запрашивали вход, просто проигнорируйте это сообщение."
+	return notify.Message{
+		Subject: "Код для входа",
+		Text:    text,
+	}
+}
+
+// loginLink — страница фронтенда с токеном запроса и кодом: открыв её, пользователь входит без ввода кода
+func loginLink(base, token, code string) string {
+	query := url.Values{}
+	query.Set("login_token", token)
+	query.Set("code", code)
+	separator := "?"
+	if strings.Contains(base, "?") {
+		separator = "&"
+	}
+	return base + separator + query.Encode()
+}
+
+// recipient — куда можно отправить код. Чат Telegram берётся только подтверждённый: непроверенный chat id
+// мог вписать кто угодно, и код ушёл бы в чужой чат
+func recipient(user *domain.User) notify.Recipient {
+	to := notify.Recipient{Email: user.Login}
+	if user.TelegramChatId.Valid && user.TelegramVerified {
+		to.TelegramChatID = user.TelegramChatId.Int64
+	}
+	return to
+}
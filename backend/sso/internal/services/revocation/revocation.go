@@ -0,0 +1,226 @@
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/admin"
+	revocationErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/revocation"
+)
+
+type Repository interface {
+	RevokeToken(ctx context.Context, token *domain.RevokedToken) error
+	CreateCutoff(ctx context.Context, cutoff *domain.RevocationCutoff) error
+	ListActiveTokens(ctx context.Context) ([]domain.RevokedToken, error)
+	ListCutoffs(ctx context.Context, maxTokenLifetime time.Duration) ([]domain.RevocationCutoff, error)
+	ListTokenVersions(ctx context.Context) ([]domain.UserTokenVersion, error)
+	DeleteExpiredTokens(ctx context.Context) error
+}
+
+// Revocations — список отозванных токенов. Источник правды — Postgres,
+// проверки идут по копии в памяти, которая перечитывается раз в reloadInterval,
+// чтобы подхватывать отзывы, сделанные другими инстансами.
+type Revocations struct {
+	repo             Repository
+	maxTokenLifetime time.Duration
+
+	mu           sync.RWMutex
+	tokens       map[string]time.Time
+	userCutoffs  map[int64]time.Time
+	globalCutoff time.Time
+	// текущая версия токенов пользователя, отсутствие записи — версия 0
+	tokenVersions map[int64]int64
+}
+
+func New(repo Repository, maxTokenLifetime time.Duration) *Revocations {
+	return &Revocations{
+		repo:             repo,
+		maxTokenLifetime: maxTokenLifetime,
+		tokens:           make(map[string]time.Time),
+		userCutoffs:      make(map[int64]time.Time),
+		tokenVersions:    make(map[int64]int64),
+	}
+}
+
+// IsRevoked проверяет токен по jti и по отсечениям для пользователя и для всех.
+// iat хранится с точностью до секунды, поэтому и отсечения сравниваются по секундам: токен, выпущенный
+// в ту же секунду сразу после отзыва, остаётся действительным
+func (r *Revocations) IsRevoked(jti string, userId int64, issuedAt time.Time) bool {
+	issuedAt = issuedAt.Truncate(time.Second)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if jti != "" {
+		if expiresAt, ok := r.tokens[jti]; ok && time.Now().Before(expiresAt) {
+			return true
+		}
+	}
+	if issuedAt.Before(r.globalCutoff) {
+		return true
+	}
+	if cutoff, ok := r.userCutoffs[userId]; ok && issuedAt.Before(cutoff) {
+		return true
+	}
+	return false
+}
+
+// IsOutdated проверяет, что токен выпущен до последнего принудительного выхода пользователя
+func (r *Revocations) IsOutdated(userId int64, tokenVersion int64) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return tokenVersion < r.tokenVersions[userId]
+}
+
+// SetTokenVersion сразу применяет новую версию токенов на этом инстансе, не дожидаясь Reload
+func (r *Revocations) SetTokenVersion(userId int64, tokenVersion int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if tokenVersion > r.tokenVersions[userId] {
+		r.tokenVersions[userId] = tokenVersion
+	}
+}
+
+// Revoke отзывает токены по запросу администратора adminId
+func (r *Revocations) Revoke(ctx context.Context, request admin.RevokeTokensRequest, adminId int64) error {
+	const op = "Revocations.Revoke"
+
+	var reason *string
+	if request.Reason != "" {
+		reason = &request.Reason
+	}
+
+	switch {
+	case request.Jti != "" && (request.UserID != nil || request.IssuedBefore != nil):
+		return revocationErrors.ErrAmbiguousRevocation
+	case request.Jti != "":
+		token := &domain.RevokedToken{
+			Jti:       request.Jti,
+			ExpiresAt: time.Now().Add(r.maxTokenLifetime),
+			RevokedAt: time.Now(),
+			RevokedBy: &adminId,
+			Reason:    reason,
+		}
+		if err := r.repo.RevokeToken(ctx, token); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		r.mu.Lock()
+		r.tokens[token.Jti] = token.ExpiresAt
+		r.mu.Unlock()
+	case request.UserID != nil || request.IssuedBefore != nil:
+		now := time.Now()
+		// отсечение в будущем не пускало бы пользователя до этого момента даже после нового входа
+		if request.IssuedBefore != nil && request.IssuedBefore.After(now) {
+			return revocationErrors.ErrIssuedBeforeInFuture
+		}
+		cutoff := &domain.RevocationCutoff{
+			UserId:        request.UserID,
+			RevokedBefore: now.Truncate(time.Second),
+			CreatedAt:     now,
+			CreatedBy:     &adminId,
+			Reason:        reason,
+		}
+		if request.IssuedBefore != nil {
+			cutoff.RevokedBefore = request.IssuedBefore.Truncate(time.Second)
+		}
+		if err := r.repo.CreateCutoff(ctx, cutoff); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		r.applyCutoff(*cutoff)
+	default:
+		return revocationErrors.ErrEmptyRevocationRequest
+	}
+
+	slog.Info("tokens revoked",
+		"op", op,
+		"admin_id", adminId,
+		"jti", request.Jti,
+		"user_id", request.UserID,
+		"issued_before", request.IssuedBefore,
+	)
+	return nil
+}
+
+// Reload перечитывает список отозванных токенов из БД
+func (r *Revocations) Reload(ctx context.Context) error {
+	tokens, err := r.repo.ListActiveTokens(ctx)
+	if err != nil {
+		return err
+	}
+	cutoffs, err := r.repo.ListCutoffs(ctx, r.maxTokenLifetime)
+	if err != nil {
+		return err
+	}
+	versions, err := r.repo.ListTokenVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	tokenMap := make(map[string]time.Time, len(tokens))
+	for _, token := range tokens {
+		tokenMap[token.Jti] = token.ExpiresAt
+	}
+	userCutoffs := make(map[int64]time.Time, len(cutoffs))
+	var globalCutoff time.Time
+	for _, cutoff := range cutoffs {
+		mergeCutoff(userCutoffs, &globalCutoff, cutoff)
+	}
+	tokenVersions := make(map[int64]int64, len(versions))
+	for _, version := range versions {
+		tokenVersions[version.UserId] = version.TokenVersion
+	}
+
+	// всё заменяется разом: IsRevoked не должен увидеть список без отсечений
+	r.mu.Lock()
+	r.tokens = tokenMap
+	r.userCutoffs = userCutoffs
+	r.globalCutoff = globalCutoff
+	r.tokenVersions = tokenVersions
+	r.mu.Unlock()
+	return nil
+}
+
+// Run перечитывает список раз в reloadInterval и чистит истёкшие отзывы. Блокируется до отмены ctx
+func (r *Revocations) Run(ctx context.Context, reloadInterval time.Duration) error {
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.repo.DeleteExpiredTokens(ctx); err != nil {
+				slog.Error("failed to delete expired revoked tokens", "err", err)
+			}
+			if err := r.Reload(ctx); err != nil {
+				slog.Error("failed to reload revoked tokens", "err", err)
+			}
+		}
+	}
+}
+
+func (r *Revocations) applyCutoff(cutoff domain.RevocationCutoff) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mergeCutoff(r.userCutoffs, &r.globalCutoff, cutoff)
+}
+
+// mergeCutoff оставляет самое позднее отсечение для пользователя или для всех.
+// Отсечения из БД могли быть записаны до округления, поэтому округляются и здесь
+func mergeCutoff(userCutoffs map[int64]time.Time, globalCutoff *time.Time, cutoff domain.RevocationCutoff) {
+	cutoff.RevokedBefore = cutoff.RevokedBefore.Truncate(time.Second)
+	if cutoff.UserId == nil {
+		if cutoff.RevokedBefore.After(*globalCutoff) {
+			*globalCutoff = cutoff.RevokedBefore
+		}
+		return
+	}
+	if cutoff.RevokedBefore.After(userCutoffs[*cutoff.UserId]) {
+		userCutoffs[*cutoff.UserId] = cutoff.RevokedBefore
+	}
+}
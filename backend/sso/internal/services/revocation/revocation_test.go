@@ -0,0 +1,230 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	"github.com/EtoNeAnanasbI95/sso/internal/dto/admin"
+	revocationErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/revocation"
+)
+
+// fakeRepository отдаёт Reload заранее заданные записи, как их вернула бы БД
+type fakeRepository struct {
+	tokens   []domain.RevokedToken
+	cutoffs  []domain.RevocationCutoff
+	versions []domain.UserTokenVersion
+	err      error
+}
+
+func (f *fakeRepository) RevokeToken(context.Context, *domain.RevokedToken) error {
+	return f.err
+}
+
+func (f *fakeRepository) CreateCutoff(context.Context, *domain.RevocationCutoff) error {
+	return f.err
+}
+
+func (f *fakeRepository) ListActiveTokens(context.Context) ([]domain.RevokedToken, error) {
+	return f.tokens, f.err
+}
+
+func (f *fakeRepository) ListCutoffs(context.Context, time.Duration) ([]domain.RevocationCutoff, error) {
+	return f.cutoffs, f.err
+}
+
+func (f *fakeRepository) ListTokenVersions(context.Context) ([]domain.UserTokenVersion, error) {
+	return f.versions, f.err
+}
+
+func (f *fakeRepository) DeleteExpiredTokens(context.Context) error {
+	return f.err
+}
+
+func userId(id int64) *int64 {
+	return &id
+}
+
+func reloaded(t *testing.T, repo *fakeRepository) *Revocations {
+	t.Helper()
+	revocations := New(repo, time.Hour)
+	if err := revocations.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	return revocations
+}
+
+func TestIsRevoked(t *testing.T) {
+	// отсечения из БД с долями секунды: записаны до того, как их стали округлять
+	globalCutoff := time.Date(2026, 3, 1, 12, 0, 0, 700_000_000, time.UTC)
+	userCutoff := time.Date(2026, 3, 2, 12, 0, 0, 300_000_000, time.UTC)
+	revocations := reloaded(t, &fakeRepository{
+		tokens: []domain.RevokedToken{
+			{Jti: "revoked", ExpiresAt: time.Now().Add(time.Hour)},
+			{Jti: "expired", ExpiresAt: time.Now().Add(-time.Minute)},
+		},
+		cutoffs: []domain.RevocationCutoff{
+			{RevokedBefore: globalCutoff},
+			{UserId: userId(1), RevokedBefore: userCutoff},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		jti      string
+		userId   int64
+		issuedAt time.Time
+		want     bool
+	}{
+		{name: "revoked jti", jti: "revoked", userId: 2, issuedAt: time.Now(), want: true},
+		{name: "expired jti revocation", jti: "expired", userId: 2, issuedAt: time.Now()},
+		{name: "unknown jti", jti: "other", userId: 2, issuedAt: time.Now()},
+		{name: "before global cutoff", userId: 2, issuedAt: globalCutoff.Add(-time.Second), want: true},
+		// iat без долей секунды: токен, выпущенный в секунду отсечения, уже новый
+		{name: "same second as global cutoff", userId: 2, issuedAt: globalCutoff.Truncate(time.Second)},
+		{name: "same second with fraction", userId: 2, issuedAt: globalCutoff.Add(200 * time.Millisecond)},
+		{name: "before user cutoff", userId: 1, issuedAt: userCutoff.Add(-time.Second), want: true},
+		{name: "same second as user cutoff", userId: 1, issuedAt: userCutoff.Truncate(time.Second)},
+		{name: "user cutoff of another user", userId: 2, issuedAt: userCutoff.Add(-time.Second)},
+		{name: "after all cutoffs", userId: 1, issuedAt: userCutoff.Add(time.Second)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := revocations.IsRevoked(tt.jti, tt.userId, tt.issuedAt); got != tt.want {
+				t.Errorf("IsRevoked = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadKeepsLatestCutoff(t *testing.T) {
+	early := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+	tests := []struct {
+		name    string
+		cutoffs []domain.RevocationCutoff
+		userId  int64
+	}{
+		{
+			name:    "global",
+			cutoffs: []domain.RevocationCutoff{{RevokedBefore: late}, {RevokedBefore: early}},
+			userId:  1,
+		},
+		{
+			name: "per user",
+			cutoffs: []domain.RevocationCutoff{
+				{UserId: userId(1), RevokedBefore: early},
+				{UserId: userId(1), RevokedBefore: late},
+				{UserId: userId(2), RevokedBefore: early},
+			},
+			userId: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revocations := reloaded(t, &fakeRepository{cutoffs: tt.cutoffs})
+			if !revocations.IsRevoked("", tt.userId, late.Add(-time.Second)) {
+				t.Error("token issued before the latest cutoff is accepted")
+			}
+			if revocations.IsRevoked("", tt.userId, late) {
+				t.Error("token issued at the latest cutoff is revoked")
+			}
+		})
+	}
+}
+
+func TestReloadReplacesState(t *testing.T) {
+	repo := &fakeRepository{
+		tokens:   []domain.RevokedToken{{Jti: "revoked", ExpiresAt: time.Now().Add(time.Hour)}},
+		cutoffs:  []domain.RevocationCutoff{{UserId: userId(1), RevokedBefore: time.Now()}},
+		versions: []domain.UserTokenVersion{{UserId: 1, TokenVersion: 3}},
+	}
+	revocations := reloaded(t, repo)
+	issuedAt := time.Now().Add(-time.Minute)
+
+	if !revocations.IsRevoked("revoked", 2, time.Now()) || !revocations.IsRevoked("", 1, issuedAt) {
+		t.Fatal("revocations from the repository are not applied")
+	}
+	if !revocations.IsOutdated(1, 2) || revocations.IsOutdated(1, 3) {
+		t.Fatal("token versions from the repository are not applied")
+	}
+
+	// записи, которых больше нет в БД, пропадают и из памяти
+	*repo = fakeRepository{}
+	if err := revocations.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if revocations.IsRevoked("revoked", 2, time.Now()) || revocations.IsRevoked("", 1, issuedAt) {
+		t.Error("revocations removed from the repository are still applied")
+	}
+	if revocations.IsOutdated(1, 2) {
+		t.Error("token version removed from the repository is still applied")
+	}
+}
+
+func TestReloadErrorKeepsState(t *testing.T) {
+	repo := &fakeRepository{tokens: []domain.RevokedToken{{Jti: "revoked", ExpiresAt: time.Now().Add(time.Hour)}}}
+	revocations := reloaded(t, repo)
+
+	repo.err = errors.New("connection refused")
+	if err := revocations.Reload(context.Background()); err == nil {
+		t.Fatal("Reload error is swallowed")
+	}
+	if !revocations.IsRevoked("revoked", 2, time.Now()) {
+		t.Error("failed Reload dropped the revocation list")
+	}
+}
+
+func TestRevokeCutoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		request admin.RevokeTokensRequest
+		userId  int64
+		wantErr error
+		revoked bool
+	}{
+		{name: "all tokens of user", request: admin.RevokeTokensRequest{UserID: userId(1)}, userId: 1, revoked: true},
+		{name: "other user untouched", request: admin.RevokeTokensRequest{UserID: userId(1)}, userId: 2},
+		{name: "global", request: admin.RevokeTokensRequest{IssuedBefore: timePtr(time.Now())}, userId: 2, revoked: true},
+		{
+			name:    "issued_before in future",
+			request: admin.RevokeTokensRequest{UserID: userId(1), IssuedBefore: timePtr(time.Now().Add(time.Hour))},
+			userId:  1,
+			wantErr: revocationErrors.ErrIssuedBeforeInFuture,
+		},
+		{
+			name:    "jti with user",
+			request: admin.RevokeTokensRequest{Jti: "jti", UserID: userId(1)},
+			userId:  1,
+			wantErr: revocationErrors.ErrAmbiguousRevocation,
+		},
+		{name: "empty", userId: 1, wantErr: revocationErrors.ErrEmptyRevocationRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revocations := New(&fakeRepository{}, time.Hour)
+			issuedAt := time.Now().Add(-time.Minute)
+
+			err := revocations.Revoke(context.Background(), tt.request, 99)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Revoke error = %v, want %v", err, tt.wantErr)
+			}
+			if got := revocations.IsRevoked("", tt.userId, issuedAt); got != tt.revoked {
+				t.Errorf("IsRevoked = %v, want %v", got, tt.revoked)
+			}
+			// новый вход после отзыва не должен отклоняться
+			if revocations.IsRevoked("", tt.userId, time.Now().Add(time.Second)) {
+				t.Error("token issued after the revocation is revoked")
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	sessionModels "github.com/EtoNeAnanasbI95/sso/internal/dto/session"
+	authErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/auth"
+	sessionErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/session"
+)
+
+type Repository interface {
+	ListActiveSessions(ctx context.Context, userId int64) ([]domain.Session, error)
+	RevokeSession(ctx context.Context, userId int64, sessionId string) (bool, error)
+	RevokeAllSessions(ctx context.Context, userId int64) error
+	ForceLogout(ctx context.Context, userId int64) (int64, bool, error)
+}
+
+// TokenVersions применяет новую версию токенов пользователя к проверке access токенов
+type TokenVersions interface {
+	SetTokenVersion(userId int64, tokenVersion int64)
+}
+
+// Sessions — просмотр и завершение сессий пользователем и администратором
+type Sessions struct {
+	repo     Repository
+	versions TokenVersions
+}
+
+func New(repo Repository, versions TokenVersions) *Sessions {
+	return &Sessions{
+		repo:     repo,
+		versions: versions,
+	}
+}
+
+// List возвращает активные сессии пользователя, currentId помечает сессию текущего запроса
+func (s *Sessions) List(ctx context.Context, userId int64, currentId string) ([]sessionModels.SessionResponse, error) {
+	sessions, err := s.repo.ListActiveSessions(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]sessionModels.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, sessionModels.SessionResponse{
+			ID:              session.Id,
+			ClientID:        session.ClientId,
+			Device:          session.Device,
+			UserAgent:       session.UserAgent,
+			IpAddress:       session.IpAddress,
+			CreatedAt:       session.CreatedAt,
+			LastRefreshedAt: session.LastRefreshedAt,
+			ExpiresAt:       session.ExpiresAt,
+			Current:         currentId != "" && session.Id == currentId,
+		})
+	}
+	return result, nil
+}
+
+// Revoke завершает одну сессию пользователя, её refresh токен после этого не принимается
+func (s *Sessions) Revoke(ctx context.Context, userId int64, sessionId string) error {
+	if !domain.IsUUID(sessionId) {
+		return sessionErrors.ErrSessionNotFound
+	}
+	revoked, err := s.repo.RevokeSession(ctx, userId, sessionId)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return sessionErrors.ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAll завершает все сессии пользователя, включая текущую
+func (s *Sessions) RevokeAll(ctx context.Context, userId int64) error {
+	return s.repo.RevokeAllSessions(ctx, userId)
+}
+
+// ForceLogout по запросу администратора adminId завершает все сессии пользователя
+// и делает недействительными все его уже выданные токены, включая access
+func (s *Sessions) ForceLogout(ctx context.Context, userId int64, adminId int64) error {
+	const op = "Sessions.ForceLogout"
+
+	version, found, err := s.repo.ForceLogout(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return authErrors.ErrUserNotFound
+	}
+	s.versions.SetTokenVersion(userId, version)
+
+	slog.Info("user sessions terminated",
+		"op", op,
+		"admin_id", adminId,
+		"user_id", userId,
+		"token_version", version,
+	)
+	return nil
+}
@@ -0,0 +1,332 @@
+package webauthn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/internal/domain"
+	webauthnModels "github.com/EtoNeAnanasbI95/sso/internal/dto/webauthn"
+	authErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/auth"
+	webauthnErrors "github.com/EtoNeAnanasbI95/sso/internal/errors/webauthn"
+	libwebauthn "github.com/EtoNeAnanasbI95/sso/internal/lib/webauthn"
+)
+
+type Repository interface {
+	CreateCredential(ctx context.Context, credential *domain.WebAuthnCredential) (bool, error)
+	GetCredential(ctx context.Context, credentialId []byte) (*domain.WebAuthnCredential, error)
+	ListCredentials(ctx context.Context, userId int64) ([]domain.WebAuthnCredential, error)
+	UseCredential(ctx context.Context, id int64, previousCount, signCount int64) (bool, error)
+	DeleteCredential(ctx context.Context, userId, id int64) (bool, error)
+	CreateCeremony(ctx context.Context, ceremony *domain.WebAuthnCeremony) error
+	ConsumeCeremony(ctx context.Context, challengeHash []byte, kind string) (*domain.WebAuthnCeremony, error)
+	DeleteExpiredCeremonies(ctx context.Context) error
+}
+
+type Users interface {
+	GetUserWithId(ctx context.Context, uid int64) (*domain.User, error)
+}
+
+// Events — журнал событий аутентификации
+type Events interface {
+	Record(ctx context.Context, event *domain.AuthEvent) error
+}
+
+// Service — ключи WebAuthn: регистрация, вход по passkey без пароля и ключ как второй фактор.
+// Токены сервис не выпускает: он только проверяет церемонии, вход завершает сервис auth.
+// rp == nil означает, что WebAuthn не настроен
+type Service struct {
+	repo   Repository
+	users  Users
+	events Events
+	rp     *libwebauthn.RelyingParty
+}
+
+func New(repo Repository, users Users, events Events, rp *libwebauthn.RelyingParty) *Service {
+	return &Service{
+		repo:   repo,
+		users:  users,
+		events: events,
+		rp:     rp,
+	}
+}
+
+// BeginRegistration начинает регистрацию нового ключа вошедшего пользователя
+func (s *Service) BeginRegistration(ctx context.Context, userId int64) (*libwebauthn.CreationOptions, error) {
+	if s.rp == nil {
+		return nil, webauthnErrors.ErrNotConfigured
+	}
+	user, err := s.users.GetUserWithId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, authErrors.ErrUserNotFound
+	}
+	credentials, err := s.repo.ListCredentials(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.newCeremony(ctx, domain.WebAuthnCeremonyRegistration, &userId, "")
+	if err != nil {
+		return nil, err
+	}
+	displayName := user.FullName
+	if displayName == "" {
+		displayName = user.Login
+	}
+	entity := libwebauthn.UserEntity{
+		ID:          domain.WebAuthnUserHandle(userId),
+		Name:        user.Login,
+		DisplayName: displayName,
+	}
+	return s.rp.CreationOptions(challenge, entity, descriptors(credentials), libwebauthn.UserVerificationPreferred), nil
+}
+
+// FinishRegistration проверяет ответ аутентификатора и сохраняет ключ
+func (s *Service) FinishRegistration(ctx context.Context, userId int64, request webauthnModels.RegisterRequest, meta domain.SessionMeta) (*webauthnModels.CredentialResponse, error) {
+	if s.rp == nil {
+		return nil, webauthnErrors.ErrNotConfigured
+	}
+	ceremony, challenge, err := s.consumeCeremony(ctx, request.Credential.Response.ClientDataJSON, domain.WebAuthnCeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if ceremony.UserId == nil || *ceremony.UserId != userId {
+		return nil, webauthnErrors.ErrInvalidCeremony
+	}
+
+	verified, err := s.rp.VerifyRegistration(&request.Credential, challenge, false)
+	if err != nil {
+		slog.Warn("webauthn registration rejected", "user_id", userId, "err", err)
+		return nil, webauthnErrors.ErrVerificationFailed
+	}
+	credential := &domain.WebAuthnCredential{
+		UserId:         userId,
+		CredentialId:   verified.ID,
+		PublicKey:      verified.PublicKey,
+		SignCount:      int64(verified.SignCount),
+		Transports:     verified.Transports,
+		Name:           request.Name,
+		BackupEligible: verified.BackupEligible,
+		CreatedAt:      time.Now(),
+	}
+	created, err := s.repo.CreateCredential(ctx, credential)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, webauthnErrors.ErrCredentialExists
+	}
+	if err := s.events.Record(ctx, domain.NewAuthEvent(userId, domain.AuthEventWebAuthnRegistered, "", meta)); err != nil {
+		return nil, err
+	}
+	slog.Info("webauthn credential registered", "user_id", userId, "credential", credential.Id)
+
+	response := credentialResponse(*credential)
+	return &response, nil
+}
+
+// Credentials возвращает ключи пользователя
+func (s *Service) Credentials(ctx context.Context, userId int64) ([]webauthnModels.CredentialResponse, error) {
+	credentials, err := s.repo.ListCredentials(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]webauthnModels.CredentialResponse, 0, len(credentials))
+	for _, credential := range credentials {
+		result = append(result, credentialResponse(credential))
+	}
+	return result, nil
+}
+
+// DeleteCredential удаляет ключ пользователя
+func (s *Service) DeleteCredential(ctx context.Context, userId, id int64, meta domain.SessionMeta) error {
+	deleted, err := s.repo.DeleteCredential(ctx, userId, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return webauthnErrors.ErrCredentialNotFound
+	}
+	return s.events.Record(ctx, domain.NewAuthEvent(userId, domain.AuthEventWebAuthnRemoved, "", meta))
+}
+
+// BeginLogin начинает вход по passkey без логина и пароля. clientId — уже проверенный клиент,
+// он запоминается в церемонии и возвращается VerifyLogin
+func (s *Service) BeginLogin(ctx context.Context, clientId string) (*libwebauthn.RequestOptions, error) {
+	if s.rp == nil {
+		return nil, webauthnErrors.ErrNotConfigured
+	}
+	challenge, err := s.newCeremony(ctx, domain.WebAuthnCeremonyLogin, nil, clientId)
+	if err != nil {
+		return nil, err
+	}
+	return s.rp.RequestOptions(challenge, nil, libwebauthn.UserVerificationRequired), nil
+}
+
+// VerifyLogin проверяет ответ passkey и возвращает пользователя и клиента входа. Требуется проверка
+// пользователя (PIN или биометрия): вместе с владением устройством это уже два фактора
+func (s *Service) VerifyLogin(ctx context.Context, response libwebauthn.AssertionResponse) (int64, string, error) {
+	if s.rp == nil {
+		return 0, "", webauthnErrors.ErrNotConfigured
+	}
+	ceremony, credential, err := s.verifyAssertion(ctx, &response, domain.WebAuthnCeremonyLogin, true)
+	if err != nil {
+		return 0, "", err
+	}
+	return credential.UserId, ceremony.ClientId, nil
+}
+
+// HasCredentials сообщает, что у пользователя есть ключи: тогда они служат вторым фактором при входе по паролю
+func (s *Service) HasCredentials(ctx context.Context, userId int64) (bool, error) {
+	if s.rp == nil {
+		return false, nil
+	}
+	credentials, err := s.repo.ListCredentials(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	return len(credentials) > 0, nil
+}
+
+// BeginSecondFactor начинает проверку ключа пользователя, уже предъявившего пароль
+func (s *Service) BeginSecondFactor(ctx context.Context, userId int64) (*libwebauthn.RequestOptions, error) {
+	if s.rp == nil {
+		return nil, webauthnErrors.ErrNotConfigured
+	}
+	credentials, err := s.repo.ListCredentials(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if len(credentials) == 0 {
+		return nil, webauthnErrors.ErrCredentialNotFound
+	}
+	challenge, err := s.newCeremony(ctx, domain.WebAuthnCeremonySecondFactor, &userId, "")
+	if err != nil {
+		return nil, err
+	}
+	return s.rp.RequestOptions(challenge, descriptors(credentials), libwebauthn.UserVerificationPreferred), nil
+}
+
+// VerifySecondFactor проверяет, что ответ подписан ключом пользователя userId
+func (s *Service) VerifySecondFactor(ctx context.Context, userId int64, response libwebauthn.AssertionResponse) error {
+	if s.rp == nil {
+		return webauthnErrors.ErrNotConfigured
+	}
+	ceremony, _, err := s.verifyAssertion(ctx, &response, domain.WebAuthnCeremonySecondFactor, false)
+	if err != nil {
+		return err
+	}
+	if ceremony.UserId == nil || *ceremony.UserId != userId {
+		return webauthnErrors.ErrInvalidCeremony
+	}
+	return nil
+}
+
+// Run раз в interval удаляет брошенные церемонии. Блокируется до отмены ctx
+func (s *Service) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.repo.DeleteExpiredCeremonies(ctx); err != nil {
+				slog.Error("failed to delete expired webauthn ceremonies", "err", err)
+			}
+		}
+	}
+}
+
+// verifyAssertion находит церемонию и ключ по ответу аутентификатора, проверяет подпись и сдвигает счётчик
+func (s *Service) verifyAssertion(ctx context.Context, response *libwebauthn.AssertionResponse, kind string, requireUserVerification bool) (*domain.WebAuthnCeremony, *domain.WebAuthnCredential, error) {
+	ceremony, challenge, err := s.consumeCeremony(ctx, response.Response.ClientDataJSON, kind)
+	if err != nil {
+		return nil, nil, err
+	}
+	credential, err := s.repo.GetCredential(ctx, response.RawID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if credential == nil || (ceremony.UserId != nil && *ceremony.UserId != credential.UserId) {
+		return nil, nil, webauthnErrors.ErrCredentialNotFound
+	}
+	if len(response.Response.UserHandle) > 0 && !bytes.Equal(response.Response.UserHandle, domain.WebAuthnUserHandle(credential.UserId)) {
+		return nil, nil, webauthnErrors.ErrVerificationFailed
+	}
+
+	signCount, err := s.rp.VerifyAssertion(response, challenge, credential.PublicKey, uint32(credential.SignCount), requireUserVerification)
+	if err != nil {
+		if errors.Is(err, libwebauthn.ErrSignCountRegression) {
+			slog.Warn("webauthn signature counter regression, credential may be cloned", "user_id", credential.UserId, "credential", credential.Id)
+		} else {
+			slog.Warn("webauthn assertion rejected", "user_id", credential.UserId, "err", err)
+		}
+		return nil, nil, webauthnErrors.ErrVerificationFailed
+	}
+	used, err := s.repo.UseCredential(ctx, credential.Id, credential.SignCount, int64(signCount))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !used {
+		return nil, nil, webauthnErrors.ErrVerificationFailed
+	}
+	return ceremony, credential, nil
+}
+
+func (s *Service) newCeremony(ctx context.Context, kind string, userId *int64, clientId string) ([]byte, error) {
+	challenge, err := libwebauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	ceremony := domain.NewWebAuthnCeremony(kind, userId, clientId, challenge, s.rp.Timeout())
+	if err := s.repo.CreateCeremony(ctx, ceremony); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// consumeCeremony находит церемонию по вызову из clientDataJSON и завершает её: повторить ответ нельзя
+func (s *Service) consumeCeremony(ctx context.Context, clientDataJSON []byte, kind string) (*domain.WebAuthnCeremony, []byte, error) {
+	challenge, err := libwebauthn.ClientChallenge(clientDataJSON)
+	if err != nil {
+		return nil, nil, webauthnErrors.ErrInvalidCeremony
+	}
+	ceremony, err := s.repo.ConsumeCeremony(ctx, domain.HashWebAuthnChallenge(challenge), kind)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ceremony == nil {
+		return nil, nil, webauthnErrors.ErrInvalidCeremony
+	}
+	return ceremony, challenge, nil
+}
+
+func descriptors(credentials []domain.WebAuthnCredential) []libwebauthn.CredentialDescriptor {
+	result := make([]libwebauthn.CredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		result = append(result, libwebauthn.Descriptor(credential.CredentialId, credential.Transports))
+	}
+	return result
+}
+
+func credentialResponse(credential domain.WebAuthnCredential) webauthnModels.CredentialResponse {
+	transports := credential.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+	return webauthnModels.CredentialResponse{
+		ID:             credential.Id,
+		Name:           credential.Name,
+		Transports:     transports,
+		BackupEligible: credential.BackupEligible,
+		CreatedAt:      credential.CreatedAt,
+		LastUsedAt:     credential.LastUsedAt,
+	}
+}
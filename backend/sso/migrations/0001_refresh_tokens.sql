@@ -0,0 +1,17 @@
+-- Серверное хранилище refresh токенов.
+-- Токены одной цепочки ротации объединены family_id; повторное предъявление уже ротированного
+-- токена отзывает всю цепочку.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id         BIGSERIAL PRIMARY KEY,
+    family_id  UUID        NOT NULL,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash BYTEA       NOT NULL UNIQUE,
+    parent_id  BIGINT      REFERENCES refresh_tokens (id) ON DELETE SET NULL,
+    issued_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    rotated_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_id_idx ON refresh_tokens (user_id);
@@ -0,0 +1,25 @@
+-- Отзыв токенов до истечения срока жизни.
+-- revoked_tokens — отзыв конкретного токена по jti, строка нужна только до expires_at.
+-- token_revocation_cutoffs — все токены, выпущенные раньше revoked_before, недействительны:
+-- для одного пользователя (user_id) или для всех (user_id IS NULL).
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti        TEXT PRIMARY KEY,
+    user_id    BIGINT      REFERENCES users (id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_by BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    reason     TEXT
+);
+
+CREATE INDEX IF NOT EXISTS revoked_tokens_expires_at_idx ON revoked_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS token_revocation_cutoffs (
+    id             BIGSERIAL PRIMARY KEY,
+    user_id        BIGINT      REFERENCES users (id) ON DELETE CASCADE,
+    revoked_before TIMESTAMPTZ NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_by     BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    reason         TEXT
+);
+
+CREATE INDEX IF NOT EXISTS token_revocation_cutoffs_user_id_idx ON token_revocation_cutoffs (user_id);
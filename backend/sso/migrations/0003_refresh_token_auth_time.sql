@@ -0,0 +1,15 @@
+-- Момент исходной аутентификации сессии. Переносится на каждый ротированный токен цепочки
+-- и ограничивает абсолютный срок жизни сессии.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS auth_time TIMESTAMPTZ;
+
+-- для уже выданных цепочек берём время выпуска первого токена
+UPDATE refresh_tokens rt
+SET auth_time = family.started_at
+FROM (
+    SELECT family_id, min(issued_at) AS started_at
+    FROM refresh_tokens
+    GROUP BY family_id
+) family
+WHERE rt.family_id = family.family_id AND rt.auth_time IS NULL;
+
+ALTER TABLE refresh_tokens ALTER COLUMN auth_time SET NOT NULL;
@@ -0,0 +1,16 @@
+-- Активные сессии пользователей. id совпадает с family_id цепочки refresh токенов.
+-- Для цепочек, выданных до миграции, запись появится при первом обновлении токенов.
+CREATE TABLE IF NOT EXISTS sessions (
+    id                UUID PRIMARY KEY,
+    user_id           BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    client_id         TEXT        NOT NULL DEFAULT '',
+    device            TEXT        NOT NULL DEFAULT '',
+    user_agent        TEXT        NOT NULL DEFAULT '',
+    ip_address        TEXT        NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_refreshed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at        TIMESTAMPTZ NOT NULL,
+    revoked_at        TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id) WHERE revoked_at IS NULL;
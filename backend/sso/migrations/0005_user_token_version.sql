@@ -0,0 +1,5 @@
+-- Версия токенов пользователя. Принудительный выход администратором увеличивает её,
+-- и все токены с меньшей версией (claim ver) перестают приниматься.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS users_token_version_idx ON users (id) WHERE token_version > 0;
@@ -0,0 +1,17 @@
+-- Одноразовые коды OIDC authorization code flow (PKCE). Хранится только sha256 от кода.
+CREATE TABLE IF NOT EXISTS authorization_codes (
+    id             BIGSERIAL PRIMARY KEY,
+    code_hash      BYTEA       NOT NULL UNIQUE,
+    client_id      TEXT        NOT NULL,
+    user_id        BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    redirect_uri   TEXT        NOT NULL,
+    scope          TEXT        NOT NULL,
+    nonce          TEXT        NOT NULL DEFAULT '',
+    code_challenge TEXT        NOT NULL,
+    auth_time      TIMESTAMPTZ NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at     TIMESTAMPTZ NOT NULL,
+    consumed_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS authorization_codes_expires_at_idx ON authorization_codes (expires_at);
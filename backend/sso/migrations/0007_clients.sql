@@ -0,0 +1,18 @@
+-- Реестр OAuth клиентов. secret_hash (bcrypt) пуст у публичных клиентов.
+-- Сроки жизни в секундах, 0 — срок из конфигурации SSO; клиент может их только сократить.
+CREATE TABLE IF NOT EXISTS clients (
+    id                      TEXT PRIMARY KEY,
+    name                    TEXT        NOT NULL DEFAULT '',
+    secret_hash             BYTEA,
+    audience                TEXT[]      NOT NULL DEFAULT '{}',
+    redirect_uris           TEXT[]      NOT NULL DEFAULT '{}',
+    allowed_origins         TEXT[]      NOT NULL DEFAULT '{}',
+    grant_types             TEXT[]      NOT NULL DEFAULT '{}',
+    scopes                  TEXT[]      NOT NULL DEFAULT '{}',
+    access_ttl_seconds      BIGINT      NOT NULL DEFAULT 0,
+    refresh_ttl_seconds     BIGINT      NOT NULL DEFAULT 0,
+    max_session_ttl_seconds BIGINT      NOT NULL DEFAULT 0,
+    is_disabled             BOOLEAN     NOT NULL DEFAULT false,
+    created_at              TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at              TIMESTAMPTZ NOT NULL DEFAULT now()
+);
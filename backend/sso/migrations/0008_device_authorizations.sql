@@ -0,0 +1,17 @@
+-- Запросы авторизации устройств (RFC 8628). Хранится только sha256 от device_code.
+CREATE TABLE IF NOT EXISTS device_authorizations (
+    id               BIGSERIAL PRIMARY KEY,
+    device_code_hash BYTEA       NOT NULL UNIQUE,
+    user_code        TEXT        NOT NULL UNIQUE,
+    client_id        TEXT        NOT NULL,
+    scope            TEXT        NOT NULL DEFAULT '',
+    status           TEXT        NOT NULL DEFAULT 'pending',
+    user_id          BIGINT REFERENCES users (id) ON DELETE CASCADE,
+    auth_time        TIMESTAMPTZ,
+    interval_seconds BIGINT      NOT NULL,
+    last_polled_at   TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at       TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS device_authorizations_expires_at_idx ON device_authorizations (expires_at);
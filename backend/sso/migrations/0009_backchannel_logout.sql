@@ -0,0 +1,27 @@
+-- OIDC back-channel logout: адрес уведомлений клиента, связь сессий клиентов с сессией SSO в браузере
+-- и очередь уведомлений с повторами.
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS backchannel_logout_uri TEXT NOT NULL DEFAULT '';
+
+-- sso_session_id — сессия SSO (cookie refresh_token), из которой сессия клиента получена через /authorize.
+-- Выход из любой сессии этой группы завершает всю группу.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS sso_session_id UUID;
+CREATE INDEX IF NOT EXISTS sessions_sso_session_id_idx ON sessions (sso_session_id) WHERE revoked_at IS NULL;
+
+ALTER TABLE authorization_codes ADD COLUMN IF NOT EXISTS session_id UUID;
+
+CREATE TABLE IF NOT EXISTS logout_notifications (
+    id              BIGSERIAL PRIMARY KEY,
+    client_id       TEXT        NOT NULL,
+    logout_uri      TEXT        NOT NULL,
+    user_id         BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    session_id      UUID        NOT NULL,
+    attempts        INT         NOT NULL DEFAULT 0,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at    TIMESTAMPTZ,
+    failed_at       TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS logout_notifications_pending_idx ON logout_notifications (next_attempt_at)
+    WHERE delivered_at IS NULL AND failed_at IS NULL;
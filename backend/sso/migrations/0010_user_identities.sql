@@ -0,0 +1,29 @@
+-- Вход через внешних провайдеров удостоверений: связи пользователей с учётными записями провайдеров
+-- и незавершённые входы (state, nonce и PKCE code_verifier между редиректом и callback).
+CREATE TABLE IF NOT EXISTS user_identities (
+    id            BIGSERIAL PRIMARY KEY,
+    provider      TEXT        NOT NULL,
+    subject       TEXT        NOT NULL,
+    user_id       BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    login         TEXT        NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_login_at TIMESTAMPTZ,
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
+
+CREATE TABLE IF NOT EXISTS upstream_logins (
+    id            BIGSERIAL PRIMARY KEY,
+    state_hash    BYTEA       NOT NULL UNIQUE,
+    provider      TEXT        NOT NULL,
+    client_id     TEXT        NOT NULL,
+    nonce         TEXT        NOT NULL,
+    code_verifier TEXT        NOT NULL,
+    return_to     TEXT        NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at    TIMESTAMPTZ NOT NULL,
+    consumed_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS upstream_logins_expires_at_idx ON upstream_logins (expires_at);
@@ -0,0 +1,22 @@
+-- Двухфакторная аутентификация: TOTP секреты пользователей (зашифрованы AES-GCM ключом mfa.encryption_key)
+-- и незавершённые входы, ожидающие второго фактора. Хранится только sha256 от токена входа.
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id          BIGINT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret_encrypted BYTEA       NOT NULL,
+    confirmed_at     TIMESTAMPTZ,
+    last_used_step   BIGINT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id          BIGSERIAL PRIMARY KEY,
+    token_hash  BYTEA       NOT NULL UNIQUE,
+    user_id     BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    client_id   TEXT        NOT NULL,
+    attempts    INT         NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at  TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS mfa_challenges_expires_at_idx ON mfa_challenges (expires_at);
@@ -0,0 +1,25 @@
+-- Одноразовые коды восстановления второго фактора. Хранится только bcrypt от кода, использованный код
+-- помечается used_at. Новый набор заменяет прежний целиком.
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  BYTEA       NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    used_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS mfa_recovery_codes_user_id_idx ON mfa_recovery_codes (user_id) WHERE used_at IS NULL;
+
+-- Журнал событий аутентификации пользователя: подключение и отключение второго фактора, использование
+-- кодов восстановления и т.п. Записи не изменяются.
+CREATE TABLE IF NOT EXISTS auth_events (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    event_type TEXT        NOT NULL,
+    client_id  TEXT        NOT NULL DEFAULT '',
+    user_agent TEXT        NOT NULL DEFAULT '',
+    ip_address TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS auth_events_user_id_idx ON auth_events (user_id, created_at DESC);
@@ -0,0 +1,29 @@
+-- Ключи WebAuthn (passkey и аппаратные ключи) пользователей: публичный ключ в формате COSE_Key и счётчик подписей.
+-- Ключ используется и для входа без пароля, и как второй фактор после пароля.
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    credential_id   BYTEA       NOT NULL UNIQUE,
+    public_key      BYTEA       NOT NULL,
+    sign_count      BIGINT      NOT NULL DEFAULT 0,
+    transports      TEXT[]      NOT NULL DEFAULT '{}',
+    name            TEXT        NOT NULL DEFAULT '',
+    backup_eligible BOOLEAN     NOT NULL DEFAULT false,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+
+-- Начатые церемонии регистрации и входа. Хранится только sha256 от вызова, запись удаляется при завершении.
+CREATE TABLE IF NOT EXISTS webauthn_ceremonies (
+    id             BIGSERIAL PRIMARY KEY,
+    challenge_hash BYTEA       NOT NULL UNIQUE,
+    kind           TEXT        NOT NULL,
+    user_id        BIGINT REFERENCES users (id) ON DELETE CASCADE,
+    client_id      TEXT        NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_ceremonies_expires_at_idx ON webauthn_ceremonies (expires_at);
@@ -0,0 +1,6 @@
+-- Способы аутентификации (claim amr) через пробел. Переносятся вместе с auth_time на каждый ротированный
+-- токен цепочки, в код авторизации и в запрос устройства, чтобы из них можно было выпустить токены с тем же amr и acr.
+-- У входов до этой миграции способ неизвестен: их amr пуст, acr — низший уровень.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS amr TEXT NOT NULL DEFAULT '';
+ALTER TABLE authorization_codes ADD COLUMN IF NOT EXISTS amr TEXT NOT NULL DEFAULT '';
+ALTER TABLE device_authorizations ADD COLUMN IF NOT EXISTS amr TEXT NOT NULL DEFAULT '';
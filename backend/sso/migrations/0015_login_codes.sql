@@ -0,0 +1,28 @@
+-- Вход без пароля по одноразовому коду, отправленному в Telegram или на почту.
+-- token_hash — хэш токена запроса, который получает клиент; code_hash — хэш кода вместе с токеном.
+CREATE TABLE IF NOT EXISTS login_codes (
+    id          BIGSERIAL PRIMARY KEY,
+    token_hash  BYTEA       NOT NULL UNIQUE,
+    code_hash   BYTEA       NOT NULL,
+    user_id     BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    client_id   TEXT        NOT NULL,
+    attempts    INT         NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at  TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS login_codes_user_id_created_at_idx ON login_codes (user_id, created_at);
+CREATE INDEX IF NOT EXISTS login_codes_expires_at_idx ON login_codes (expires_at);
+
+-- Ограничение частоты запросов кода входа по логину, а не по пользователю: оно срабатывает одинаково
+-- для существующих и несуществующих логинов. login_hash — SHA-256 нормализованного логина.
+CREATE TABLE IF NOT EXISTS login_code_requests (
+    login_hash   BYTEA PRIMARY KEY,
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS login_code_requests_requested_at_idx ON login_code_requests (requested_at);
+
+-- Второй шаг входа помнит, чем пройден первый: паролем или одноразовым кодом.
+ALTER TABLE mfa_challenges ADD COLUMN IF NOT EXISTS amr TEXT NOT NULL DEFAULT '';
@@ -4,4 +4,13 @@ type CtxKey string
 
 const RequestIDCtxKey CtxKey = "request_id"
 const TraceIDCtxKey CtxKey = "trace_id"
-const UserIDCtxKey CtxKey = "user_id"
\ No newline at end of file
+const UserIDCtxKey CtxKey = "user_id"
+const RoleCtxKey CtxKey = "role"
+const SessionIDCtxKey CtxKey = "session_id"
+const PrincipalTypeCtxKey CtxKey = "principal_type"
+const ClientIDCtxKey CtxKey = "client_id"
+const ScopesCtxKey CtxKey = "scopes"
+const AuthTimeCtxKey CtxKey = "auth_time"
+const AmrCtxKey CtxKey = "amr"
+const AcrCtxKey CtxKey = "acr"
+const AudienceCtxKey CtxKey = "aud"
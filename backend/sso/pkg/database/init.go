@@ -2,10 +2,11 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/EtoNeAnanasbI95/sso/pkg/contextkeys"
 )
 
@@ -55,3 +56,8 @@ func WithUserTransaction[T any](db *sqlx.DB, ctx context.Context, f func(tx *sql
 	return result, nil
 }
 
+// IsUniqueViolation сообщает, что запрос нарушил уникальный индекс
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,28 @@
+package echomiddleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/EtoNeAnanasbI95/sso/pkg/contextkeys"
+	"github.com/labstack/echo/v4"
+)
+
+// RequireAudience пропускает только токены, в aud которых есть audience: токен, выпущенный для другого клиента,
+// проходит JwtValidation, но не предназначен для этого API. Пустой audience не проверяется. Ставится после JwtValidation
+func RequireAudience(audience string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if audience == "" {
+				return next(c)
+			}
+			accepted, _ := c.Request().Context().Value(contextkeys.AudienceCtxKey).([]string)
+			if !slices.Contains(accepted, audience) {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "token audience not accepted",
+				})
+			}
+			return next(c)
+		}
+	}
+}
@@ -4,24 +4,82 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"time"
 
-	"github.com/labstack/echo/v4"
 	"github.com/EtoNeAnanasbI95/sso/pkg/contextkeys"
+	"github.com/labstack/echo/v4"
+)
+
+// Кому принадлежит токен
+const (
+	PrincipalUser    = "user"
+	PrincipalService = "service"
 )
 
+// TokenClaims — то, что middleware нужно знать о проверенном access токене
+type TokenClaims struct {
+	ID string
+	// PrincipalType — PrincipalUser или PrincipalService
+	PrincipalType string
+	UserID        int64
+	Role          string
+	ClientID      string
+	// Audience — aud токена: кому он предназначен
+	Audience []string
+	// Scopes — разрешения сервисного токена
+	Scopes    []string
+	SessionID string
+	// TokenVersion сравнивается с текущей версией токенов пользователя
+	TokenVersion int64
+	IssuedAt     time.Time
+	// AuthTime — когда пользователь ввёл учётные данные
+	AuthTime time.Time
+	// Amr — способы аутентификации, Acr — их уровень
+	Amr []string
+	Acr string
+}
+
 type Jwt interface {
-	ParseToken(tokenString string) (int64, error)
+	ParseToken(tokenString string) (*TokenClaims, error)
+}
+
+// Revocations — список отозванных токенов и версии токенов пользователей
+type Revocations interface {
+	IsRevoked(jti string, userId int64, issuedAt time.Time) bool
+	IsOutdated(userId int64, tokenVersion int64) bool
 }
 
-func JwtValidation(jwt Jwt) echo.MiddlewareFunc {
+func JwtValidation(jwt Jwt, revocations Revocations) echo.MiddlewareFunc {
 	skip := map[string]struct{}{
-		"/auth/logIn":           {},
-		"/auth/signUp":          {},
-		"/auth/refresh":         {},
-		"/auth/password/request": {},
-		"/auth/password/complete": {},
-		"/health":               {},
-		"/swagger/*":            {},
+		"/auth/logIn":                       {},
+		"/auth/signUp":                      {},
+		"/auth/mfa/verify":                  {},
+		"/auth/mfa/webauthn/options":        {},
+		"/auth/webauthn/login/options":      {},
+		"/auth/webauthn/login":              {},
+		"/auth/code/request":                {},
+		"/auth/code/verify":                 {},
+		"/auth/telegram":                    {},
+		"/auth/telegram/webapp":             {},
+		"/auth/refresh":                     {},
+		"/auth/password/request":            {},
+		"/auth/password/complete":           {},
+		"/auth/external":                    {},
+		"/auth/external/:provider/login":    {},
+		"/auth/external/:provider/callback": {},
+		"/health":                           {},
+		"/.well-known/jwks.json":            {},
+		"/oauth/introspect":                 {},
+		"/.well-known/openid-configuration": {},
+		"/authorize":                        {},
+		"/token":                            {},
+		"/oauth/token":                      {},
+		"/oauth/device/code":                {},
+		"/swagger/*":                        {},
+	}
+	// без заголовка Authorization запрос проходит без пользователя: обработчик найдёт сессию SSO по cookie
+	optional := map[string]struct{}{
+		"/oauth/device": {},
 	}
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -34,6 +92,9 @@ func JwtValidation(jwt Jwt) echo.MiddlewareFunc {
 
 			authHeader := c.Request().Header.Get("Authorization")
 			if authHeader == "" {
+				if _, ok := optional[p]; ok {
+					return next(c)
+				}
 				return echo.ErrUnauthorized
 			}
 
@@ -43,15 +104,38 @@ func JwtValidation(jwt Jwt) echo.MiddlewareFunc {
 			}
 			tokenString := parts[1]
 
-			userId, err := jwt.ParseToken(tokenString)
+			claims, err := jwt.ParseToken(tokenString)
 			if err != nil {
 				return c.JSON(http.StatusUnauthorized, map[string]string{
 					"error": err.Error(),
 				})
 			}
+			isUser := claims.PrincipalType == PrincipalUser
+			if revocations.IsRevoked(claims.ID, claims.UserID, claims.IssuedAt) || (isUser && revocations.IsOutdated(claims.UserID, claims.TokenVersion)) {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "token revoked",
+				})
+			}
 
 			ctx := c.Request().Context()
-			ctx = context.WithValue(ctx, contextkeys.UserIDCtxKey, userId)
+			ctx = context.WithValue(ctx, contextkeys.PrincipalTypeCtxKey, claims.PrincipalType)
+			ctx = context.WithValue(ctx, contextkeys.AudienceCtxKey, claims.Audience)
+			if claims.ClientID != "" {
+				ctx = context.WithValue(ctx, contextkeys.ClientIDCtxKey, claims.ClientID)
+			}
+			// у сервиса нет пользователя и роли, поэтому обработчики для пользователей его не пропустят
+			if isUser {
+				ctx = context.WithValue(ctx, contextkeys.UserIDCtxKey, claims.UserID)
+				ctx = context.WithValue(ctx, contextkeys.RoleCtxKey, claims.Role)
+				ctx = context.WithValue(ctx, contextkeys.AuthTimeCtxKey, claims.AuthTime)
+				ctx = context.WithValue(ctx, contextkeys.AmrCtxKey, claims.Amr)
+				ctx = context.WithValue(ctx, contextkeys.AcrCtxKey, claims.Acr)
+			} else {
+				ctx = context.WithValue(ctx, contextkeys.ScopesCtxKey, claims.Scopes)
+			}
+			if claims.SessionID != "" {
+				ctx = context.WithValue(ctx, contextkeys.SessionIDCtxKey, claims.SessionID)
+			}
 			c.SetRequest(c.Request().WithContext(ctx))
 
 			return next(c)
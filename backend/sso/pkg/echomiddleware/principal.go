@@ -0,0 +1,48 @@
+package echomiddleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/EtoNeAnanasbI95/sso/pkg/contextkeys"
+	"github.com/labstack/echo/v4"
+)
+
+// RequireUser пропускает только токены пользователей, сервисные токены отклоняются. Ставится после JwtValidation
+func RequireUser() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, _ := c.Request().Context().Value(contextkeys.PrincipalTypeCtxKey).(string)
+			if principal != PrincipalUser {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "user token required",
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireScope пропускает только сервисные токены, которым выданы все перечисленные scope. Ставится после JwtValidation
+func RequireScope(scopes ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			principal, _ := ctx.Value(contextkeys.PrincipalTypeCtxKey).(string)
+			granted, _ := ctx.Value(contextkeys.ScopesCtxKey).([]string)
+			if principal != PrincipalService {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "service token required",
+				})
+			}
+			for _, scope := range scopes {
+				if !slices.Contains(granted, scope) {
+					return c.JSON(http.StatusForbidden, map[string]string{
+						"error": "insufficient scope",
+					})
+				}
+			}
+			return next(c)
+		}
+	}
+}
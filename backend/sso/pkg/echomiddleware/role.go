@@ -0,0 +1,28 @@
+package echomiddleware
+
+import (
+	"net/http"
+
+	"github.com/EtoNeAnanasbI95/sso/pkg/contextkeys"
+	"github.com/labstack/echo/v4"
+)
+
+// RequireRole пропускает только пользователей с одной из ролей. Ставится после JwtValidation
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	allowed := make(map[string]struct{}, len(roles))
+	for _, role := range roles {
+		allowed[role] = struct{}{}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, _ := c.Request().Context().Value(contextkeys.RoleCtxKey).(string)
+			if _, ok := allowed[role]; !ok {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "insufficient role",
+				})
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package echomiddleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EtoNeAnanasbI95/sso/pkg/contextkeys"
+	"github.com/labstack/echo/v4"
+)
+
+// ErrorInsufficientUserAuthentication — код ошибки step-up из RFC 9470
+const ErrorInsufficientUserAuthentication = "insufficient_user_authentication"
+
+// StepUp — требования к входу пользователя для чувствительных операций. Нулевые поля не проверяются
+type StepUp struct {
+	// ACR — минимальный уровень acr. Уровни — числа, больший надёжнее
+	ACR string
+	// MaxAge — сколько может пройти с auth_time
+	MaxAge time.Duration
+}
+
+// StepUpRequired — ответ, по которому фронтенд понимает, что пользователю нужно войти заново:
+// с уровнем не ниже acr_values и не раньше чем max_age секунд назад
+type StepUpRequired struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	AcrValues        string `json:"acr_values,omitempty"`
+	MaxAge           int64  `json:"max_age,omitempty"`
+}
+
+// RequireStepUp пропускает пользователей, вошедших достаточно надёжно и недавно. Остальным отвечает 401
+// с StepUpRequired и тем же вызовом в WWW-Authenticate. Ставится после JwtValidation
+func RequireStepUp(policy StepUp) echo.MiddlewareFunc {
+	required := acrLevel(policy.ACR)
+	maxAge := int64(policy.MaxAge / time.Second)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			principal, _ := ctx.Value(contextkeys.PrincipalTypeCtxKey).(string)
+			if principal != PrincipalUser {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "user token required",
+				})
+			}
+			acr, _ := ctx.Value(contextkeys.AcrCtxKey).(string)
+			authTime, _ := ctx.Value(contextkeys.AuthTimeCtxKey).(time.Time)
+
+			var reasons []string
+			if acrLevel(acr) < required {
+				reasons = append(reasons, "a stronger authentication level is required")
+			}
+			if policy.MaxAge > 0 && (authTime.IsZero() || time.Since(authTime) > policy.MaxAge) {
+				reasons = append(reasons, "a more recent authentication is required")
+			}
+			if len(reasons) == 0 {
+				return next(c)
+			}
+
+			challenge := StepUpRequired{
+				Error:            ErrorInsufficientUserAuthentication,
+				ErrorDescription: strings.Join(reasons, "; "),
+				AcrValues:        policy.ACR,
+				MaxAge:           maxAge,
+			}
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, challenge.header())
+			return c.JSON(http.StatusUnauthorized, challenge)
+		}
+	}
+}
+
+func (s StepUpRequired) header() string {
+	header := fmt.Sprintf(`Bearer error="%s", error_description="%s"`, s.Error, s.ErrorDescription)
+	if s.AcrValues != "" {
+		header += fmt.Sprintf(`, acr_values="%s"`, s.AcrValues)
+	}
+	if s.MaxAge > 0 {
+		header += fmt.Sprintf(`, max_age=%d`, s.MaxAge)
+	}
+	return header
+}
+
+// acrLevel переводит acr в число; отсутствующий или незнакомый уровень — 0
+func acrLevel(acr string) int {
+	level, err := strconv.Atoi(acr)
+	if err != nil {
+		return 0
+	}
+	return level
+}